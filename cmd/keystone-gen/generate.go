@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/keystonedb/sdk-go/keystone"
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+// initialisms are kept upper case in field and type names
+var initialisms = map[string]bool{
+	"api": true, "id": true, "ids": true, "ip": true, "json": true, "http": true, "https": true,
+	"sku": true, "sql": true, "uid": true, "uri": true, "url": true, "uuid": true, "vat": true,
+}
+
+// tagOptions are the keystone tag options for each property option
+var tagOptions = []struct {
+	option proto.Property_Option
+	tag    string
+}{
+	{proto.Property_Unique, "unique"},
+	{proto.Property_Primary, "primary"},
+	{proto.Property_Indexed, "indexed"},
+	{proto.Property_Searchable, "searchable"},
+	{proto.Property_Immutable, "immutable"},
+	{proto.Property_Deprecated, "deprecated"},
+	{proto.Property_Required, "required"},
+	{proto.Property_ReverseLookup, "lookup"},
+	{proto.Property_Metric, "metric"},
+	{proto.Property_MetricFilter, "metricFilter"},
+	{proto.Property_NoSnapshot, "no-snapshot"},
+}
+
+// goName converts a keystone type or property name to an exported Go identifier
+func goName(name string) string {
+	parts := strings.FieldsFunc(keystone.PropertyName(name), func(r rune) bool { return r == '_' })
+
+	result := ""
+	for _, part := range parts {
+		lower := strings.ToLower(part)
+		if initialisms[lower] {
+			result += strings.ToUpper(lower)
+			if lower == "ids" {
+				result = result[:len(result)-1] + "s"
+			}
+			continue
+		}
+		result += strings.ToUpper(lower[:1]) + lower[1:]
+	}
+	if result == "" || result[0] >= '0' && result[0] <= '9' {
+		result = "X" + result
+	}
+	return result
+}
+
+// goType returns the Go type for a property, and any tag options required to keep its data type
+func goType(prop *proto.Property) (string, []string) {
+	switch prop.GetDataType() {
+	case proto.Property_Text:
+		switch prop.GetExtendedType() {
+		// string kinds are mapped by the string reflector, so the tag keeps the extended type
+		case proto.Property_UserInput:
+			return "keystone.UserInput", []string{"user"}
+		case proto.Property_Personal:
+			return "keystone.PII", []string{"pii"}
+		case proto.Property_URL:
+			return "keystone.URL", nil
+		case proto.Property_Country:
+			return "keystone.Country", nil
+		case proto.Property_IP:
+			return "keystone.IPAddress", nil
+		case proto.Property_ExternalID:
+			return "keystone.ExternalID", nil
+		case proto.Property_PersonName, proto.Property_Email, proto.Property_Phone:
+			return "string", []string{"pii"}
+		}
+		return "string", nil
+	case proto.Property_SecureText:
+		switch prop.GetExtendedType() {
+		case proto.Property_Personal:
+			return "keystone.SecurePII", nil
+		case proto.Property_PersonName:
+			return "keystone.PersonName", nil
+		case proto.Property_Phone:
+			return "keystone.Phone", nil
+		case proto.Property_Email:
+			return "keystone.Email", nil
+		case proto.Property_IP:
+			return "keystone.SecureIP", nil
+		}
+		return "keystone.SecureString", nil
+	case proto.Property_VerifyText:
+		return "keystone.VerifyString", nil
+	case proto.Property_Number:
+		return "int64", nil
+	case proto.Property_Boolean:
+		return "bool", nil
+	case proto.Property_Float:
+		return "float64", nil
+	case proto.Property_Time:
+		return "time.Time", nil
+	case proto.Property_Amount:
+		if prop.GetExtendedType() == proto.Property_Interval {
+			return "keystone.Interval", nil
+		}
+		return "keystone.Amount", nil
+	case proto.Property_KeyValue:
+		return "keystone.Keyed[string]", nil
+	case proto.Property_Strings:
+		return "[]string", nil
+	case proto.Property_Ints:
+		return "[]int64", nil
+	case proto.Property_IntSet:
+		return "keystone.IntSet", nil
+	case proto.Property_StringSet:
+		return "keystone.StringSet", nil
+	case proto.Property_Mixed:
+		if prop.GetExtendedType() == proto.Property_URL {
+			return "keystone.Link", nil
+		}
+		return "keystone.Mixed", nil
+	case proto.Property_KeyMixed:
+		return "keystone.KeyMixed", nil
+	}
+	// bytes and unmanaged properties are read and written as raw data
+	return "[]byte", nil
+}
+
+// structField is a field in a generated struct, either a property or a nested struct of prefixed properties
+type structField struct {
+	name     string
+	goName   string
+	property *proto.Property
+	nested   *structDef
+}
+
+type structDef struct {
+	name   string
+	prefix string // dotted property prefix of a nested struct
+	fields []*structField
+}
+
+func (s *structDef) field(name string) *structField {
+	for _, f := range s.fields {
+		if f.name == name {
+			return f
+		}
+	}
+	f := &structField{name: name, goName: goName(name)}
+	s.fields = append(s.fields, f)
+	return f
+}
+
+// add places the property in the struct, creating nested structs for dotted property names
+func (s *structDef) add(path []string, prop *proto.Property) {
+	f := s.field(path[0])
+	if len(path) == 1 {
+		f.property = prop
+		return
+	}
+	if f.nested == nil {
+		f.nested = &structDef{name: s.name + f.goName, prefix: strings.TrimPrefix(s.prefix+"."+f.name, ".")}
+	}
+	f.nested.add(path[1:], prop)
+}
+
+// generator writes Go entity structs for keystone schemas
+type generator struct {
+	pkg     string
+	buf     bytes.Buffer
+	imports map[string]bool
+	nested  []*structDef
+}
+
+// Generate returns formatted Go source declaring an entity struct for each schema
+func Generate(pkg string, schemas []*proto.Schema) ([]byte, error) {
+	g := &generator{pkg: pkg, imports: map[string]bool{"github.com/keystonedb/sdk-go/keystone": true}}
+
+	sorted := append([]*proto.Schema(nil), schemas...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GetType() < sorted[j].GetType() })
+
+	body := &bytes.Buffer{}
+	seen := map[string]bool{}
+	for _, schema := range sorted {
+		if schema.GetType() == "" {
+			return nil, fmt.Errorf("schema %q has no type", schema.GetName())
+		}
+		if seen[schema.GetType()] {
+			return nil, fmt.Errorf("duplicate schema %s", schema.GetType())
+		}
+		seen[schema.GetType()] = true
+		g.writeEntity(body, schema)
+	}
+
+	g.buf.WriteString("// Code generated by keystone-gen. DO NOT EDIT.\n\n")
+	g.buf.WriteString("package " + g.pkg + "\n\n")
+	g.writeImports()
+	g.buf.Write(body.Bytes())
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated source: %w", err)
+	}
+	return src, nil
+}
+
+func (g *generator) writeImports() {
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	g.buf.WriteString("import (\n")
+	for _, path := range paths {
+		g.buf.WriteString("\t" + strconv.Quote(path) + "\n")
+	}
+	g.buf.WriteString(")\n\n")
+}
+
+func (g *generator) writeEntity(w *bytes.Buffer, schema *proto.Schema) {
+	entity := &structDef{name: goName(schema.GetType())}
+	props := append([]*proto.Property(nil), schema.GetProperties()...)
+	sort.SliceStable(props, func(i, j int) bool { return props[i].GetName() < props[j].GetName() })
+	for _, prop := range props {
+		if prop.GetName() == "" || strings.HasPrefix(prop.GetName(), "_") {
+			continue
+		}
+		entity.add(strings.Split(prop.GetName(), "."), prop)
+	}
+
+	fmt.Fprintf(w, "// %s is the %s keystone entity\n", entity.name, schema.GetType())
+	if description := schema.GetDescription(); description != "" {
+		fmt.Fprintf(w, "//\n// %s\n", strings.ReplaceAll(description, "\n", "\n// "))
+	}
+	fmt.Fprintf(w, "type %s struct {\n", entity.name)
+	switch {
+	case schema.GetIsChild():
+		w.WriteString("\tkeystone.BaseChildEntity\n")
+	case schema.GetKsType() == proto.Schema_TimeSeries:
+		w.WriteString("\tkeystone.BaseEntity\n\tkeystone.TimeSeriesEntity\n")
+	default:
+		w.WriteString("\tkeystone.BaseEntity\n")
+	}
+	g.writeFields(w, entity)
+	w.WriteString("}\n\n")
+
+	g.writeDefinition(w, entity.name, schema)
+
+	for len(g.nested) > 0 {
+		nested := g.nested[0]
+		g.nested = g.nested[1:]
+		fmt.Fprintf(w, "// %s holds the %s properties of %s\n", nested.name, nested.prefix, entity.name)
+		fmt.Fprintf(w, "type %s struct {\n", nested.name)
+		g.writeFields(w, nested)
+		w.WriteString("}\n\n")
+	}
+}
+
+func (g *generator) writeFields(w *bytes.Buffer, s *structDef) {
+	for _, f := range s.fields {
+		if f.nested != nil {
+			// a nested struct cannot also hold a value, so a property with the same name as the prefix is dropped
+			g.nested = append(g.nested, f.nested)
+			fmt.Fprintf(w, "\t%s %s `keystone:%s`\n", f.goName, f.nested.name, strconv.Quote(f.name))
+			continue
+		}
+
+		typ, tags := goType(f.property)
+		if typ == "time.Time" {
+			g.imports["time"] = true
+		}
+		tag := append([]string{f.name}, tags...)
+		for _, opt := range tagOptions {
+			for _, propOpt := range f.property.GetOptions() {
+				if propOpt == opt.option {
+					tag = append(tag, opt.tag)
+					break
+				}
+			}
+		}
+		fmt.Fprintf(w, "\t%s %s `keystone:%s`\n", f.goName, typ, strconv.Quote(strings.Join(tag, ",")))
+	}
+}
+
+func (g *generator) writeDefinition(w *bytes.Buffer, name string, schema *proto.Schema) {
+	fmt.Fprintf(w, "func (e *%s) GetKeystoneDefinition() keystone.TypeDefinition {\n", name)
+	w.WriteString("\treturn keystone.TypeDefinition{\n")
+	fmt.Fprintf(w, "\t\tType: %s,\n", strconv.Quote(schema.GetType()))
+	for _, field := range []struct{ name, value string }{
+		{"Name", schema.GetName()},
+		{"Description", schema.GetDescription()},
+		{"Singular", schema.GetSingular()},
+		{"Plural", schema.GetPlural()},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(w, "\t\t%s: %s,\n", field.name, strconv.Quote(field.value))
+		}
+	}
+	if len(schema.GetOptions()) > 0 {
+		g.imports["github.com/keystonedb/sdk-go/proto"] = true
+		opts := make([]string, len(schema.GetOptions()))
+		for i, opt := range schema.GetOptions() {
+			opts[i] = "proto.Schema_" + opt.String()
+		}
+		fmt.Fprintf(w, "\t\tOptions: []proto.Schema_Option{%s},\n", strings.Join(opts, ", "))
+	}
+	if schema.GetKsType() != proto.Schema_Entity {
+		g.imports["github.com/keystonedb/sdk-go/proto"] = true
+		fmt.Fprintf(w, "\t\tKeystoneType: proto.Schema_%s,\n", schema.GetKsType())
+	}
+	w.WriteString("\t}\n}\n\n")
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/keystonedb/sdk-go/keystone"
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+func testSchema() *proto.Schema {
+	return &proto.Schema{
+		Type:        "library-user",
+		Name:        "Library User",
+		Description: "A member of the library",
+		Options:     []proto.Schema_Option{proto.Schema_StoreMutations},
+		Properties: []*proto.Property{
+			{Name: "user_id", DataType: proto.Property_Text, Options: []proto.Property_Option{proto.Property_Unique, proto.Property_Indexed}},
+			{Name: "email", DataType: proto.Property_SecureText, ExtendedType: proto.Property_Email},
+			{Name: "balance", DataType: proto.Property_Amount},
+			{Name: "membership", DataType: proto.Property_Amount, ExtendedType: proto.Property_Interval},
+			{Name: "tags", DataType: proto.Property_StringSet},
+			{Name: "settings", DataType: proto.Property_KeyValue},
+			{Name: "joined", DataType: proto.Property_Time},
+			{Name: "address.line1", DataType: proto.Property_Text},
+			{Name: "address.geo.lat", DataType: proto.Property_Float},
+			{Name: "_created", DataType: proto.Property_Time},
+		},
+	}
+}
+
+var (
+	exportOnce  sync.Once
+	exportFiles map[string]string
+	exportErr   error
+)
+
+// exportData locates the compiled export data of the packages generated code may import,
+// type checking against export data avoids parsing the dependencies from source
+func exportData() (map[string]string, error) {
+	exportOnce.Do(func() {
+		out, err := exec.Command("go", "list", "-export", "-deps", "-f", "{{.ImportPath}} {{.Export}}",
+			"time", "github.com/keystonedb/sdk-go/keystone", "github.com/keystonedb/sdk-go/proto").Output()
+		if err != nil {
+			exportErr = err
+			return
+		}
+		exportFiles = map[string]string{}
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			if path, file, ok := strings.Cut(line, " "); ok && file != "" {
+				exportFiles[path] = file
+			}
+		}
+	})
+	return exportFiles, exportErr
+}
+
+// typeCheck parses and type checks generated source against the keystone packages
+func typeCheck(t *testing.T, src []byte) *types.Package {
+	t.Helper()
+	files, err := exportData()
+	if err != nil {
+		t.Fatalf("unable to locate export data: %v", err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "gen.go", src, parser.AllErrors)
+	if err != nil {
+		t.Fatalf("generated source does not parse: %v\n%s", err, src)
+	}
+	lookup := func(path string) (io.ReadCloser, error) {
+		if export, ok := files[path]; ok {
+			return os.Open(export)
+		}
+		return nil, os.ErrNotExist
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "gc", lookup)}
+	pkg, err := conf.Check("models", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("generated source does not type check: %v\n%s", err, src)
+	}
+	return pkg
+}
+
+// propertyNames returns the keystone property names of a generated struct, following nested structs
+func propertyNames(t *testing.T, st *types.Struct, prefix string) []string {
+	t.Helper()
+	var names []string
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		if field.Embedded() {
+			continue
+		}
+		tag, ok := reflect.StructTag(st.Tag(i)).Lookup("keystone")
+		if !ok {
+			t.Errorf("field %s has no valid keystone tag: %s", field.Name(), st.Tag(i))
+			continue
+		}
+		name := prefix + strings.Split(tag, ",")[0]
+		if named, isNamed := field.Type().(*types.Named); isNamed && named.Obj().Pkg().Name() == "models" {
+			if nested, isStruct := named.Underlying().(*types.Struct); isStruct {
+				names = append(names, propertyNames(t, nested, name+".")...)
+				continue
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := Generate("models", []*proto.Schema{testSchema()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := typeCheck(t, src)
+
+	// the generated tags map back to every non-internal schema property
+	var want []string
+	for _, prop := range testSchema().GetProperties() {
+		if !strings.HasPrefix(prop.GetName(), "_") {
+			want = append(want, prop.GetName())
+		}
+	}
+	got := propertyNames(t, pkg.Scope().Lookup("LibraryUser").Type().Underlying().(*types.Struct), "")
+	slices.Sort(want)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("expected generated properties %v, got %v", want, got)
+	}
+
+	// compare without alignment, which depends on the longest field in each struct
+	code := strings.Join(strings.Fields(string(src)), " ")
+	for _, want := range []string{
+		"type LibraryUser struct {",
+		"keystone.BaseEntity",
+		"UserID string `keystone:\"user_id,unique,indexed\"`",
+		"Email keystone.Email",
+		"Balance keystone.Amount",
+		"Membership keystone.Interval",
+		"Tags keystone.StringSet",
+		"Settings keystone.Keyed[string]",
+		"Joined time.Time",
+		"Address LibraryUserAddress `keystone:\"address\"`",
+		"// LibraryUserAddressGeo holds the address.geo properties of LibraryUser",
+		"Lat float64 `keystone:\"lat\"`",
+		"func (e *LibraryUser) GetKeystoneDefinition() keystone.TypeDefinition {",
+		"Type: \"library-user\",",
+		"Options: []proto.Schema_Option{proto.Schema_StoreMutations},",
+		"\"time\"",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected generated source to contain %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "Created") {
+		t.Errorf("internal properties should not be generated\n%s", code)
+	}
+}
+
+func TestGenerate_ChildAndTimeSeries(t *testing.T) {
+	src, err := Generate("models", []*proto.Schema{
+		{Type: "order-line", IsChild: true},
+		{Type: "page-view", KsType: proto.Schema_TimeSeries},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	typeCheck(t, src)
+	code := string(src)
+	for _, want := range []string{"keystone.BaseChildEntity", "keystone.TimeSeriesEntity", "KeystoneType: proto.Schema_TimeSeries"} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected generated source to contain %q\n%s", want, code)
+		}
+	}
+
+	if _, err = Generate("models", []*proto.Schema{{Type: "a"}, {Type: "a"}}); err == nil {
+		t.Error("expected an error for duplicate schemas")
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"library-user":   "LibraryUser",
+		"user_id":        "UserID",
+		"product_ids":    "ProductIDs",
+		"homepage_url":   "HomepageURL",
+		"3d_model":       "X3DModel",
+		"metricFilter":   "MetricFilter",
+		"current_role":   "CurrentRole",
+		"years_with_org": "YearsWithOrg",
+	}
+	for in, want := range tests {
+		if got := goName(in); got != want {
+			t.Errorf("goName(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
+
+func TestLoadSchemas(t *testing.T) {
+	dir := t.TempDir()
+	snapshotPath := filepath.Join(dir, "snapshot.json")
+	if err := (keystone.SchemaSnapshot{"library-user": testSchema()}).Save(snapshotPath); err != nil {
+		t.Fatal(err)
+	}
+
+	arrayPath := filepath.Join(dir, "array.json")
+	if err := os.WriteFile(arrayPath, []byte(`[{"type":"a"},{"type":"b","properties":[{"name":"x","data_type":"Number"}]}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	singlePath := filepath.Join(dir, "single.json")
+	if err := os.WriteFile(singlePath, []byte(`{"type":"single"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]int{snapshotPath: 1, arrayPath: 2, singlePath: 1}
+	for path, want := range tests {
+		schemas, err := loadSchemas(path)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+		} else if len(schemas) != want {
+			t.Errorf("%s: expected %d schemas, got %d", path, want, len(schemas))
+		}
+	}
+
+	schemas, _ := loadSchemas(snapshotPath)
+	if len(schemas) == 1 && len(schemas[0].GetProperties()) != len(testSchema().GetProperties()) {
+		t.Errorf("expected snapshot properties to be loaded, got %v", schemas[0])
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// loadSchemas reads the schemas from a dump file.
+// JSON files may hold a single schema, an array of schemas, or a keystone.SchemaSnapshot keyed by type.
+// Any other file is read as a binary encoded proto.Schema.
+func loadSchemas(path string) ([]*proto.Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.Ext(path) != ".json" {
+		schema := &proto.Schema{}
+		if err = protobuf.Unmarshal(data, schema); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return []*proto.Schema{schema}, nil
+	}
+
+	schemas, err := parseJSONSchemas(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return schemas, nil
+}
+
+func parseJSONSchemas(data []byte) ([]*proto.Schema, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var raw []json.RawMessage
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	} else {
+		single := &proto.Schema{}
+		if err := protojson.Unmarshal(data, single); err == nil {
+			return []*proto.Schema{single}, nil
+		}
+
+		// not a schema, so read as a snapshot of schemas keyed by type
+		keyed := map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &keyed); err != nil {
+			return nil, err
+		}
+		for _, msg := range keyed {
+			raw = append(raw, msg)
+		}
+	}
+
+	schemas := make([]*proto.Schema, 0, len(raw))
+	for _, msg := range raw {
+		schema := &proto.Schema{}
+		if err := protojson.Unmarshal(msg, schema); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, nil
+}
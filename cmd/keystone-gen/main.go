@@ -0,0 +1,70 @@
+// Command keystone-gen generates Go entity structs from keystone schema dumps.
+//
+// Schemas are read from JSON or binary proto files, including the snapshots written by keystone.SchemaSnapshot.Save
+// from the server schemas returned by SchemaPlan.Apply. The keystone API has no call to list schemas without
+// defining them, so schemas shared by another app must be exported by that app and passed to keystone-gen.
+//
+// Usage:
+//
+//	keystone-gen -in schema.json [-in other.json] -pkg models -out models/entities_gen.go
+//
+// Each struct embeds keystone.BaseEntity, is tagged with the property names and options of its schema,
+// and implements GetKeystoneDefinition so the schema type, names and options are kept.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+type fileList []string
+
+func (f *fileList) String() string { return strings.Join(*f, ",") }
+
+func (f *fileList) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func main() {
+	var inputs fileList
+	flag.Var(&inputs, "in", "schema dump to read, JSON or binary proto (repeatable)")
+	pkg := flag.String("pkg", "models", "package name of the generated file")
+	out := flag.String("out", "", "file to write, defaults to stdout")
+	flag.Parse()
+
+	if err := run(inputs, *pkg, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "keystone-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(inputs []string, pkg, out string) error {
+	if len(inputs) == 0 {
+		return fmt.Errorf("no schema dumps given, use -in")
+	}
+
+	var schemas []*proto.Schema
+	for _, input := range inputs {
+		loaded, err := loadSchemas(input)
+		if err != nil {
+			return err
+		}
+		schemas = append(schemas, loaded...)
+	}
+
+	src, err := Generate(pkg, schemas)
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
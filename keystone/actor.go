@@ -30,6 +30,20 @@ func (a *Actor) CloneWithoutWorkspace() *Actor {
 	}
 }
 
+// cloneForWorkspace returns a copy of the actor making requests in the given workspace
+func (a *Actor) cloneForWorkspace(workspaceID string) *Actor {
+	if a == nil {
+		return nil
+	}
+
+	return &Actor{
+		connection:  a.connection,
+		workspaceID: workspaceID,
+		traceID:     a.traceID,
+		user:        a.user,
+	}
+}
+
 func (a *Actor) ReplaceConnection(c *Connection) { a.connection = c }
 func (a *Actor) Connection() *Connection         { return a.connection }
 
@@ -63,7 +77,7 @@ func (a *Actor) Authorization() *proto.Authorization {
 	}
 	return &proto.Authorization{
 		Source:      &a.connection.appID,
-		Token:       a.connection.token,
+		Token:       a.connection.accessToken(),
 		TraceId:     a.traceID,
 		WorkspaceId: a.workspaceID,
 		User:        a.User(),
@@ -76,7 +90,7 @@ func (a *Actor) AuthorizeContext(ctx context.Context) context.Context {
 		"trace_id":     a.TraceID(),
 		"vendor_id":    a.VendorID(),
 		"app_id":       a.AppID(),
-		"token":        a.Connection().token,
+		"token":        a.Connection().accessToken(),
 		"client":       a.Client(),
 		"user_id":      a.UserID(),
 		"user_agent":   a.UserAgent(),
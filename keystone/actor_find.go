@@ -6,8 +6,28 @@ import (
 	"github.com/keystonedb/sdk-go/proto"
 )
 
+// FindResult is the result of a find request, including the total number of matching entities
+type FindResult struct {
+	Entities     []*proto.EntityResponse
+	TotalResults int32
+	ResultID     string
+	LastID       string // cursor for the next page, when paged through the index
+}
+
 // Find returns a list of entities matching the given entityType and retrieveProperties
 func (a *Actor) Find(ctx context.Context, entityType string, retrieve RetrieveOption, options ...FindOption) ([]*proto.EntityResponse, error) {
+	result, err := a.FindWithResult(ctx, entityType, retrieve, options...)
+	if err != nil {
+		return nil, err
+	}
+	return result.Entities, nil
+}
+
+// FindWithResult returns the entities matching the given entityType, along with the total result count and result ID.
+// The find request has no sort or page fields, so when SortBy or Limit is set the matching page of IDs is read from the
+// index with QueryIndex, and only those entities are loaded. Label and relation filters cannot be applied by the index,
+// so combined with SortBy or Limit they cost an extra find of every matching ID before the index is queried.
+func (a *Actor) FindWithResult(ctx context.Context, entityType string, retrieve RetrieveOption, options ...FindOption) (*FindResult, error) {
 	findRequest := &proto.FindRequest{
 		Authorization: a.Authorization(),
 		Schema:        &proto.Key{Key: entityType, Source: a.Authorization().Source},
@@ -31,6 +51,11 @@ func (a *Actor) Find(ctx context.Context, entityType string, retrieve RetrieveOp
 	if retrieve != nil {
 		retrieve.Apply(findRequest.View)
 	}
+	if definition, ok := a.connection.registeredDefinition(entityType); ok {
+		if err := definition.validateView(findRequest.View); err != nil {
+			return nil, err
+		}
+	}
 
 	fReq := &filterRequest{Properties: []*proto.PropertyRequest{}}
 
@@ -44,9 +69,113 @@ func (a *Actor) Find(ctx context.Context, entityType string, retrieve RetrieveOp
 	findRequest.ParentEntityId = fReq.ParentEntityID
 	findRequest.EntityIds = fReq.EntityIds
 
+	if len(fReq.sortBy) > 0 || fReq.PerPage > 0 || fReq.AfterID != "" {
+		return a.findPage(ctx, findRequest, fReq)
+	}
+
+	resp, err := a.connection.Find(ctx, findRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FindResult{
+		Entities:     resp.GetEntities(),
+		TotalResults: resp.GetTotalResults(),
+		ResultID:     resp.GetResultId(),
+	}
+	if result.TotalResults == 0 {
+		result.TotalResults = int32(len(result.Entities))
+	}
+	return result, nil
+}
+
+// findPage sorts and pages the matching IDs through the index, then finds the entities on the requested page
+func (a *Actor) findPage(ctx context.Context, findRequest *proto.FindRequest, fReq *filterRequest) (*FindResult, error) {
+	indexRequest := &proto.QueryIndexRequest{
+		Authorization:  findRequest.GetAuthorization(),
+		Schema:         findRequest.GetSchema(),
+		Properties:     sortProperties(fReq.sortBy),
+		Filters:        fReq.Filters,
+		Sort:           fReq.sortBy,
+		ParentEntityId: fReq.ParentEntityID,
+		EntityIds:      fReq.EntityIds,
+		Page: &proto.PageRequest{
+			PerPage:    fReq.PerPage,
+			PageNumber: fReq.PageNumber,
+			AfterId:    fReq.AfterID,
+		},
+	}
+
+	if len(fReq.Labels) > 0 || fReq.RelationOf != nil {
+		// the index cannot filter by labels or relations, so find the matching IDs without any properties first
+		idRequest := &proto.FindRequest{
+			Authorization:   findRequest.GetAuthorization(),
+			Schema:          findRequest.GetSchema(),
+			View:            &proto.EntityView{},
+			EntityIds:       findRequest.GetEntityIds(),
+			RelationOf:      findRequest.GetRelationOf(),
+			PropertyFilters: findRequest.GetPropertyFilters(),
+			LabelFilters:    findRequest.GetLabelFilters(),
+			ParentEntityId:  findRequest.GetParentEntityId(),
+		}
+		idResp, err := a.connection.Find(ctx, idRequest)
+		if err != nil {
+			return nil, err
+		}
+		if len(idResp.GetEntities()) == 0 {
+			return &FindResult{Entities: []*proto.EntityResponse{}, ResultID: idResp.GetResultId()}, nil
+		}
+		indexRequest.EntityIds = entityIDs(idResp.GetEntities())
+	}
+
+	indexResp, err := a.connection.QueryIndex(ctx, indexRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FindResult{
+		Entities:     []*proto.EntityResponse{},
+		TotalResults: indexResp.GetTotalResults(),
+		LastID:       indexResp.GetLastId(),
+	}
+	pageIDs := entityIDs(indexResp.GetEntities())
+	if len(pageIDs) == 0 {
+		return result, nil
+	}
+
+	findRequest.EntityIds = pageIDs
 	resp, err := a.connection.Find(ctx, findRequest)
 	if err != nil {
 		return nil, err
 	}
-	return resp.Entities, nil
+	result.ResultID = resp.GetResultId()
+
+	// entities are returned in the order of the index page
+	found := make(map[string]*proto.EntityResponse, len(resp.GetEntities()))
+	for _, entity := range resp.GetEntities() {
+		found[entity.GetEntity().GetEntityId()] = entity
+	}
+	for _, id := range pageIDs {
+		if entity, ok := found[id]; ok {
+			result.Entities = append(result.Entities, entity)
+		}
+	}
+	return result, nil
+}
+
+// sortProperties returns the properties sorted by, which are the only properties needed from the index
+func sortProperties(sorts []*proto.PropertySort) []string {
+	var props []string
+	for _, s := range sorts {
+		props = append(props, s.GetProperty())
+	}
+	return props
+}
+
+func entityIDs(entities []*proto.EntityResponse) []string {
+	ids := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		ids = append(ids, entity.GetEntity().GetEntityId())
+	}
+	return ids
 }
@@ -1,6 +1,7 @@
 package keystone
 
 import (
+	"context"
 	"testing"
 
 	"github.com/keystonedb/sdk-go/proto"
@@ -822,3 +823,125 @@ func TestFindMultipleSortOptions(t *testing.T) {
 		t.Error("second sort should be name ascending")
 	}
 }
+
+func findTestEntity(id string, score int64) *proto.EntityResponse {
+	return &proto.EntityResponse{
+		Entity:     &proto.Entity{EntityId: id},
+		Properties: []*proto.EntityProperty{{Property: "score", Value: &proto.Value{Int: score}}},
+	}
+}
+
+func TestActorFindWithResult_SortAndLimit(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	mock.QueryIndexFunc = func(_ context.Context, req *proto.QueryIndexRequest) (*proto.QueryIndexResponse, error) {
+		if len(req.GetSort()) != 1 || req.GetSort()[0].GetProperty() != "score" || !req.GetSort()[0].GetDescending() {
+			t.Errorf("expected the sort to be sent to the index, got %v", req.GetSort())
+		}
+		if req.GetPage().GetPerPage() != 2 || req.GetPage().GetPageNumber() != 1 {
+			t.Errorf("expected the page to be sent to the index, got %v", req.GetPage())
+		}
+		if len(req.GetProperties()) != 1 || req.GetProperties()[0] != "score" {
+			t.Errorf("expected only the sort property from the index, got %v", req.GetProperties())
+		}
+		return &proto.QueryIndexResponse{
+			Entities:     []*proto.EntityResponse{findTestEntity("b", 30), findTestEntity("c", 20)},
+			TotalResults: 4,
+		}, nil
+	}
+	mock.FindFunc = func(_ context.Context, req *proto.FindRequest) (*proto.FindResponse, error) {
+		if len(req.GetEntityIds()) != 2 || req.GetEntityIds()[0] != "b" || req.GetEntityIds()[1] != "c" {
+			t.Errorf("expected only the page to be found, got %v", req.GetEntityIds())
+		}
+		if props := req.GetView().GetProperties(); len(props) != 1 || props[0].GetProperties()[0] != "name" {
+			t.Errorf("expected the view to be unchanged, got %v", props)
+		}
+		return &proto.FindResponse{
+			Entities: []*proto.EntityResponse{{Entity: &proto.Entity{EntityId: "c"}}, {Entity: &proto.Entity{EntityId: "b"}}},
+			ResultId: "result-1",
+		}, nil
+	}
+
+	result, err := actor.FindWithResult(context.Background(), "find-entity", WithProperties("name"), SortDesc("score"), Limit(2, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.TotalResults != 4 {
+		t.Errorf("expected 4 total results, got %d", result.TotalResults)
+	}
+	if result.ResultID != "result-1" {
+		t.Errorf("expected result ID result-1, got %q", result.ResultID)
+	}
+	if len(result.Entities) != 2 {
+		t.Fatalf("expected 2 entities, got %d", len(result.Entities))
+	}
+	if result.Entities[0].GetEntity().GetEntityId() != "b" || result.Entities[1].GetEntity().GetEntityId() != "c" {
+		t.Errorf("expected index order b, c got %s, %s", result.Entities[0].GetEntity().GetEntityId(), result.Entities[1].GetEntity().GetEntityId())
+	}
+}
+
+func TestActorFindWithResult_LabelsLimit(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	finds := 0
+	mock.FindFunc = func(_ context.Context, req *proto.FindRequest) (*proto.FindResponse, error) {
+		finds++
+		if len(req.GetLabelFilters()) != 1 {
+			t.Errorf("expected the label filter on every find, got %v", req.GetLabelFilters())
+		}
+		if finds == 1 {
+			if len(req.GetView().GetProperties()) != 0 {
+				t.Errorf("expected the IDs to be found without properties, got %v", req.GetView().GetProperties())
+			}
+			return &proto.FindResponse{Entities: []*proto.EntityResponse{
+				{Entity: &proto.Entity{EntityId: "a"}}, {Entity: &proto.Entity{EntityId: "b"}}, {Entity: &proto.Entity{EntityId: "c"}},
+			}}, nil
+		}
+		return &proto.FindResponse{Entities: []*proto.EntityResponse{{Entity: &proto.Entity{EntityId: req.GetEntityIds()[0]}}}}, nil
+	}
+	mock.QueryIndexFunc = func(_ context.Context, req *proto.QueryIndexRequest) (*proto.QueryIndexResponse, error) {
+		if len(req.GetEntityIds()) != 3 {
+			t.Errorf("expected the matching IDs to be paged, got %v", req.GetEntityIds())
+		}
+		return &proto.QueryIndexResponse{Entities: []*proto.EntityResponse{{Entity: &proto.Entity{EntityId: "c"}}}, TotalResults: 3}, nil
+	}
+
+	result, err := actor.FindWithResult(context.Background(), "find-entity", WithProperties("name"), WithLabel("team", "red"), Limit(1, 3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if finds != 2 || len(result.Entities) != 1 || result.Entities[0].GetEntity().GetEntityId() != "c" || result.TotalResults != 3 {
+		t.Errorf("expected the third entity of 3, got %v after %d finds", result, finds)
+	}
+
+	// no matching IDs skips the index
+	mock.FindFunc = func(_ context.Context, req *proto.FindRequest) (*proto.FindResponse, error) {
+		return &proto.FindResponse{}, nil
+	}
+	mock.QueryIndexFunc = func(_ context.Context, req *proto.QueryIndexRequest) (*proto.QueryIndexResponse, error) {
+		t.Errorf("expected the index not to be queried without matches")
+		return &proto.QueryIndexResponse{}, nil
+	}
+	if result, err = actor.FindWithResult(context.Background(), "find-entity", nil, WithLabel("team", "red"), Limit(1, 1)); err != nil || len(result.Entities) != 0 {
+		t.Errorf("expected no entities, got %v %v", result, err)
+	}
+}
+
+func TestActorFindWithResult_ServerTotal(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	mock.FindFunc = func(_ context.Context, req *proto.FindRequest) (*proto.FindResponse, error) {
+		return &proto.FindResponse{Entities: []*proto.EntityResponse{findTestEntity("a", 1)}, TotalResults: 50}, nil
+	}
+
+	result, err := actor.FindWithResult(context.Background(), "find-entity", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.TotalResults != 50 {
+		t.Errorf("expected server total of 50, got %d", result.TotalResults)
+	}
+}
@@ -18,6 +18,16 @@ func (a *Actor) GetByUniqueProperty(ctx context.Context, uniqueId, propertyName
 
 // Get retrieves an entity by the given retrieveBy, storing the result in dst
 func (a *Actor) Get(ctx context.Context, retrieveBy RetrieveBy, dst interface{}, retrieve ...RetrieveOption) error {
+	resp, err := a.retrieveEntity(ctx, retrieveBy, dst, retrieve)
+	if err != nil {
+		return err
+	}
+	observeRetrieveOptions(resp, retrieve)
+	return hydrateRetrieved(resp, dst)
+}
+
+// retrieveEntity requests the entity for dst from keystone
+func (a *Actor) retrieveEntity(ctx context.Context, retrieveBy RetrieveBy, dst interface{}, retrieve []RetrieveOption) (*proto.EntityResponse, error) {
 	entityRequest := retrieveBy.BaseRequest()
 	entityRequest.Authorization = a.Authorization()
 	for _, rOpt := range retrieve {
@@ -30,7 +40,7 @@ func (a *Actor) Get(ctx context.Context, retrieveBy RetrieveBy, dst interface{},
 	_, loadByUnique := retrieveBy.(byUniqueProperty)
 	_, genericResult := dst.(GenericResult)
 	if loadByUnique && genericResult {
-		return errors.New("invalid retrieveBy and dst combination")
+		return nil, errors.New("invalid retrieveBy and dst combination")
 	}
 
 	view := entityRequest.View
@@ -44,10 +54,12 @@ func (a *Actor) Get(ctx context.Context, retrieveBy RetrieveBy, dst interface{},
 		r.Source = a.Authorization().GetSource()
 	}
 
-	schema, registered := a.connection.registerType(dst)
-	if !registered {
-		// wait for the type to be registered with the keystone server
-		a.connection.SyncSchema().Wait()
+	schema, err := a.connection.ensureType(ctx, dst)
+	if err != nil {
+		return nil, err
+	}
+	if err = schema.validateView(view); err != nil {
+		return nil, err
 	}
 
 	entityRequest.Schema = &proto.Key{Key: schema.Type, Source: a.Authorization().Source}
@@ -60,11 +72,20 @@ func (a *Actor) Get(ctx context.Context, retrieveBy RetrieveBy, dst interface{},
 		entityRequest.UniqueId.SchemaId = schemaID
 	}
 
-	resp, err := a.connection.Retrieve(ctx, entityRequest)
-	if err != nil {
-		return err
+	return a.connection.Retrieve(ctx, entityRequest)
+}
+
+// observeRetrieveOptions passes the response to each retrieve option observing it, such as WithChildren
+func observeRetrieveOptions(resp *proto.EntityResponse, retrieve []RetrieveOption) {
+	for _, option := range retrieve {
+		if observe, ok := option.(RetrieveObserver); ok {
+			observe.ObserveRetrieve(resp)
+		}
 	}
+}
 
+// hydrateRetrieved sets the lock result and known watcher values on dst, then unmarshals the response into it
+func hydrateRetrieved(resp *proto.EntityResponse, dst interface{}) error {
 	if lk, ok := dst.(Locker); ok && resp.GetLock() != nil {
 		LockData := &LockInfo{
 			LockAcquired: resp.GetLock().GetLockAcquired(),
@@ -99,12 +120,6 @@ func (a *Actor) Get(ctx context.Context, retrieveBy RetrieveBy, dst interface{},
 		watcher.AppendKnownValues(newProps)
 	}
 
-	for _, option := range retrieve {
-		if observe, ok := option.(RetrieveObserver); ok {
-			observe.ObserveRetrieve(resp)
-		}
-	}
-
 	if observe, ok := dst.(RetrieveObserver); ok {
 		observe.ObserveRetrieve(resp)
 	}
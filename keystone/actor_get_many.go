@@ -0,0 +1,148 @@
+package keystone
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+const defaultGetConcurrency = 10
+
+// GetManyErrors holds the error for each entity ID that could not be retrieved
+type GetManyErrors map[ID]error
+
+func (e GetManyErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for id, err := range e {
+		msgs = append(msgs, id.String()+": "+err.Error())
+	}
+	sort.Strings(msgs)
+	return "failed to retrieve entities: " + strings.Join(msgs, ", ")
+}
+
+// GetMany retrieves the given entity IDs into dst, which must be a pointer to a slice of structs, or a non-nil map keyed by ID.
+// Entities are retrieved concurrently with the same watcher and lock handling as Get. Retrieve options observing the
+// response, such as WithChildren, are called once all entities are retrieved, one entity at a time in the order of ids.
+// Slices are filled in the order of ids, skipping failed entities, which are returned as GetManyErrors.
+func (a *Actor) GetMany(ctx context.Context, ids []ID, dst any, retrieve ...RetrieveOption) error {
+	return a.GetManyWithConcurrency(ctx, defaultGetConcurrency, ids, dst, retrieve...)
+}
+
+// GetManyWithConcurrency retrieves the given entity IDs into dst, with at most concurrency requests in flight, see GetMany
+func (a *Actor) GetManyWithConcurrency(ctx context.Context, concurrency int, ids []ID, dst any, retrieve ...RetrieveOption) error {
+	if dst == nil {
+		return ErrMustBeMapOrSlice
+	}
+	dstVal := reflect.ValueOf(dst)
+
+	isMap := dstVal.Kind() == reflect.Map
+	var elemType reflect.Type
+	switch {
+	case isMap:
+		if dstVal.IsNil() {
+			return ErrNilMapGiven
+		}
+		elemType = dstVal.Type().Elem()
+		if dstVal.Type().Key().Kind() != reflect.String {
+			return ErrMustBeMapOrSlice
+		}
+	case dstVal.Kind() == reflect.Pointer && dstVal.Elem().Kind() == reflect.Slice:
+		elemType = dstVal.Elem().Type().Elem()
+	default:
+		return ErrMustBeMapOrSlice
+	}
+
+	structType := elemType
+	if structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return ErrMustBeMapOrSlice
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]reflect.Value, len(ids))
+	responses := make([]*proto.EntityResponse, len(ids))
+	errs := GetManyErrors{}
+	errLock := sync.Mutex{}
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+
+	for i, id := range ids {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errLock.Lock()
+			errs[id] = ctx.Err()
+			errLock.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, id ID) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			item := reflect.New(structType)
+			resp, err := a.retrieveEntity(ctx, ByEntityID(Type(item.Interface()), id), item.Interface(), retrieve)
+			if err == nil {
+				responses[i] = resp
+				err = hydrateRetrieved(resp, item.Interface())
+			}
+			if err != nil {
+				errLock.Lock()
+				errs[id] = err
+				errLock.Unlock()
+				return
+			}
+			results[i] = item
+		}(i, id)
+	}
+	wg.Wait()
+
+	// retrieve observers are not safe for concurrent use
+	for _, resp := range responses {
+		if resp != nil {
+			observeRetrieveOptions(resp, retrieve)
+		}
+	}
+
+	slice := reflect.Value{}
+	if !isMap {
+		slice = dstVal.Elem()
+	}
+
+	for i, item := range results {
+		if !item.IsValid() {
+			continue
+		}
+		if elemType.Kind() != reflect.Pointer {
+			item = item.Elem()
+		}
+		if isMap {
+			dstVal.SetMapIndex(reflect.ValueOf(ids[i]).Convert(dstVal.Type().Key()), item)
+		} else {
+			slice = reflect.Append(slice, item)
+		}
+	}
+
+	if !isMap {
+		dstVal.Elem().Set(slice)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package keystone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+func getManyRetrieve(inFlight, maxInFlight *int32) func(context.Context, *proto.EntityRequest) (*proto.EntityResponse, error) {
+	return func(_ context.Context, req *proto.EntityRequest) (*proto.EntityResponse, error) {
+		current := atomic.AddInt32(inFlight, 1)
+		defer atomic.AddInt32(inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt32(maxInFlight, seen, current) {
+				break
+			}
+		}
+
+		if req.GetEntityId() == "missing" {
+			return nil, errors.New("entity not found")
+		}
+		return &proto.EntityResponse{
+			Entity:     &proto.Entity{EntityId: req.GetEntityId()},
+			Properties: []*proto.EntityProperty{{Property: "name", Value: &proto.Value{Text: "name-" + req.GetEntityId()}}},
+		}, nil
+	}
+}
+
+func TestActorGetMany_Slice(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	var inFlight, maxInFlight int32
+	mock.RetrieveFunc = getManyRetrieve(&inFlight, &maxInFlight)
+
+	ids := []ID{"a", "b", "missing", "c", "d", "e"}
+	var users []*queryTestUser
+	err := actor.GetManyWithConcurrency(context.Background(), 2, ids, &users, WithProperties("name"))
+
+	var getErrs GetManyErrors
+	if !errors.As(err, &getErrs) {
+		t.Fatalf("expected GetManyErrors, got %v", err)
+	}
+	if len(getErrs) != 1 || getErrs["missing"] == nil {
+		t.Errorf("expected a single error for missing, got %v", getErrs)
+	}
+	if len(users) != 5 {
+		t.Fatalf("expected 5 users, got %d", len(users))
+	}
+	if users[2].GetKeystoneID() != "c" || users[2].Name != "name-c" {
+		t.Errorf("expected users in ID order, got %s at index 2", users[2].GetKeystoneID())
+	}
+	if maxInFlight > 2 {
+		t.Errorf("expected at most 2 concurrent retrieves, got %d", maxInFlight)
+	}
+}
+
+func TestActorGetMany_Map(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	var inFlight, maxInFlight int32
+	mock.RetrieveFunc = getManyRetrieve(&inFlight, &maxInFlight)
+
+	users := map[ID]queryTestUser{}
+	if err := actor.GetMany(context.Background(), []ID{"a", "b"}, users); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(users) != 2 || users["b"].Name != "name-b" {
+		t.Errorf("expected users a and b, got %v", users)
+	}
+}
+
+func TestActorGetMany_RetrieveObservers(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	mock.RetrieveFunc = func(_ context.Context, req *proto.EntityRequest) (*proto.EntityResponse, error) {
+		return &proto.EntityResponse{
+			Entity:   &proto.Entity{EntityId: req.GetEntityId()},
+			Children: []*proto.EntityChild{{Type: &proto.Key{Key: "note"}, Cid: req.GetEntityId() + "-1"}},
+		}, nil
+	}
+
+	ids := make([]ID, 20)
+	for i := range ids {
+		ids[i] = ID(fmt.Sprintf("e%d", i))
+	}
+	notes := WithChildren("note")
+	var users []*queryTestUser
+	if err := actor.GetManyWithConcurrency(context.Background(), 8, ids, &users, &notes); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(notes.loaded) != len(ids) {
+		t.Fatalf("expected a child for every entity, got %d", len(notes.loaded))
+	}
+	for i, child := range notes.loaded {
+		if child.GetCid() != fmt.Sprintf("e%d-1", i) {
+			t.Errorf("expected children observed in ID order, got %s at %d", child.GetCid(), i)
+		}
+	}
+}
+
+func TestActorGetMany_InvalidDestination(t *testing.T) {
+	actor := &Actor{}
+	var nilMap map[ID]*queryTestUser
+	var strs []string
+
+	if err := actor.GetMany(context.Background(), []ID{"a"}, nilMap); !errors.Is(err, ErrNilMapGiven) {
+		t.Errorf("expected ErrNilMapGiven, got %v", err)
+	}
+	if err := actor.GetMany(context.Background(), []ID{"a"}, &strs); !errors.Is(err, ErrMustBeMapOrSlice) {
+		t.Errorf("expected ErrMustBeMapOrSlice, got %v", err)
+	}
+	if err := actor.GetMany(context.Background(), []ID{"a"}, queryTestUser{}); !errors.Is(err, ErrMustBeMapOrSlice) {
+		t.Errorf("expected ErrMustBeMapOrSlice, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package keystone
+
+import (
+	"context"
+	"iter"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+const defaultIteratePageSize = 100
+
+type indexPage struct {
+	entities []*proto.EntityResponse
+	total    int32
+	lastID   string
+	err      error
+}
+
+// IterateIndex walks every entity within the index matching the given options, loading pages as they are consumed.
+// The page size and first page are taken from Limit or LimitAfter (defaulting to 100 per page, from page 1),
+// and Prefetch sets how many pages are loaded ahead.
+func (a *Actor) IterateIndex(ctx context.Context, entityType string, retrieveProperties []string, options ...FindOption) iter.Seq2[*proto.EntityResponse, error] {
+	return iteratePages(ctx, options, func(ctx context.Context, page FindOption) indexPage {
+		resp, err := a.QueryIndexWithResult(ctx, entityType, retrieveProperties, append(options[:len(options):len(options)], page)...)
+		return indexPage{entities: resp.GetEntities(), total: resp.GetTotalResults(), lastID: resp.GetLastId(), err: err}
+	})
+}
+
+// IterateFind walks every entity found matching the given options, loading pages as they are consumed.
+// Pages are loaded with FindWithResult, so each page is ordered and paged by the index before its entities are found.
+// Paging options are applied as with IterateIndex.
+func (a *Actor) IterateFind(ctx context.Context, entityType string, retrieve RetrieveOption, options ...FindOption) iter.Seq2[*proto.EntityResponse, error] {
+	return iteratePages(ctx, options, func(ctx context.Context, page FindOption) indexPage {
+		result, err := a.FindWithResult(ctx, entityType, retrieve, append(options[:len(options):len(options)], page)...)
+		if err != nil {
+			return indexPage{err: err}
+		}
+		return indexPage{entities: result.Entities, total: result.TotalResults, lastID: result.LastID}
+	})
+}
+
+// iteratePages yields the entities of each page returned by load, which is passed the option selecting the page
+func iteratePages(ctx context.Context, options []FindOption, load func(context.Context, FindOption) indexPage) iter.Seq2[*proto.EntityResponse, error] {
+	return func(yield func(*proto.EntityResponse, error) bool) {
+		fReq := &filterRequest{}
+		for _, opt := range options {
+			opt.Apply(fReq)
+		}
+
+		p := &pager{load: load, perPage: fReq.PerPage, pageNumber: max(fReq.PageNumber, 1), afterID: fReq.AfterID}
+		if p.perPage <= 0 {
+			p.perPage = defaultIteratePageSize
+		}
+		// the total counts from the first result, so it cannot end an iteration resumed from a cursor
+		p.useTotal = p.afterID == ""
+		p.fetched = int(p.perPage) * int(p.pageNumber-1)
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		for page := range p.pages(ctx, fReq.prefetch) {
+			if page.err != nil {
+				yield(nil, page.err)
+				return
+			}
+			for _, entity := range page.entities {
+				if !yield(entity, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// pager tracks the position of an iteration through pages of results
+type pager struct {
+	load       func(context.Context, FindOption) indexPage
+	perPage    int32
+	pageNumber int32
+	afterID    string
+	useTotal   bool
+	fetched    int
+	done       bool
+}
+
+// pages returns the pages in order, loading up to prefetch pages ahead in the background.
+// Without prefetching, each page is only loaded once the previous page has been consumed.
+func (p *pager) pages(ctx context.Context, prefetch int) iter.Seq[indexPage] {
+	if prefetch <= 0 {
+		return func(yield func(indexPage) bool) {
+			for !p.done && ctx.Err() == nil {
+				if !yield(p.next(ctx)) {
+					return
+				}
+			}
+		}
+	}
+
+	return func(yield func(indexPage) bool) {
+		// a page waiting to be sent is also loaded ahead, so the buffer holds one page fewer
+		loaded := make(chan indexPage, prefetch-1)
+		go func() {
+			defer close(loaded)
+			for !p.done {
+				select {
+				case loaded <- p.next(ctx):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		for page := range loaded {
+			if !yield(page) {
+				return
+			}
+		}
+	}
+}
+
+// next loads the page at the current position, and moves the position past it
+func (p *pager) next(ctx context.Context) indexPage {
+	pageOpt := Limit(p.perPage, p.pageNumber)
+	if p.afterID != "" {
+		pageOpt = LimitAfter(p.perPage, p.afterID)
+	}
+	page := p.load(ctx, pageOpt)
+	if page.err != nil || len(page.entities) == 0 {
+		p.done = true
+		return page
+	}
+	p.fetched += len(page.entities)
+
+	// the server may cap the page size, so a short page only ends the iteration without a total or cursor
+	if p.useTotal && page.total > 0 {
+		p.done = p.fetched >= int(page.total)
+	} else if page.lastID == "" && len(page.entities) < int(p.perPage) {
+		p.done = true
+	}
+
+	// prefer the cursor, falling back to page numbers when the server does not provide one
+	p.afterID = page.lastID
+	p.pageNumber++
+	return page
+}
+
+// IterateAs unmarshals each entity from an iterator into T
+func IterateAs[T any](seq iter.Seq2[*proto.EntityResponse, error]) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		for entity, err := range seq {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			dst := new(T)
+			if err = Unmarshal(entity, dst); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(dst, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package keystone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+// iterateTestIndex serves total entities in pages, using the after ID cursor when withCursor is set
+func iterateTestIndex(total int, withCursor bool) func(context.Context, *proto.QueryIndexRequest) (*proto.QueryIndexResponse, error) {
+	return func(_ context.Context, req *proto.QueryIndexRequest) (*proto.QueryIndexResponse, error) {
+		start := 0
+		if req.GetPage().GetAfterId() != "" {
+			_, _ = fmt.Sscanf(req.GetPage().GetAfterId(), "e%d", &start)
+			start++
+		} else if req.GetPage().GetPageNumber() > 1 {
+			start = int(req.GetPage().GetPerPage() * (req.GetPage().GetPageNumber() - 1))
+		}
+
+		resp := &proto.QueryIndexResponse{TotalResults: int32(total)}
+		for i := start; i < total && i < start+int(req.GetPage().GetPerPage()); i++ {
+			resp.Entities = append(resp.Entities, &proto.EntityResponse{
+				Entity:     &proto.Entity{EntityId: fmt.Sprintf("e%d", i)},
+				Properties: []*proto.EntityProperty{{Property: "name", Value: &proto.Value{Text: fmt.Sprintf("name-%d", i)}}},
+			})
+		}
+		if withCursor && len(resp.Entities) > 0 {
+			resp.LastId = resp.Entities[len(resp.Entities)-1].GetEntity().GetEntityId()
+		}
+		return resp, nil
+	}
+}
+
+func TestActorIterateIndex(t *testing.T) {
+	for _, withCursor := range []bool{true, false} {
+		t.Run(fmt.Sprintf("cursor=%t", withCursor), func(t *testing.T) {
+			actor, mock, cleanup := newQueryIndexTestActor(t)
+			defer cleanup()
+
+			calls := 0
+			serve := iterateTestIndex(25, withCursor)
+			mock.QueryIndexFunc = func(ctx context.Context, req *proto.QueryIndexRequest) (*proto.QueryIndexResponse, error) {
+				calls++
+				return serve(ctx, req)
+			}
+
+			seen := 0
+			for entity, err := range actor.IterateIndex(context.Background(), "iterate-entity", []string{"name"}, Limit(10, 1), Prefetch(2)) {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if entity.GetEntity().GetEntityId() != fmt.Sprintf("e%d", seen) {
+					t.Fatalf("expected e%d, got %s", seen, entity.GetEntity().GetEntityId())
+				}
+				seen++
+			}
+			if seen != 25 {
+				t.Errorf("expected 25 entities, got %d", seen)
+			}
+			if calls != 3 {
+				t.Errorf("expected 3 page requests, got %d", calls)
+			}
+		})
+	}
+}
+
+func TestActorIterateIndex_CappedPageSize(t *testing.T) {
+	for _, withCursor := range []bool{true, false} {
+		t.Run(fmt.Sprintf("cursor=%t", withCursor), func(t *testing.T) {
+			actor, mock, cleanup := newQueryIndexTestActor(t)
+			defer cleanup()
+
+			// the server returns at most 4 entities per page, regardless of the page size requested
+			serve := iterateTestIndex(10, withCursor)
+			mock.QueryIndexFunc = func(ctx context.Context, req *proto.QueryIndexRequest) (*proto.QueryIndexResponse, error) {
+				req.Page.PerPage = min(req.GetPage().GetPerPage(), 4)
+				return serve(ctx, req)
+			}
+
+			seen := 0
+			for _, err := range actor.IterateIndex(context.Background(), "iterate-entity", nil, Limit(10, 1)) {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				seen++
+			}
+			if seen != 10 {
+				t.Errorf("expected 10 entities, got %d", seen)
+			}
+		})
+	}
+}
+
+func TestActorIterateIndex_Error(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	serve := iterateTestIndex(25, true)
+	mock.QueryIndexFunc = func(ctx context.Context, req *proto.QueryIndexRequest) (*proto.QueryIndexResponse, error) {
+		if req.GetPage().GetAfterId() != "" {
+			return nil, errors.New("index unavailable")
+		}
+		return serve(ctx, req)
+	}
+
+	seen := 0
+	var lastErr error
+	for _, err := range actor.IterateIndex(context.Background(), "iterate-entity", nil, Limit(10, 1)) {
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		seen++
+	}
+	if seen != 10 {
+		t.Errorf("expected 10 entities before the error, got %d", seen)
+	}
+	if lastErr == nil {
+		t.Error("expected an error from the second page")
+	}
+}
+
+func TestIterateAs(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	mock.QueryIndexFunc = iterateTestIndex(5, true)
+
+	var names []string
+	for user, err := range IterateAs[queryTestUser](actor.IterateIndex(context.Background(), "query-test-user", []string{"name"})) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		names = append(names, user.Name)
+		if len(names) == 3 {
+			break
+		}
+	}
+	if len(names) != 3 || names[2] != "name-2" {
+		t.Errorf("expected to stop after 3 users, got %v", names)
+	}
+}
+
+func TestActorIterateIndex_StartPage(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	mock.QueryIndexFunc = iterateTestIndex(25, false)
+
+	var ids []string
+	for entity, err := range actor.IterateIndex(context.Background(), "iterate-entity", nil, Limit(10, 2)) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, entity.GetEntity().GetEntityId())
+	}
+	if len(ids) != 15 || ids[0] != "e10" || ids[14] != "e24" {
+		t.Errorf("expected the iteration to start on page 2, got %v", ids)
+	}
+}
+
+func TestActorIterateIndex_NoPrefetch(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	var calls atomic.Int32
+	serve := iterateTestIndex(25, true)
+	mock.QueryIndexFunc = func(ctx context.Context, req *proto.QueryIndexRequest) (*proto.QueryIndexResponse, error) {
+		calls.Add(1)
+		return serve(ctx, req)
+	}
+
+	seen := 0
+	for _, err := range actor.IterateIndex(context.Background(), "iterate-entity", nil, Limit(10, 1)) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		seen++
+		if seen == 10 && calls.Load() != 1 {
+			t.Errorf("expected the next page not to be loaded before the first is consumed, got %d requests", calls.Load())
+		}
+	}
+	if seen != 25 {
+		t.Errorf("expected 25 entities, got %d", seen)
+	}
+}
+
+func TestActorIterateFind(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	mock.QueryIndexFunc = iterateTestIndex(25, true)
+	var finds atomic.Int32
+	mock.FindFunc = func(_ context.Context, req *proto.FindRequest) (*proto.FindResponse, error) {
+		finds.Add(1)
+		resp := &proto.FindResponse{}
+		for _, id := range req.GetEntityIds() {
+			resp.Entities = append(resp.Entities, &proto.EntityResponse{Entity: &proto.Entity{EntityId: id}})
+		}
+		return resp, nil
+	}
+
+	seen := 0
+	for entity, err := range actor.IterateFind(context.Background(), "iterate-entity", WithProperties("name"), Limit(10, 1)) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if entity.GetEntity().GetEntityId() != fmt.Sprintf("e%d", seen) {
+			t.Fatalf("expected e%d, got %s", seen, entity.GetEntity().GetEntityId())
+		}
+		seen++
+	}
+	if seen != 25 {
+		t.Errorf("expected 25 entities, got %d", seen)
+	}
+	if finds.Load() != 3 {
+		t.Errorf("expected a find per page, got %d", finds.Load())
+	}
+}
@@ -107,10 +107,9 @@ func (a *Actor) mutateWithProperties(ctx context.Context, src interface{}, props
 	var onSuccess []func() error
 	var onSuccessMutate []func(response *proto.MutateResponse)
 
-	schema, registered := a.connection.registerType(src)
-	if !registered {
-		// wait for the type to be registered with the keystone server
-		a.connection.SyncSchema().Wait()
+	schema, err := a.connection.ensureType(ctx, src)
+	if err != nil {
+		return err
 	}
 
 	mutation := &proto.Mutation{}
@@ -173,6 +172,13 @@ func (a *Actor) mutateWithProperties(ctx context.Context, src interface{}, props
 	}
 
 	for _, option := range options {
+		if cond, ok := option.(ifUnchanged); ok {
+			cond = cond.forSource(src)
+			if cond.lastUpdated.IsZero() && entityID != "" {
+				return ErrUnknownLastUpdate
+			}
+			option = cond
+		}
 		option.apply(m)
 	}
 
@@ -0,0 +1,129 @@
+package keystone
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const defaultBatchConcurrency = 10
+
+var ErrBatchAborted = errors.New("batch aborted after an earlier mutation failed")
+
+// MutateResult is the outcome of mutating a single entity within a batch
+type MutateResult struct {
+	Entity   any
+	ID       ID
+	Err      error
+	Extended *Error // keystone error details, when the server rejected the mutation
+}
+
+// MutateResults are the results of a batch mutation, in the order the entities were given
+type MutateResults []MutateResult
+
+// Failed returns the results that did not mutate successfully
+func (r MutateResults) Failed() MutateResults {
+	var failed MutateResults
+	for _, res := range r {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// Err returns all mutation errors joined, or nil if every mutation succeeded
+func (r MutateResults) Err() error {
+	var errs []error
+	for _, res := range r.Failed() {
+		errs = append(errs, res.Err)
+	}
+	return errors.Join(errs...)
+}
+
+type batchOptions struct {
+	concurrency   int
+	failFast      bool
+	mutateOptions []MutateOption
+}
+
+// BatchOption configures MutateBatch
+type BatchOption func(*batchOptions)
+
+// BatchConcurrency sets the number of mutations MutateBatch will run at once
+func BatchConcurrency(concurrency int) BatchOption {
+	return func(o *batchOptions) {
+		o.concurrency = max(concurrency, 1)
+	}
+}
+
+// BatchFailFast stops MutateBatch from starting further mutations after the first failure
+func BatchFailFast() BatchOption {
+	return func(o *batchOptions) {
+		o.failFast = true
+	}
+}
+
+// BatchMutateOptions sets the mutate options shared by every mutation in the batch, such as WithMutationComment
+func BatchMutateOptions(options ...MutateOption) BatchOption {
+	return func(o *batchOptions) {
+		o.mutateOptions = append(o.mutateOptions, options...)
+	}
+}
+
+// MutateBatch mutates each entity with Mutate and returns a result per entity
+func (a *Actor) MutateBatch(ctx context.Context, entities []any, opts ...BatchOption) MutateResults {
+	options := batchOptions{concurrency: defaultBatchConcurrency}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	results := make(MutateResults, len(entities))
+
+	for i, src := range entities {
+		results[i].Entity = src
+	}
+
+	batchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := make(chan struct{}, options.concurrency)
+	wg := sync.WaitGroup{}
+	for i, src := range entities {
+		select {
+		case sem <- struct{}{}:
+		case <-batchCtx.Done():
+			results[i].Err = abortedError(ctx)
+			continue
+		}
+		if batchCtx.Err() != nil {
+			<-sem
+			results[i].Err = abortedError(ctx)
+			continue
+		}
+
+		wg.Add(1)
+		go func(res *MutateResult, src any) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			res.Err = a.Mutate(batchCtx, src, options.mutateOptions...)
+			if entity, ok := src.(Entity); ok {
+				res.ID = entity.GetKeystoneID()
+			}
+			errors.As(res.Err, &res.Extended)
+			if res.Err != nil && options.failFast {
+				cancel()
+			}
+		}(&results[i], src)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func abortedError(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return ErrBatchAborted
+}
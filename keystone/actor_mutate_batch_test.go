@@ -0,0 +1,88 @@
+package keystone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+type batchTestEntity struct {
+	BaseEntity
+	Name string
+}
+
+func batchTestMutate(calls *int32) func(context.Context, *proto.MutateRequest) (*proto.MutateResponse, error) {
+	return func(_ context.Context, req *proto.MutateRequest) (*proto.MutateResponse, error) {
+		n := atomic.AddInt32(calls, 1)
+		if req.GetMutation().GetComment() != "import" {
+			return nil, fmt.Errorf("expected shared comment, got %q", req.GetMutation().GetComment())
+		}
+		for _, p := range req.GetMutation().GetProperties() {
+			if p.GetProperty() == "name" && p.GetValue().GetText() == "bad" {
+				return &proto.MutateResponse{ErrorCode: 400, ErrorMessage: "invalid name"}, nil
+			}
+		}
+		return &proto.MutateResponse{Success: true, EntityId: fmt.Sprintf("e%d", n)}, nil
+	}
+}
+
+func TestActorMutateBatch(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	var calls int32
+	mock.MutateFunc = batchTestMutate(&calls)
+
+	good := &batchTestEntity{Name: "good"}
+	good.AddLabel("imported", "true")
+	bad := &batchTestEntity{Name: "bad"}
+
+	results := actor.MutateBatch(context.Background(), []any{good, bad, &batchTestEntity{Name: "other"}},
+		BatchMutateOptions(WithMutationComment("import")), BatchConcurrency(2))
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0].Err != nil || results[0].ID == "" || results[0].Entity != good {
+		t.Errorf("expected the first entity to succeed with an ID, got %+v", results[0])
+	}
+	if len(good.GetLabels()) != 0 {
+		t.Error("expected labels to be cleared after a successful mutation")
+	}
+	if results[1].Extended == nil || results[1].Extended.ErrorCode != 400 {
+		t.Errorf("expected extended error details for the second entity, got %+v", results[1])
+	}
+	if failed := results.Failed(); len(failed) != 1 || failed[0].Entity != bad {
+		t.Errorf("expected only the bad entity to fail, got %v", failed)
+	}
+	if results.Err() == nil {
+		t.Error("expected a joined error")
+	}
+}
+
+func TestActorMutateBatch_FailFast(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	var calls int32
+	mock.MutateFunc = batchTestMutate(&calls)
+
+	entities := []any{&batchTestEntity{Name: "bad"}}
+	for i := 0; i < 5; i++ {
+		entities = append(entities, &batchTestEntity{Name: "good"})
+	}
+
+	results := actor.MutateBatch(context.Background(), entities, BatchMutateOptions(WithMutationComment("import")), BatchConcurrency(1), BatchFailFast())
+	if calls != 1 {
+		t.Errorf("expected a single mutation before aborting, got %d", calls)
+	}
+	for _, res := range results[1:] {
+		if !errors.Is(res.Err, ErrBatchAborted) {
+			t.Errorf("expected ErrBatchAborted, got %v", res.Err)
+		}
+	}
+}
@@ -1,10 +1,14 @@
 package keystone
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"testing"
 	"time"
 
 	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestWithMutationComment(t *testing.T) {
@@ -288,7 +292,8 @@ func TestPrepareUploadsObserveMutation(t *testing.T) {
 
 	// Test successful response
 	response := &proto.MutateResponse{
-		Success: true,
+		Success:  true,
+		EntityId: "entity-1",
 		SignedObjectUrls: []*proto.EntityObject{
 			{
 				Path:          "path/to/file.txt",
@@ -306,6 +311,9 @@ func TestPrepareUploadsObserveMutation(t *testing.T) {
 	if obj.uploadHeaders["Content-Type"] != "application/octet-stream" {
 		t.Errorf("Expected Content-Type header, got %v", obj.uploadHeaders)
 	}
+	if obj.GetEntityID() != "entity-1" {
+		t.Errorf("Expected entity ID to be set, got '%s'", obj.GetEntityID())
+	}
 }
 
 func TestPrepareUploadsObserveMutationFailure(t *testing.T) {
@@ -602,3 +610,79 @@ func TestWithStateForbidsRemovedState(t *testing.T) {
 func TestWithStateImplementsInterface(t *testing.T) {
 	var _ MutateOption = WithState(proto.EntityState_Active)
 }
+
+func TestIfUnchangedSince(t *testing.T) {
+	lastUpdated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	req := &proto.MutateRequest{Mutation: &proto.Mutation{}}
+
+	IfUnchangedSince(lastUpdated).apply(req)
+
+	if len(req.Where) != 1 {
+		t.Fatalf("Expected 1 where filter, got %d", len(req.Where))
+	}
+	if req.Where[0].Property != PropertyLastUpdate || req.Where[0].Operator != proto.Operator_Equal {
+		t.Errorf("Expected %s equal filter, got %s %v", PropertyLastUpdate, req.Where[0].Property, req.Where[0].Operator)
+	}
+	if !req.Where[0].Values[0].GetTime().AsTime().Equal(lastUpdated) {
+		t.Errorf("Expected last update %v, got %v", lastUpdated, req.Where[0].Values[0].GetTime().AsTime())
+	}
+}
+
+func TestIfUnchangedSkipsNewEntities(t *testing.T) {
+	req := &proto.MutateRequest{Mutation: &proto.Mutation{}}
+
+	IfUnchanged().(ifUnchanged).forSource(&queryTestUser{}).apply(req)
+
+	if len(req.Where) != 0 {
+		t.Errorf("Expected no where filters for a new entity, got %d", len(req.Where))
+	}
+}
+
+func TestMutateIfUnchangedConflict(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	lastUpdated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mock.RetrieveFunc = func(_ context.Context, req *proto.EntityRequest) (*proto.EntityResponse, error) {
+		return &proto.EntityResponse{
+			Entity:     &proto.Entity{EntityId: req.GetEntityId(), LastUpdate: timestamppb.New(lastUpdated)},
+			Properties: []*proto.EntityProperty{{Property: "name", Value: &proto.Value{Text: "Amy"}}},
+		}, nil
+	}
+	mock.MutateFunc = func(_ context.Context, req *proto.MutateRequest) (*proto.MutateResponse, error) {
+		if len(req.GetWhere()) != 1 || !req.GetWhere()[0].GetValues()[0].GetTime().AsTime().Equal(lastUpdated) {
+			t.Errorf("Expected last update precondition, got %v", req.GetWhere())
+		}
+		return &proto.MutateResponse{ErrorCode: http.StatusConflict, ErrorMessage: "Validation conditions not met"}, nil
+	}
+
+	user := &queryTestUser{}
+	if err := actor.GetByID(context.Background(), "user-1", user, WithProperties("name")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user.Name = "Bob"
+
+	err := actor.Mutate(context.Background(), user, IfUnchanged())
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict, got %v", err)
+	}
+	var ksErr *Error
+	if !errors.As(err, &ksErr) || ksErr.ErrorCode != http.StatusConflict {
+		t.Errorf("Expected the keystone error to be preserved, got %v", err)
+	}
+
+	// a unique property conflict cannot be resolved by retrying, so is not ErrConflict
+	mock.MutateFunc = func(_ context.Context, req *proto.MutateRequest) (*proto.MutateResponse, error) {
+		return &proto.MutateResponse{ErrorCode: http.StatusConflict, ErrorMessage: "unique property email already exists"}, nil
+	}
+	err = actor.Mutate(context.Background(), user, IfUnchanged())
+	if errors.Is(err, ErrConflict) || !errors.Is(err, ErrUniqueConflict) {
+		t.Errorf("Expected ErrUniqueConflict and not ErrConflict, got %v", err)
+	}
+
+	unknown := &queryTestUser{}
+	unknown.SetKeystoneID("user-2")
+	if err = actor.Mutate(context.Background(), unknown, IfUnchanged()); !errors.Is(err, ErrUnknownLastUpdate) {
+		t.Errorf("Expected ErrUnknownLastUpdate, got %v", err)
+	}
+}
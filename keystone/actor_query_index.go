@@ -13,6 +13,15 @@ func (a *Actor) List(ctx context.Context, entityType string, retrieveProperties
 
 // QueryIndex returns a list of entities within the index
 func (a *Actor) QueryIndex(ctx context.Context, entityType string, retrieveProperties []string, options ...FindOption) ([]*proto.EntityResponse, error) {
+	resp, err := a.QueryIndexWithResult(ctx, entityType, retrieveProperties, options...)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Entities, nil
+}
+
+// QueryIndexWithResult returns the full index response, including the total results and last ID
+func (a *Actor) QueryIndexWithResult(ctx context.Context, entityType string, retrieveProperties []string, options ...FindOption) (*proto.QueryIndexResponse, error) {
 	listRequest := &proto.QueryIndexRequest{
 		Authorization: a.Authorization(),
 		Schema:        &proto.Key{Key: entityType, Source: a.Authorization().Source},
@@ -45,11 +54,8 @@ func (a *Actor) QueryIndex(ctx context.Context, entityType string, retrievePrope
 	listRequest.Page = &proto.PageRequest{
 		PerPage:    fReq.PerPage,
 		PageNumber: fReq.PageNumber,
+		AfterId:    fReq.AfterID,
 	}
 
-	resp, err := a.connection.QueryIndex(ctx, listRequest)
-	if err != nil {
-		return nil, err
-	}
-	return resp.Entities, nil
+	return a.connection.QueryIndex(ctx, listRequest)
 }
@@ -18,6 +18,9 @@ func newQueryIndexTestActor(t *testing.T) (*Actor, *MockServer, func()) {
 
 	conn, mock, _, server := MockConnection()
 	go func() { _ = server.Serve(mockListener) }()
+	mock.DefineFunc = func(_ context.Context, req *proto.SchemaRequest) (*proto.Schema, error) {
+		return req.GetSchema(), nil
+	}
 	actor := conn.Actor("query-1", "127.0.0.1", "user-1", "go-test")
 
 	return &actor, mock, func() {
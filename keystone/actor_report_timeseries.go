@@ -15,10 +15,9 @@ func (a *Actor) ReportTimeSeries(ctx context.Context, src interface{}) error {
 		return errors.New("mutate requires a pointer to a struct")
 	}
 
-	schema, registered := a.connection.registerType(src)
-	if !registered {
-		// wait for the type to be registered with the keystone server
-		a.connection.SyncSchema().Wait()
+	schema, err := a.connection.ensureType(ctx, src)
+	if err != nil {
+		return err
 	}
 
 	var inputTime *timestamppb.Timestamp
@@ -15,10 +15,8 @@ import (
 	"github.com/packaged/logger/v3/logger"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/keepalive"
 )
 
 // Connection is a connection to a keystone server
@@ -29,8 +27,13 @@ type Connection struct {
 	timeLogConfig *logger.TimedLogConfig
 	appID         proto.VendorApp
 	token         string
-	typeRegister  map[reflect.Type]*TypeDefinition
-	registerQueue map[reflect.Type]bool // true if the type is processing registration
+	typeRegister  map[reflect.Type]*typeRegistration
+	typeLock      sync.Mutex
+	middleware    []Middleware
+	grpcConn      *grpc.ClientConn // set when dialed by the SDK
+	tokenSource   TokenSource
+	tokenLock     sync.RWMutex
+	schemaSource  SchemaSource
 }
 
 func transportCredentials(endpoint string) grpc.DialOption {
@@ -44,42 +47,11 @@ func transportCredentials(endpoint string) grpc.DialOption {
 }
 
 func DefaultConnection(host, port, vendorID, appID, accessToken string) *Connection {
-	ksGrpcConn, err := grpc.NewClient(host+":"+port,
-		transportCredentials(host+":"+port),
-		grpc.WithIdleTimeout(time.Minute*5),
-		grpc.WithConnectParams(grpc.ConnectParams{
-			Backoff: backoff.Config{
-				BaseDelay:  200 * time.Millisecond,
-				Multiplier: 1.6,
-				Jitter:     0.2,
-				MaxDelay:   5 * time.Second,
-			},
-			MinConnectTimeout: 3 * time.Second,
-		}),
-		grpc.WithKeepaliveParams(keepalive.ClientParameters{
-			Time:                30 * time.Second,
-			Timeout:             10 * time.Second,
-			PermitWithoutStream: true,
-		}),
-		grpc.WithDefaultServiceConfig(`{
-			"methodConfig": [{
-				"name": [{"service": ""}],
-				"waitForReady": true,
-				"retryPolicy": {
-					"maxAttempts": 3,
-					"initialBackoff": "0.5s",
-					"maxBackoff": "5s",
-					"backoffMultiplier": 2,
-					"retryableStatusCodes": ["UNAVAILABLE"]
-				}
-			}]
-		}`),
-	)
+	conn, err := NewConnectionWithOptions(host+":"+port, vendorID, appID, accessToken)
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
-
-	return NewConnection(proto.NewKeystoneClient(ksGrpcConn), vendorID, appID, accessToken)
+	return conn
 }
 
 // NewConnection creates a new connection to a keystone server
@@ -91,12 +63,11 @@ func NewConnection(client proto.KeystoneClient, vendorID, appID, accessToken str
 			InfoDuration:  2 * time.Second,
 			DebugDuration: 100 * time.Millisecond,
 		},
-		logger:        logger.I(),
-		client:        client,
-		appID:         proto.VendorApp{VendorId: vendorID, AppId: appID},
-		token:         accessToken,
-		typeRegister:  make(map[reflect.Type]*TypeDefinition),
-		registerQueue: make(map[reflect.Type]bool),
+		logger:       logger.I(),
+		client:       client,
+		appID:        proto.VendorApp{VendorId: vendorID, AppId: appID},
+		token:        accessToken,
+		typeRegister: make(map[reflect.Type]*typeRegistration),
 	}
 }
 
@@ -106,7 +77,7 @@ func (c *Connection) DirectClient() proto.KeystoneClient { return c.client }
 func (c *Connection) authorization() *proto.Authorization {
 	return &proto.Authorization{
 		Source: &c.appID,
-		Token:  c.token,
+		Token:  c.accessToken(),
 	}
 }
 
@@ -144,404 +115,199 @@ func (c *Connection) RegisterTypes(types ...interface{}) int {
 	return registered
 }
 
-// registerType returns true if the type is already registered
-func (c *Connection) registerType(t interface{}) (TypeDefinition, bool) {
-	typ := reflect.TypeOf(t)
-
-	if typ.Kind() == reflect.Ptr {
-		typ = typ.Elem()
-	}
-
-	sDef, ok := c.typeRegister[typ]
-	if !ok {
-		newDef := Define(t)
-		c.typeRegister[typ] = &newDef
-		c.registerQueue[typ] = false
-		return newDef, false
-	}
-	return *sDef, true
-}
-
-// SyncSchema syncs the schema with the server
-func (c *Connection) SyncSchema() *sync.WaitGroup {
-	wg := &sync.WaitGroup{}
-	wg.Add(len(c.registerQueue))
-	go func() {
-		for typ, processing := range c.registerQueue {
-			if !processing {
-				if toRegister, ok := c.typeRegister[typ]; ok {
-					resp, err := c.Define(context.Background(), &proto.SchemaRequest{
-						Authorization: c.authorization(),
-						Schema:        toRegister.Schema(),
-						//TODO: Handle views
-						//Views:         toRegister.definition.Views,
-					})
-
-					if err == nil {
-						c.typeRegister[typ].id = resp.GetId()
-						c.typeRegister[typ].Name = resp.GetName()
-						c.typeRegister[typ].Type = resp.GetType()
-						//TODO: Do we need this back/replaced?
-						//c.typeRegister[typ].Properties = resp.GetProperties()
-						c.typeRegister[typ].Options = resp.GetOptions()
-						c.typeRegister[typ].Singular = resp.GetSingular()
-						c.typeRegister[typ].Plural = resp.GetPlural()
-					} else {
-						c.logger.Error("Failed to define schema", zap.Error(err))
-					}
-
-				}
-				wg.Done()
-			}
-		}
-	}()
-	return wg
-}
-
 func (c *Connection) Define(ctx context.Context, in *proto.SchemaRequest, opts ...grpc.CallOption) (*proto.Schema, error) {
-	tl := c.timeLogConfig.NewLog("Define", zap.String("schema", in.GetSchema().GetType()))
-	resp, err := c.client.Define(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "Define", in, c.client.Define, opts, zap.String("schema", in.GetSchema().GetType()))
 }
 
 func (c *Connection) Mutate(ctx context.Context, in *proto.MutateRequest, opts ...grpc.CallOption) (*proto.MutateResponse, error) {
-	tl := c.timeLogConfig.NewLog("Mutate", zap.String("EntityId", in.GetEntityId()))
-	resp, err := c.client.Mutate(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "Mutate", in, c.client.Mutate, opts, zap.String("EntityId", in.GetEntityId()))
 }
 
 func (c *Connection) ReportTimeSeries(ctx context.Context, in *proto.ReportTimeSeriesRequest, opts ...grpc.CallOption) (*proto.MutateResponse, error) {
-	tl := c.timeLogConfig.NewLog("ReportTimeSeries", zap.String("EntityId", in.GetEntityId()))
-	resp, err := c.client.ReportTimeSeries(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "ReportTimeSeries", in, c.client.ReportTimeSeries, opts, zap.String("EntityId", in.GetEntityId()))
 }
 
 func (c *Connection) Retrieve(ctx context.Context, in *proto.EntityRequest, opts ...grpc.CallOption) (*proto.EntityResponse, error) {
-	tl := c.timeLogConfig.NewLog("Retrieve", zap.String("EntityId", in.GetEntityId()))
-	resp, err := c.client.Retrieve(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "Retrieve", in, c.client.Retrieve, opts, zap.String("EntityId", in.GetEntityId()))
 }
 
 func (c *Connection) Log(ctx context.Context, in *proto.LogRequest, opts ...grpc.CallOption) (*proto.LogResponse, error) {
-	tl := c.timeLogConfig.NewLog("Logs", zap.String("EntityId", in.GetEntityId()))
-	resp, err := c.client.Log(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "Log", in, c.client.Log, opts, zap.String("EntityId", in.GetEntityId()))
 }
 
 func (c *Connection) Logs(ctx context.Context, in *proto.LogsRequest, opts ...grpc.CallOption) (*proto.LogsResponse, error) {
-	tl := c.timeLogConfig.NewLog("Logs", zap.String("EntityId", in.GetEntityId()))
-	resp, err := c.client.Logs(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "Logs", in, c.client.Logs, opts, zap.String("EntityId", in.GetEntityId()))
 }
 
 func (c *Connection) Events(ctx context.Context, in *proto.EventRequest, opts ...grpc.CallOption) (*proto.EventsResponse, error) {
-	tl := c.timeLogConfig.NewLog("Events", zap.String("EntityId", in.GetEntityId()))
-	resp, err := c.client.Events(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "Events", in, c.client.Events, opts, zap.String("EntityId", in.GetEntityId()))
 }
 
 func (c *Connection) Find(ctx context.Context, in *proto.FindRequest, opts ...grpc.CallOption) (*proto.FindResponse, error) {
-	tl := c.timeLogConfig.NewLog("Find", zap.String("schema", in.GetSchema().GetKey()))
-	resp, err := c.client.Find(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "Find", in, c.client.Find, opts, zap.String("schema", in.GetSchema().GetKey()))
 }
 
 func (c *Connection) List(ctx context.Context, in *proto.ListRequest, opts ...grpc.CallOption) (*proto.ListResponse, error) {
-	tl := c.timeLogConfig.NewLog("List", zap.String("schema", in.GetSchema().GetKey()))
-	resp, err := c.client.List(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "List", in, c.client.List, opts, zap.String("schema", in.GetSchema().GetKey()))
 }
 
 func (c *Connection) GroupCount(ctx context.Context, in *proto.GroupCountRequest, opts ...grpc.CallOption) (*proto.GroupCountResponse, error) {
-	tl := c.timeLogConfig.NewLog("GroupCount", zap.String("schema", in.GetSchema().GetKey()))
-	resp, err := c.client.GroupCount(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "GroupCount", in, c.client.GroupCount, opts, zap.String("schema", in.GetSchema().GetKey()))
 }
 
 func (c *Connection) DailyEntities(ctx context.Context, in *proto.DailyEntityRequest, opts ...grpc.CallOption) (*proto.DailyEntityResponse, error) {
-	tl := c.timeLogConfig.NewLog("DailyEntities", zap.String("schema", in.GetSchema().GetKey()))
-	resp, err := c.client.DailyEntities(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "DailyEntities", in, c.client.DailyEntities, opts, zap.String("schema", in.GetSchema().GetKey()))
 }
 func (c *Connection) SchemaStatistics(ctx context.Context, in *proto.SchemaStatisticsRequest, opts ...grpc.CallOption) (*proto.SchemaStatisticsResponse, error) {
-	tl := c.timeLogConfig.NewLog("SchemaStatistics", zap.String("schema", in.GetSchema().GetKey()))
-	resp, err := c.client.SchemaStatistics(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "SchemaStatistics", in, c.client.SchemaStatistics, opts, zap.String("schema", in.GetSchema().GetKey()))
 }
 
 func (c *Connection) ChartTimeSeries(ctx context.Context, in *proto.ChartTimeSeriesRequest, opts ...grpc.CallOption) (*proto.ChartTimeSeriesResponse, error) {
-	tl := c.timeLogConfig.NewLog("ChartTimeSeries", zap.String("schema", in.GetSchema().GetKey()))
-	resp, err := c.client.ChartTimeSeries(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "ChartTimeSeries", in, c.client.ChartTimeSeries, opts, zap.String("schema", in.GetSchema().GetKey()))
 }
 
 func (c *Connection) ShareView(ctx context.Context, in *proto.ShareViewRequest, opts ...grpc.CallOption) (*proto.SharedViewResponse, error) {
-	tl := c.timeLogConfig.NewLog("ShareView", zap.String("EntityId", in.GetEntityId()))
-	resp, err := c.client.ShareView(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "ShareView", in, c.client.ShareView, opts, zap.String("EntityId", in.GetEntityId()))
 }
 
 func (c *Connection) SharedViews(ctx context.Context, in *proto.SharedViewsRequest, opts ...grpc.CallOption) (*proto.SharedViewsResponse, error) {
-	tl := c.timeLogConfig.NewLog("ShareViews", zap.String("EntityId", in.GetEntityId()))
-	resp, err := c.client.SharedViews(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "SharedViews", in, c.client.SharedViews, opts, zap.String("EntityId", in.GetEntityId()))
 }
 
 func (c *Connection) RateLimit(ctx context.Context, in *proto.RateLimitRequest, opts ...grpc.CallOption) (*proto.RateLimitResponse, error) {
-	tl := c.timeLogConfig.NewLog("RateLimit", zap.String("Key", in.GetKey()))
-	resp, err := c.client.RateLimit(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "RateLimit", in, c.client.RateLimit, opts, zap.String("Key", in.GetKey()))
 }
 
 func (c *Connection) AKVGet(ctx context.Context, in *proto.AKVGetRequest, opts ...grpc.CallOption) (*proto.AKVGetResponse, error) {
-	tl := c.timeLogConfig.NewLog("AKVGet", zap.String("App", in.GetAuthorization().GetSource().String()))
-	resp, err := c.client.AKVGet(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "AKVGet", in, c.client.AKVGet, opts, zap.String("App", in.GetAuthorization().GetSource().String()))
 }
 func (c *Connection) AKVPut(ctx context.Context, in *proto.AKVPutRequest, opts ...grpc.CallOption) (*proto.GenericResponse, error) {
-	tl := c.timeLogConfig.NewLog("AKVPut", zap.String("App", in.GetAuthorization().GetSource().String()))
-	resp, err := c.client.AKVPut(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "AKVPut", in, c.client.AKVPut, opts, zap.String("App", in.GetAuthorization().GetSource().String()))
 }
 func (c *Connection) AKVDel(ctx context.Context, in *proto.AKVDelRequest, opts ...grpc.CallOption) (*proto.GenericResponse, error) {
-	tl := c.timeLogConfig.NewLog("AKVDel", zap.String("App", in.GetAuthorization().GetSource().String()))
-	resp, err := c.client.AKVDel(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "AKVDel", in, c.client.AKVDel, opts, zap.String("App", in.GetAuthorization().GetSource().String()))
 }
 
 func (c *Connection) AKVTimePut(ctx context.Context, in *proto.AKVTimePutRequest, opts ...grpc.CallOption) (*proto.GenericResponse, error) {
-	tl := c.timeLogConfig.NewLog("AKVTimePut", zap.String("App", in.GetAuthorization().GetSource().String()))
-	resp, err := c.client.AKVTimePut(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "AKVTimePut", in, c.client.AKVTimePut, opts, zap.String("App", in.GetAuthorization().GetSource().String()))
 }
 func (c *Connection) AKVTimeGet(ctx context.Context, in *proto.AKVTimeGetRequest, opts ...grpc.CallOption) (*proto.AKVTimeGetResponse, error) {
-	tl := c.timeLogConfig.NewLog("AKVTimeGet", zap.String("App", in.GetAuthorization().GetSource().String()))
-	resp, err := c.client.AKVTimeGet(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "AKVTimeGet", in, c.client.AKVTimeGet, opts, zap.String("App", in.GetAuthorization().GetSource().String()))
 }
 func (c *Connection) AKVTimeDel(ctx context.Context, in *proto.AKVTimeDelRequest, opts ...grpc.CallOption) (*proto.GenericResponse, error) {
-	tl := c.timeLogConfig.NewLog("AKVTimeDel", zap.String("App", in.GetAuthorization().GetSource().String()))
-	resp, err := c.client.AKVTimeDel(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "AKVTimeDel", in, c.client.AKVTimeDel, opts, zap.String("App", in.GetAuthorization().GetSource().String()))
 }
 
 func (c *Connection) EnumPut(ctx context.Context, in *proto.EnumPutRequest, opts ...grpc.CallOption) (*proto.GenericResponse, error) {
-	tl := c.timeLogConfig.NewLog("EnumPut")
-	resp, err := c.client.EnumPut(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "EnumPut", in, c.client.EnumPut, opts)
 }
 func (c *Connection) EnumGet(ctx context.Context, in *proto.EnumGetRequest, opts ...grpc.CallOption) (*proto.EnumGetResponse, error) {
-	tl := c.timeLogConfig.NewLog("EnumGet")
-	resp, err := c.client.EnumGet(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "EnumGet", in, c.client.EnumGet, opts)
 }
 func (c *Connection) EnumDelete(ctx context.Context, in *proto.EnumDeleteRequest, opts ...grpc.CallOption) (*proto.GenericResponse, error) {
-	tl := c.timeLogConfig.NewLog("EnumDelete")
-	resp, err := c.client.EnumDelete(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "EnumDelete", in, c.client.EnumDelete, opts)
 }
 func (c *Connection) EnumList(ctx context.Context, in *proto.EnumListRequest, opts ...grpc.CallOption) (*proto.EnumListResponse, error) {
-	tl := c.timeLogConfig.NewLog("EnumList")
-	resp, err := c.client.EnumList(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "EnumList", in, c.client.EnumList, opts)
 }
 func (c *Connection) EnumReplace(ctx context.Context, in *proto.EnumReplaceRequest, opts ...grpc.CallOption) (*proto.GenericResponse, error) {
-	tl := c.timeLogConfig.NewLog("EnumReplace")
-	resp, err := c.client.EnumReplace(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "EnumReplace", in, c.client.EnumReplace, opts)
 }
 
 func (c *Connection) PiiToken(ctx context.Context, in *proto.PiiTokenRequest, opts ...grpc.CallOption) (*proto.PiiTokenResponse, error) {
-	tl := c.timeLogConfig.NewLog("PiiToken", zap.String("App", in.GetAuthorization().GetSource().String()))
-	resp, err := c.client.PiiToken(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "PiiToken", in, c.client.PiiToken, opts, zap.String("App", in.GetAuthorization().GetSource().String()))
 }
 
 func (c *Connection) PiiAnonymize(ctx context.Context, in *proto.PiiAnonymizeRequest, opts ...grpc.CallOption) (*proto.PiiAnonymizeResponse, error) {
-	tl := c.timeLogConfig.NewLog("PiiAnonymize", zap.String("App", in.GetAuthorization().GetSource().String()))
-	resp, err := c.client.PiiAnonymize(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "PiiAnonymize", in, c.client.PiiAnonymize, opts, zap.String("App", in.GetAuthorization().GetSource().String()))
 }
 
 func (c *Connection) IID(ctx context.Context, in *proto.IIDCreateRequest, opts ...grpc.CallOption) (*proto.IIDResponse, error) {
-	tl := c.timeLogConfig.NewLog("IID", zap.String("App", in.GetAuthorization().GetSource().String()))
-	resp, err := c.client.IID(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "IID", in, c.client.IID, opts, zap.String("App", in.GetAuthorization().GetSource().String()))
 }
 
 func (c *Connection) IIDLookup(ctx context.Context, in *proto.IIDRequest, opts ...grpc.CallOption) (*proto.IIDsResponse, error) {
-	tl := c.timeLogConfig.NewLog("IIDLookup", zap.String("App", in.GetAuthorization().GetSource().String()))
-	resp, err := c.client.IIDLookup(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "IIDLookup", in, c.client.IIDLookup, opts, zap.String("App", in.GetAuthorization().GetSource().String()))
 }
 
 func (c *Connection) EventStream(ctx context.Context, in *proto.EventStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[proto.EventStreamResponse], error) {
-	tl := c.timeLogConfig.NewLog("EventStream", zap.String("App", in.GetAuthorization().GetSource().String()))
-	resp, err := c.client.EventStream(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "EventStream", in, c.client.EventStream, opts, zap.String("App", in.GetAuthorization().GetSource().String()))
 }
 
 func (c *Connection) PushTask(ctx context.Context, in *proto.PushTaskRequest, opts ...grpc.CallOption) (*proto.GenericResponse, error) {
-	tl := c.timeLogConfig.NewLog("PushTask", zap.String("App", in.GetAuthorization().GetSource().String()))
-	resp, err := c.client.PushTask(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "PushTask", in, c.client.PushTask, opts, zap.String("App", in.GetAuthorization().GetSource().String()))
 }
 
 func (c *Connection) TaskStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[proto.TaskAckRequest, proto.TaskResponse], error) {
-	tl := c.timeLogConfig.NewLog("TaskStream")
-	resp, err := c.client.TaskStream(ctx, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "TaskStream", any(nil), func(ctx context.Context, _ any, opts ...grpc.CallOption) (grpc.BidiStreamingClient[proto.TaskAckRequest, proto.TaskResponse], error) {
+		return c.client.TaskStream(ctx, opts...)
+	}, opts)
 }
 
 func (c *Connection) QueryIndex(ctx context.Context, in *proto.QueryIndexRequest, opts ...grpc.CallOption) (*proto.QueryIndexResponse, error) {
-	tl := c.timeLogConfig.NewLog("QueryIndex", zap.String("schema", in.GetSchema().GetKey()))
-	resp, err := c.client.QueryIndex(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "QueryIndex", in, c.client.QueryIndex, opts, zap.String("schema", in.GetSchema().GetKey()))
 }
 
 func (c *Connection) Destroy(ctx context.Context, in *proto.DestroyRequest, opts ...grpc.CallOption) (*proto.DestroyResponse, error) {
-	tl := c.timeLogConfig.NewLog("Destroy", zap.String("schema", in.GetSchema().GetKey()))
-	resp, err := c.client.Destroy(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "Destroy", in, c.client.Destroy, opts, zap.String("schema", in.GetSchema().GetKey()))
 }
 
 func (c *Connection) SQUID(ctx context.Context, in *proto.SquidRequest, opts ...grpc.CallOption) (*proto.SquidResponse, error) {
-	tl := c.timeLogConfig.NewLog("SQUID", zap.String("App", in.GetAuthorization().GetSource().String()), zap.String("key", in.GetSequenceKey()))
-	resp, err := c.client.SQUID(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "SQUID", in, c.client.SQUID, opts, zap.String("App", in.GetAuthorization().GetSource().String()), zap.String("key", in.GetSequenceKey()))
 }
 
 func (c *Connection) SQUIDRecover(ctx context.Context, in *proto.SquidRecoverRequest, opts ...grpc.CallOption) (*proto.SquidResponse, error) {
-	tl := c.timeLogConfig.NewLog("SQUIDRecover", zap.String("App", in.GetAuthorization().GetSource().String()), zap.String("key", in.GetSequenceKey()))
-	resp, err := c.client.SQUIDRecover(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "SQUIDRecover", in, c.client.SQUIDRecover, opts, zap.String("App", in.GetAuthorization().GetSource().String()), zap.String("key", in.GetSequenceKey()))
 }
 
 func (c *Connection) SnapshotReport(ctx context.Context, in *proto.SnapshotReportRequest, opts ...grpc.CallOption) (*proto.MutateResponse, error) {
-	tl := c.timeLogConfig.NewLog("SnapshotReport", zap.String("App", in.GetAuthorization().GetSource().String()), zap.String("eid", in.GetEntityId()))
-	resp, err := c.client.SnapshotReport(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "SnapshotReport", in, c.client.SnapshotReport, opts, zap.String("App", in.GetAuthorization().GetSource().String()), zap.String("eid", in.GetEntityId()))
 }
 
 func (c *Connection) Status(ctx context.Context, in *proto.Authorization, opts ...grpc.CallOption) (*proto.StatusResponse, error) {
-	tl := c.timeLogConfig.NewLog("Status", zap.String("App", in.GetSource().String()))
-	resp, err := c.client.Status(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "Status", in, c.client.Status, opts, zap.String("App", in.GetSource().String()))
 }
 
 func (c *Connection) Lookup(ctx context.Context, in *proto.LookupRequest, opts ...grpc.CallOption) (*proto.LookupResponse, error) {
-	tl := c.timeLogConfig.NewLog("Lookup", zap.String("property", in.GetProperty()))
-	resp, err := c.client.Lookup(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "Lookup", in, c.client.Lookup, opts, zap.String("property", in.GetProperty()))
 }
 
 func (c *Connection) RelayCreateSession(ctx context.Context, in *proto.RelayCreateSessionRequest, opts ...grpc.CallOption) (*proto.RelayCreateSessionResponse, error) {
-	tl := c.timeLogConfig.NewLog("RelayCreateSession", zap.String("App", in.GetAuthorization().GetSource().String()))
-	resp, err := c.client.RelayCreateSession(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "RelayCreateSession", in, c.client.RelayCreateSession, opts, zap.String("App", in.GetAuthorization().GetSource().String()))
 }
 
 func (c *Connection) RelayExtendSession(ctx context.Context, in *proto.RelayExtendSessionRequest, opts ...grpc.CallOption) (*proto.RelayExtendSessionResponse, error) {
-	tl := c.timeLogConfig.NewLog("RelayExtendSession", zap.String("SessionId", in.GetSessionId()))
-	resp, err := c.client.RelayExtendSession(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "RelayExtendSession", in, c.client.RelayExtendSession, opts, zap.String("SessionId", in.GetSessionId()))
 }
 
 func (c *Connection) RelayDestroySession(ctx context.Context, in *proto.RelayDestroySessionRequest, opts ...grpc.CallOption) (*proto.RelayDestroySessionResponse, error) {
-	tl := c.timeLogConfig.NewLog("RelayDestroySession", zap.String("SessionId", in.GetSessionId()))
-	resp, err := c.client.RelayDestroySession(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "RelayDestroySession", in, c.client.RelayDestroySession, opts, zap.String("SessionId", in.GetSessionId()))
 }
 
 func (c *Connection) RelayCreateShortCode(ctx context.Context, in *proto.RelayCreateShortCodeRequest, opts ...grpc.CallOption) (*proto.RelayCreateShortCodeResponse, error) {
-	tl := c.timeLogConfig.NewLog("RelayCreateShortCode", zap.String("SessionId", in.GetSessionId()))
-	resp, err := c.client.RelayCreateShortCode(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "RelayCreateShortCode", in, c.client.RelayCreateShortCode, opts, zap.String("SessionId", in.GetSessionId()))
 }
 
 func (c *Connection) RelayResolveShortCode(ctx context.Context, in *proto.RelayResolveShortCodeRequest, opts ...grpc.CallOption) (*proto.RelayResolveShortCodeResponse, error) {
-	tl := c.timeLogConfig.NewLog("RelayResolveShortCode", zap.String("Code", in.GetCode()))
-	resp, err := c.client.RelayResolveShortCode(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "RelayResolveShortCode", in, c.client.RelayResolveShortCode, opts, zap.String("Code", in.GetCode()))
 }
 
 func (c *Connection) RelayDeleteShortCode(ctx context.Context, in *proto.RelayDeleteShortCodeRequest, opts ...grpc.CallOption) (*proto.RelayDeleteShortCodeResponse, error) {
-	tl := c.timeLogConfig.NewLog("RelayDeleteShortCode", zap.String("Code", in.GetCode()))
-	resp, err := c.client.RelayDeleteShortCode(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "RelayDeleteShortCode", in, c.client.RelayDeleteShortCode, opts, zap.String("Code", in.GetCode()))
 }
 
 func (c *Connection) RelayPublish(ctx context.Context, in *proto.RelayPublishRequest, opts ...grpc.CallOption) (*proto.RelayPublishResponse, error) {
-	tl := c.timeLogConfig.NewLog("RelayPublish", zap.String("SessionId", in.GetSessionId()), zap.String("Type", in.GetType()))
-	resp, err := c.client.RelayPublish(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "RelayPublish", in, c.client.RelayPublish, opts, zap.String("SessionId", in.GetSessionId()), zap.String("Type", in.GetType()))
 }
 
 func (c *Connection) RelayGetPresence(ctx context.Context, in *proto.RelayGetPresenceRequest, opts ...grpc.CallOption) (*proto.RelayGetPresenceResponse, error) {
-	tl := c.timeLogConfig.NewLog("RelayGetPresence", zap.String("SessionId", in.GetSessionId()))
-	resp, err := c.client.RelayGetPresence(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "RelayGetPresence", in, c.client.RelayGetPresence, opts, zap.String("SessionId", in.GetSessionId()))
 }
 
 func (c *Connection) RelayGetSessionMetadata(ctx context.Context, in *proto.RelayGetSessionMetadataRequest, opts ...grpc.CallOption) (*proto.RelayGetSessionMetadataResponse, error) {
-	tl := c.timeLogConfig.NewLog("RelayGetSessionMetadata", zap.String("SessionId", in.GetSessionId()))
-	resp, err := c.client.RelayGetSessionMetadata(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "RelayGetSessionMetadata", in, c.client.RelayGetSessionMetadata, opts, zap.String("SessionId", in.GetSessionId()))
 }
 
 func (c *Connection) RelaySetSessionMetadata(ctx context.Context, in *proto.RelaySetSessionMetadataRequest, opts ...grpc.CallOption) (*proto.RelaySetSessionMetadataResponse, error) {
-	tl := c.timeLogConfig.NewLog("RelaySetSessionMetadata", zap.String("SessionId", in.GetSessionId()))
-	resp, err := c.client.RelaySetSessionMetadata(ctx, in, opts...)
-	c.logger.TimedLog(tl)
-	return resp, err
+	return invoke(c, ctx, "RelaySetSessionMetadata", in, c.client.RelaySetSessionMetadata, opts, zap.String("SessionId", in.GetSessionId()))
 }
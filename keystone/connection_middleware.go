@@ -0,0 +1,71 @@
+package keystone
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// Call describes a single RPC made through a Connection
+type Call struct {
+	Method    string // RPC name, e.g. Mutate
+	Request   any    // Request message, nil for TaskStream
+	Streaming bool   // True for EventStream and TaskStream, where the response is the stream client
+}
+
+// Invoker makes a call to keystone, returning the response message or stream client
+type Invoker func(ctx context.Context, call *Call, opts ...grpc.CallOption) (any, error)
+
+// Middleware wraps an Invoker, e.g. for tracing, metrics, request mutation or retries
+type Middleware func(next Invoker) Invoker
+
+// Use appends middleware to the connection, the first middleware added is the outermost.
+// Middleware should be added before the connection is shared between goroutines.
+func (c *Connection) Use(middleware ...Middleware) {
+	c.middleware = append(c.middleware, middleware...)
+}
+
+func (c *Connection) chain(final Invoker) Invoker {
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		final = c.middleware[i](final)
+	}
+	return final
+}
+
+func invoke[Req any, Resp any](c *Connection, ctx context.Context, method string, in Req, call func(context.Context, Req, ...grpc.CallOption) (Resp, error), opts []grpc.CallOption, fields ...zap.Field) (Resp, error) {
+	final := func(ctx context.Context, rpc *Call, opts ...grpc.CallOption) (any, error) {
+		req, ok := rpc.Request.(Req)
+		if !ok && rpc.Request != nil {
+			return nil, fmt.Errorf("%s: middleware replaced request with %T", rpc.Method, rpc.Request)
+		}
+		ctx, err := c.authorizeCall(ctx, rpc.Request)
+		if err != nil {
+			return nil, err
+		}
+		return call(ctx, req, opts...)
+	}
+
+	rpc := &Call{Method: method, Request: in}
+	_, rpc.Streaming = streamingMethods[method]
+
+	tl := c.timeLogConfig.NewLog(method, fields...)
+	resp, err := c.chain(final)(ctx, rpc, opts...)
+	c.logger.TimedLog(tl)
+
+	if err != nil {
+		var zero Resp
+		return zero, toError(err)
+	}
+	typed, ok := resp.(Resp)
+	if !ok {
+		return typed, fmt.Errorf("%s: middleware returned response %T", method, resp)
+	}
+	return typed, nil
+}
+
+var streamingMethods = map[string]struct{}{
+	"EventStream": {},
+	"TaskStream":  {},
+}
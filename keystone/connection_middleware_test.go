@@ -0,0 +1,125 @@
+package keystone
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConnectionUse_Order(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	conn := actor.connection
+
+	mock.StatusFunc = func(context.Context, *proto.Authorization) (*proto.StatusResponse, error) {
+		return &proto.StatusResponse{}, nil
+	}
+
+	var order []string
+	record := func(name string) Middleware {
+		return func(next Invoker) Invoker {
+			return func(ctx context.Context, call *Call, opts ...grpc.CallOption) (any, error) {
+				order = append(order, name+":"+call.Method)
+				return next(ctx, call, opts...)
+			}
+		}
+	}
+	conn.Use(record("outer"), record("inner"))
+
+	if _, err := conn.Status(context.Background(), conn.authorization()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(order) != 2 || order[0] != "outer:Status" || order[1] != "inner:Status" {
+		t.Errorf("unexpected middleware order %v", order)
+	}
+}
+
+func TestConnectionUse_RequestMutationAndRetry(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	conn := actor.connection
+
+	attempts := 0
+	mock.MutateFunc = func(_ context.Context, req *proto.MutateRequest) (*proto.MutateResponse, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, status.Error(codes.Unavailable, "try again")
+		}
+		return &proto.MutateResponse{Success: true, EntityId: req.GetEntityId()}, nil
+	}
+
+	conn.Use(func(next Invoker) Invoker {
+		return func(ctx context.Context, call *Call, opts ...grpc.CallOption) (any, error) {
+			if req, ok := call.Request.(*proto.MutateRequest); ok {
+				req.EntityId = "rewritten"
+			}
+			resp, err := next(ctx, call, opts...)
+			if status.Code(err) == codes.Unavailable {
+				resp, err = next(ctx, call, opts...)
+			}
+			return resp, err
+		}
+	})
+
+	resp, err := conn.Mutate(context.Background(), &proto.MutateRequest{EntityId: "original"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected a retry, got %d attempts", attempts)
+	}
+	if resp.GetEntityId() != "rewritten" {
+		t.Errorf("expected rewritten entity ID, got %q", resp.GetEntityId())
+	}
+}
+
+func TestConnectionUse_Streaming(t *testing.T) {
+	actor, _, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	conn := actor.connection
+
+	var streamed []string
+	conn.Use(func(next Invoker) Invoker {
+		return func(ctx context.Context, call *Call, opts ...grpc.CallOption) (any, error) {
+			if call.Streaming {
+				streamed = append(streamed, call.Method)
+			}
+			return next(ctx, call, opts...)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, _ = conn.TaskStream(ctx)
+	_, _ = conn.EventStream(ctx, &proto.EventStreamRequest{})
+	_, _ = conn.Status(ctx, conn.authorization())
+
+	if len(streamed) != 2 || streamed[0] != "TaskStream" || streamed[1] != "EventStream" {
+		t.Errorf("expected streaming calls to be flagged, got %v", streamed)
+	}
+}
+
+func TestConnectionUse_WrongResponseType(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	conn := actor.connection
+
+	mock.StatusFunc = func(context.Context, *proto.Authorization) (*proto.StatusResponse, error) {
+		return &proto.StatusResponse{}, nil
+	}
+	conn.Use(func(next Invoker) Invoker {
+		return func(ctx context.Context, call *Call, opts ...grpc.CallOption) (any, error) {
+			_, err := next(ctx, call, opts...)
+			return &proto.GenericResponse{}, err
+		}
+	})
+
+	resp, err := conn.Status(context.Background(), conn.authorization())
+	if err == nil || resp != nil {
+		t.Errorf("expected an error for the wrong response type, got %v %v", resp, err)
+	}
+}
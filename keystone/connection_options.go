@@ -0,0 +1,257 @@
+package keystone
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+// DefaultRetryServiceConfig retries unavailable calls up to 3 times
+const DefaultRetryServiceConfig = `{
+	"methodConfig": [{
+		"name": [{"service": ""}],
+		"waitForReady": true,
+		"retryPolicy": {
+			"maxAttempts": 3,
+			"initialBackoff": "0.5s",
+			"maxBackoff": "5s",
+			"backoffMultiplier": 2,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]
+}`
+
+var ErrDialTimeout = errors.New("timed out connecting to keystone")
+
+type connOptions struct {
+	tls            *bool
+	rootCAs        *x509.CertPool
+	certificates   []tls.Certificate
+	serverName     string
+	serviceConfig  string
+	dialTimeout    time.Duration
+	connectTimeout time.Duration
+	dialOptions    []grpc.DialOption
+	tokenSource    TokenSource
+	errs           []error
+}
+
+// ConnOption configures a connection created by NewConnectionWithOptions
+type ConnOption func(*connOptions)
+
+// WithTLS forces TLS on or off, by default TLS is used for :443 and https:// addresses
+func WithTLS(enabled bool) ConnOption {
+	return func(o *connOptions) {
+		o.tls = &enabled
+	}
+}
+
+// WithCAPool verifies the server certificate against the given pool, enabling TLS
+func WithCAPool(pool *x509.CertPool) ConnOption {
+	return func(o *connOptions) {
+		o.rootCAs = pool
+	}
+}
+
+// WithCAFile verifies the server certificate against the PEM encoded CA bundle at path, enabling TLS
+func WithCAFile(path string) ConnOption {
+	return func(o *connOptions) {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			o.errs = append(o.errs, fmt.Errorf("reading CA file: %w", err))
+			return
+		}
+		if o.rootCAs == nil {
+			o.rootCAs = x509.NewCertPool()
+		}
+		if !o.rootCAs.AppendCertsFromPEM(pem) {
+			o.errs = append(o.errs, fmt.Errorf("no certificates found in CA file %s", path))
+		}
+	}
+}
+
+// WithClientCertificate presents the certificate to the server for mutual TLS, enabling TLS
+func WithClientCertificate(cert tls.Certificate) ConnOption {
+	return func(o *connOptions) {
+		o.certificates = append(o.certificates, cert)
+	}
+}
+
+// WithClientCertificateFiles loads a PEM encoded certificate and key for mutual TLS, enabling TLS
+func WithClientCertificateFiles(certFile, keyFile string) ConnOption {
+	return func(o *connOptions) {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			o.errs = append(o.errs, fmt.Errorf("loading client certificate: %w", err))
+			return
+		}
+		o.certificates = append(o.certificates, cert)
+	}
+}
+
+// WithServerName overrides the name used to verify the server certificate, enabling TLS
+func WithServerName(name string) ConnOption {
+	return func(o *connOptions) {
+		o.serverName = name
+	}
+}
+
+// WithRetryServiceConfig replaces DefaultRetryServiceConfig with a custom gRPC service config
+func WithRetryServiceConfig(serviceConfig string) ConnOption {
+	return func(o *connOptions) {
+		o.serviceConfig = serviceConfig
+	}
+}
+
+// WithDialTimeout waits up to timeout for the connection to be ready, returning ErrDialTimeout if it is not
+func WithDialTimeout(timeout time.Duration) ConnOption {
+	return func(o *connOptions) {
+		o.dialTimeout = timeout
+	}
+}
+
+// WithMinConnectTimeout sets the minimum time allowed for each connection attempt
+func WithMinConnectTimeout(timeout time.Duration) ConnOption {
+	return func(o *connOptions) {
+		o.connectTimeout = timeout
+	}
+}
+
+// WithDialOptions appends raw gRPC dial options, applied after the keystone defaults
+func WithDialOptions(opts ...grpc.DialOption) ConnOption {
+	return func(o *connOptions) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+func (o *connOptions) tlsConfig(address string) *tls.Config {
+	useTLS := o.rootCAs != nil || len(o.certificates) > 0 || o.serverName != ""
+	if o.tls != nil {
+		useTLS = *o.tls
+	}
+	if !useTLS {
+		return nil
+	}
+
+	serverName := o.serverName
+	if serverName == "" {
+		serverName = strings.TrimPrefix(address, "https://")
+		if host, _, err := net.SplitHostPort(serverName); err == nil {
+			serverName = host
+		}
+	}
+
+	return &tls.Config{
+		ServerName:   serverName,
+		RootCAs:      o.rootCAs,
+		Certificates: o.certificates,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+func (o *connOptions) transportCredentials(address string) grpc.DialOption {
+	if strings.HasPrefix(address, "unix:") || strings.HasPrefix(address, "unix-abstract:") {
+		if o.tls == nil || !*o.tls {
+			return grpc.WithTransportCredentials(insecure.NewCredentials())
+		}
+	}
+	if o.tls == nil && o.rootCAs == nil && len(o.certificates) == 0 && o.serverName == "" {
+		return transportCredentials(address)
+	}
+	if cfg := o.tlsConfig(address); cfg != nil {
+		return grpc.WithTransportCredentials(credentials.NewTLS(cfg))
+	}
+	return grpc.WithTransportCredentials(insecure.NewCredentials())
+}
+
+// NewConnectionWithOptions dials the keystone server at address, which may be host:port or a unix:// socket path
+func NewConnectionWithOptions(address, vendorID, appID, accessToken string, opts ...ConnOption) (*Connection, error) {
+	o := &connOptions{
+		serviceConfig:  DefaultRetryServiceConfig,
+		connectTimeout: 3 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if len(o.errs) > 0 {
+		return nil, errors.Join(o.errs...)
+	}
+
+	dialOpts := []grpc.DialOption{
+		o.transportCredentials(address),
+		grpc.WithIdleTimeout(time.Minute * 5),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff: backoff.Config{
+				BaseDelay:  200 * time.Millisecond,
+				Multiplier: 1.6,
+				Jitter:     0.2,
+				MaxDelay:   5 * time.Second,
+			},
+			MinConnectTimeout: o.connectTimeout,
+		}),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                30 * time.Second,
+			Timeout:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+	}
+	if o.serviceConfig != "" {
+		dialOpts = append(dialOpts, grpc.WithDefaultServiceConfig(o.serviceConfig))
+	}
+	dialOpts = append(dialOpts, o.dialOptions...)
+
+	ksGrpcConn, err := grpc.NewClient(address, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.dialTimeout > 0 {
+		if err = waitForReady(ksGrpcConn, o.dialTimeout); err != nil {
+			_ = ksGrpcConn.Close()
+			return nil, err
+		}
+	}
+
+	conn := NewConnection(proto.NewKeystoneClient(ksGrpcConn), vendorID, appID, accessToken)
+	conn.grpcConn = ksGrpcConn
+	conn.tokenSource = o.tokenSource
+	return conn, nil
+}
+
+func waitForReady(conn *grpc.ClientConn, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	conn.Connect()
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("%w after %s (%s)", ErrDialTimeout, timeout, state)
+		}
+	}
+}
+
+// Close closes the underlying gRPC connection, when the connection was dialed by the SDK
+func (c *Connection) Close() error {
+	if c.grpcConn == nil {
+		return nil
+	}
+	return c.grpcConn.Close()
+}
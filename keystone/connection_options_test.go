@@ -0,0 +1,181 @@
+package keystone
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+func TestConnOptions_TLSConfig(t *testing.T) {
+	pool := x509.NewCertPool()
+	o := &connOptions{}
+	WithCAPool(pool)(o)
+
+	cfg := o.tlsConfig("keystone.internal:8443")
+	if cfg == nil {
+		t.Fatal("expected a CA pool to enable TLS")
+	}
+	if cfg.ServerName != "keystone.internal" {
+		t.Errorf("expected server name from the address host, got %q", cfg.ServerName)
+	}
+	if cfg.RootCAs != pool {
+		t.Error("expected the CA pool to be used")
+	}
+
+	WithServerName("override.internal")(o)
+	if cfg = o.tlsConfig("10.0.0.1:8443"); cfg.ServerName != "override.internal" {
+		t.Errorf("expected server name override, got %q", cfg.ServerName)
+	}
+
+	WithTLS(false)(o)
+	if o.tlsConfig("keystone.internal:8443") != nil {
+		t.Error("expected TLS to be disabled")
+	}
+}
+
+func TestNewConnectionWithOptions_Errors(t *testing.T) {
+	if _, err := NewConnectionWithOptions("127.0.0.1:50051", "v", "a", "t", WithCAFile(filepath.Join(t.TempDir(), "missing.pem"))); err == nil {
+		t.Error("expected an error for a missing CA file")
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.pem")
+	if err := os.WriteFile(invalid, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewConnectionWithOptions("127.0.0.1:50051", "v", "a", "t", WithCAFile(invalid)); err == nil {
+		t.Error("expected an error for a CA file without certificates")
+	}
+
+	if _, err := NewConnectionWithOptions("127.0.0.1:50051", "v", "a", "t", WithRetryServiceConfig("{invalid")); err == nil {
+		t.Error("expected an error for an invalid service config")
+	}
+}
+
+func TestNewConnectionWithOptions_DialTimeout(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	_ = lis.Close()
+
+	_, err = NewConnectionWithOptions(addr, "v", "a", "t", WithDialTimeout(200*time.Millisecond))
+	if !errors.Is(err, ErrDialTimeout) {
+		t.Errorf("expected ErrDialTimeout, got %v", err)
+	}
+}
+
+func serveOptionsTestServer(t *testing.T, lis net.Listener, opts ...grpc.ServerOption) {
+	t.Helper()
+	s := grpc.NewServer(opts...)
+	proto.RegisterKeystoneServer(s, &MockServer{StatusFunc: func(context.Context, *proto.Authorization) (*proto.StatusResponse, error) {
+		return &proto.StatusResponse{}, nil
+	}})
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+}
+
+func TestNewConnectionWithOptions_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "keystone.sock")
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveOptionsTestServer(t, lis)
+
+	conn, err := NewConnectionWithOptions("unix://"+socket, "v", "a", "t", WithDialTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Status(context.Background(), conn.authorization()); err != nil {
+		t.Errorf("unexpected status error: %v", err)
+	}
+}
+
+func optionsTestCertificate(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (tls.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, key
+}
+
+func TestNewConnectionWithOptions_MutualTLS(t *testing.T) {
+	notAfter := time.Now().Add(time.Hour)
+	ca, caKey := optionsTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "test-ca"}, NotAfter: notAfter,
+		IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign,
+	}, nil, nil)
+	server, _ := optionsTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2), Subject: pkix.Name{CommonName: "keystone.internal"}, NotAfter: notAfter,
+		DNSNames: []string{"keystone.internal"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca.Leaf, caKey)
+	client, _ := optionsTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3), Subject: pkix.Name{CommonName: "client"}, NotAfter: notAfter,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca.Leaf, caKey)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveOptionsTestServer(t, lis, grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{server},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})))
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err = os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := NewConnectionWithOptions(lis.Addr().String(), "v", "a", "t",
+		WithCAFile(caFile), WithClientCertificate(client), WithServerName("keystone.internal"), WithDialTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Status(context.Background(), conn.authorization()); err != nil {
+		t.Errorf("unexpected status error: %v", err)
+	}
+
+	if _, err = NewConnectionWithOptions(lis.Addr().String(), "v", "a", "t",
+		WithCAFile(caFile), WithServerName("keystone.internal"), WithDialTimeout(500*time.Millisecond)); err == nil {
+		t.Error("expected the connection to fail without a client certificate")
+	}
+}
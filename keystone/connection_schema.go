@@ -0,0 +1,168 @@
+package keystone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+// typeRegistration is a type registered with the connection, and the state of its schema sync with the server
+type typeRegistration struct {
+	definition TypeDefinition
+	synced     bool
+	pending    *schemaSync // in flight sync, shared by every caller waiting on this type
+}
+
+// schemaSync is a single Define call for a type
+type schemaSync struct {
+	done chan struct{}
+	err  error
+}
+
+func registeredType(t interface{}) reflect.Type {
+	typ := reflect.TypeOf(t)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// registerType returns true if the type is already registered
+func (c *Connection) registerType(t interface{}) (TypeDefinition, bool) {
+	typ := registeredType(t)
+
+	c.typeLock.Lock()
+	defer c.typeLock.Unlock()
+	if reg, ok := c.typeRegister[typ]; ok {
+		return reg.definition, true
+	}
+
+	// Define outside the register would allow two definitions of the same type, so this is held under the lock
+	reg := &typeRegistration{definition: Define(t)}
+	c.typeRegister[typ] = reg
+	return reg.definition, false
+}
+
+// registeredDefinition returns the definition of a registered type by its keystone type name
+func (c *Connection) registeredDefinition(schemaType string) (TypeDefinition, bool) {
+	c.typeLock.Lock()
+	defer c.typeLock.Unlock()
+	for _, reg := range c.typeRegister {
+		if reg.definition.Type == schemaType {
+			return reg.definition, true
+		}
+	}
+	return TypeDefinition{}, false
+}
+
+// ensureType registers the type if required, and waits for its schema to be synced with the server
+func (c *Connection) ensureType(ctx context.Context, t interface{}) (TypeDefinition, error) {
+	c.registerType(t)
+	typ := registeredType(t)
+
+	c.typeLock.Lock()
+	reg := c.typeRegister[typ]
+	if reg.synced {
+		c.typeLock.Unlock()
+		return reg.definition, nil
+	}
+	call := c.startSync(ctx, reg)
+	c.typeLock.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return TypeDefinition{}, ctx.Err()
+	}
+	if call.err != nil {
+		return TypeDefinition{}, call.err
+	}
+
+	c.typeLock.Lock()
+	defer c.typeLock.Unlock()
+	return reg.definition, nil
+}
+
+// startSync starts a Define call for the registration, or joins the one in flight; the type lock must be held
+func (c *Connection) startSync(ctx context.Context, reg *typeRegistration) *schemaSync {
+	if reg.pending != nil {
+		return reg.pending
+	}
+
+	call := &schemaSync{done: make(chan struct{})}
+	reg.pending = call
+	schema := reg.definition.Schema()
+	views := reg.definition.Views
+
+	// the sync is shared with other callers, so must not be cancelled by the caller which started it
+	go c.defineType(context.WithoutCancel(ctx), reg, call, schema, views)
+	return call
+}
+
+func (c *Connection) defineType(ctx context.Context, reg *typeRegistration, call *schemaSync, schema *proto.Schema, views []*proto.EntityView) {
+	resp, err := c.Define(ctx, &proto.SchemaRequest{
+		Authorization: c.authorization(),
+		Schema:        schema,
+		Views:         views,
+	})
+
+	c.typeLock.Lock()
+	defer c.typeLock.Unlock()
+	if err == nil {
+		reg.definition.id = resp.GetId()
+		reg.definition.Name = resp.GetName()
+		reg.definition.Type = resp.GetType()
+		//TODO: Do we need this back/replaced?
+		//reg.definition.Properties = resp.GetProperties()
+		reg.definition.Options = resp.GetOptions()
+		reg.definition.Singular = resp.GetSingular()
+		reg.definition.Plural = resp.GetPlural()
+		reg.synced = true
+	} else {
+		call.err = fmt.Errorf("define schema %s: %w", schema.GetType(), err)
+	}
+
+	// a failed sync is cleared, so the next caller retries it
+	reg.pending = nil
+	close(call.done)
+}
+
+// SyncSchemaContext syncs every registered type with the server, waiting until all have been defined or ctx is done
+func (c *Connection) SyncSchemaContext(ctx context.Context) error {
+	c.typeLock.Lock()
+	var calls []*schemaSync
+	for _, reg := range c.typeRegister {
+		if !reg.synced {
+			calls = append(calls, c.startSync(ctx, reg))
+		}
+	}
+	c.typeLock.Unlock()
+
+	var errs []error
+	for _, call := range calls {
+		select {
+		case <-call.done:
+			errs = append(errs, call.err)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SyncSchema syncs the schema with the server
+//
+// Deprecated: use SyncSchemaContext, which returns any errors defining the schema
+func (c *Connection) SyncSchema() *sync.WaitGroup {
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = c.SyncSchemaContext(context.Background())
+	}()
+	return wg
+}
@@ -0,0 +1,118 @@
+package keystone
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type schemaTestEntity struct {
+	BaseEntity
+	Name string
+}
+
+func TestEnsureType_SingleFlight(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	conn := actor.connection
+
+	var defines atomic.Int32
+	release := make(chan struct{})
+	mock.DefineFunc = func(_ context.Context, req *proto.SchemaRequest) (*proto.Schema, error) {
+		defines.Add(1)
+		<-release
+		schema := req.GetSchema()
+		schema.Id = "schema-1"
+		return schema, nil
+	}
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			def, err := conn.ensureType(context.Background(), &schemaTestEntity{})
+			if err == nil && def.id != "schema-1" {
+				err = errors.New("expected synced definition, got " + def.id)
+			}
+			errs <- err
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if defines.Load() != 1 {
+		t.Errorf("expected a single define, got %d", defines.Load())
+	}
+}
+
+func TestEnsureType_ErrorIsReturnedAndRetried(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	conn := actor.connection
+
+	var defines atomic.Int32
+	mock.DefineFunc = func(_ context.Context, req *proto.SchemaRequest) (*proto.Schema, error) {
+		if defines.Add(1) == 1 {
+			return nil, status.Error(codes.Unavailable, "schema store offline")
+		}
+		return req.GetSchema(), nil
+	}
+
+	err := actor.Mutate(context.Background(), &schemaTestEntity{Name: "a"})
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected define error to be returned, got %v", err)
+	}
+
+	if _, err = conn.ensureType(context.Background(), &schemaTestEntity{}); err != nil {
+		t.Errorf("expected define to be retried, got %v", err)
+	}
+	if defines.Load() != 2 {
+		t.Errorf("expected 2 defines, got %d", defines.Load())
+	}
+}
+
+func TestSyncSchemaContext(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	conn := actor.connection
+
+	release := make(chan struct{})
+	mock.DefineFunc = func(_ context.Context, req *proto.SchemaRequest) (*proto.Schema, error) {
+		<-release
+		return req.GetSchema(), nil
+	}
+
+	if n := conn.RegisterTypes(schemaTestEntity{}, &queryTestUser{}); n != 2 {
+		t.Fatalf("expected 2 new types, got %d", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := conn.SyncSchemaContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	close(release)
+	if err := conn.SyncSchemaContext(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// everything is synced, so nothing is waited on
+	conn.SyncSchema().Wait()
+}
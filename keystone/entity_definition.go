@@ -29,6 +29,7 @@ type TypeDefinition struct {
 	isChild      bool
 
 	Properties map[Property]proto.PropertyDefinition
+	Views      []*proto.EntityView
 }
 
 func NewTypeDefinition() TypeDefinition {
@@ -70,6 +71,7 @@ func Define(input interface{}) TypeDefinition {
 	if props, err := MapProperties(input); err == nil {
 		definition.Properties = props
 	}
+	definition.Views = mergeViews(defineViews(input), definition.Views)
 	return definition
 }
 
@@ -102,7 +104,7 @@ func MapPropertiesWithPrefix(v interface{}, prefix string) (map[Property]proto.P
 			continue
 		}
 		currentVal := val.FieldByIndex(field.Index)
-		ref := GetReflector(field.Type, currentVal)
+		ref := fieldReflector(field, currentVal)
 		if ref != nil {
 			properties[currentProp] = mergeDefinitions(def, ref.PropertyDefinition())
 		} else {
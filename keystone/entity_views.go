@@ -0,0 +1,113 @@
+package keystone
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/keystonedb/sdk-go/keystone/reflector"
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+// ViewedEntity declares named views for the entity, stored with its schema.
+// Views can also be declared on fields with the view= tag option, e.g. `keystone:"email,view=contact"`
+type ViewedEntity interface {
+	EntityViews() []*proto.EntityView
+}
+
+// HasView returns true if the type declares a view with the given name
+func (t TypeDefinition) HasView(name string) bool {
+	for _, view := range t.Views {
+		if view.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateView checks a requested view name is declared by the type.
+// Types without declared views may have views configured on the server, so are not validated
+func (t TypeDefinition) validateView(view *proto.EntityView) error {
+	name := view.GetName()
+	if name == "" || len(t.Views) == 0 || t.HasView(name) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s has no view %q", ErrUnknownView, t.Type, name)
+}
+
+// defineViews returns the views declared by the input, through view tags and the ViewedEntity interface
+func defineViews(input interface{}) []*proto.EntityView {
+	var views []*proto.EntityView
+	if val := reflector.Deref(reflect.ValueOf(input)); val.Kind() == reflect.Struct {
+		views = tagViews(val.Type())
+	}
+
+	if viewed, ok := input.(ViewedEntity); ok {
+		views = mergeViews(views, viewed.EntityViews())
+	} else if t := reflect.ValueOf(input).Type(); t.Kind() == reflect.Struct {
+		if viewed, ok = reflect.New(t).Interface().(ViewedEntity); ok {
+			views = mergeViews(views, viewed.EntityViews())
+		}
+	}
+	return views
+}
+
+// tagViews builds a view for each view= tag option, containing every property tagged with it
+func tagViews(t reflect.Type) []*proto.EntityView {
+	properties := map[string][]string{}
+	var collect func(t reflect.Type, prefix string)
+	collect = func(t reflect.Type, prefix string) {
+		for _, field := range reflect.VisibleFields(t) {
+			if field.Anonymous || !field.IsExported() {
+				continue
+			}
+
+			opt := getFieldOptions(field)
+			prop := knownPrefixProperty(prefix, opt.name)
+			if opt.name == "" || prop.HydrateOnly() {
+				continue
+			}
+			for _, view := range opt.views {
+				properties[view] = append(properties[view], prop.Name())
+			}
+
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct && fieldReflector(field, reflect.Value{}) == nil {
+				collect(fieldType, prop.Name())
+			}
+		}
+	}
+	collect(t, "")
+
+	views := make([]*proto.EntityView, 0, len(properties))
+	for name, props := range properties {
+		sort.Strings(props)
+		views = append(views, &proto.EntityView{
+			Name:       name,
+			Properties: []*proto.PropertyRequest{{Properties: props}},
+		})
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].GetName() < views[j].GetName() })
+	return views
+}
+
+// mergeViews appends the additional views, replacing any existing views of the same name
+func mergeViews(views, additional []*proto.EntityView) []*proto.EntityView {
+	for _, view := range additional {
+		replaced := false
+		for i, existing := range views {
+			if existing.GetName() == view.GetName() {
+				views[i] = view
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			views = append(views, view)
+		}
+	}
+	return views
+}
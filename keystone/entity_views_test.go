@@ -0,0 +1,155 @@
+package keystone
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+type viewTestAddress struct {
+	Line1   string `keystone:",view=contact"`
+	Country string `keystone:",view=contact,view=summary"`
+}
+
+type viewTestEntity struct {
+	BaseEntity
+	Name    string `keystone:",view=summary"`
+	Email   string `keystone:",view=contact"`
+	Address viewTestAddress
+	Notes   string
+}
+
+type viewInterfaceEntity struct {
+	BaseEntity
+	Name  string `keystone:",view=summary"`
+	Email string
+}
+
+func (e *viewInterfaceEntity) EntityViews() []*proto.EntityView {
+	return []*proto.EntityView{
+		{Name: "summary", Properties: []*proto.PropertyRequest{{Properties: []string{"name", "email"}}}},
+		{Name: "labelled", Labels: true},
+	}
+}
+
+func viewProperties(t *testing.T, def TypeDefinition, name string) []string {
+	t.Helper()
+	for _, view := range def.Views {
+		if view.GetName() == name {
+			var props []string
+			for _, req := range view.GetProperties() {
+				props = append(props, req.GetProperties()...)
+			}
+			return props
+		}
+	}
+	t.Fatalf("expected view %s, got %v", name, def.Views)
+	return nil
+}
+
+func TestDefine_TagViews(t *testing.T) {
+	def := Define(&viewTestEntity{})
+	if len(def.Views) != 2 {
+		t.Fatalf("expected 2 views, got %d", len(def.Views))
+	}
+
+	tests := map[string][]string{
+		"contact": {"address.country", "address.line1", "email"},
+		"summary": {"address.country", "name"},
+	}
+	for name, want := range tests {
+		got := viewProperties(t, def, name)
+		if len(got) != len(want) {
+			t.Errorf("view %s = %v; want %v", name, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("view %s = %v; want %v", name, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestDefine_EntityViews(t *testing.T) {
+	for _, input := range []interface{}{&viewInterfaceEntity{}, viewInterfaceEntity{}} {
+		def := Define(input)
+		if !def.HasView("labelled") {
+			t.Errorf("expected the labelled view from EntityViews, got %v", def.Views)
+		}
+		// views from EntityViews replace tag views of the same name
+		if props := viewProperties(t, def, "summary"); len(props) != 2 {
+			t.Errorf("expected the summary view from EntityViews, got %v", props)
+		}
+	}
+}
+
+func TestDefine_SendsViews(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	var lock sync.Mutex
+	var views []*proto.EntityView
+	mock.DefineFunc = func(_ context.Context, req *proto.SchemaRequest) (*proto.Schema, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		views = req.GetViews()
+		return req.GetSchema(), nil
+	}
+
+	if _, err := actor.connection.ensureType(context.Background(), &viewTestEntity{}); err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(views) != 2 {
+		t.Errorf("expected views to be sent with the schema, got %v", views)
+	}
+}
+
+func TestWithView_Validation(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	var lock sync.Mutex
+	var retrieved []string
+	mock.RetrieveFunc = func(_ context.Context, req *proto.EntityRequest) (*proto.EntityResponse, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		retrieved = append(retrieved, req.GetView().GetName())
+		return &proto.EntityResponse{Entity: &proto.Entity{EntityId: req.GetEntityId()}}, nil
+	}
+	mock.FindFunc = func(_ context.Context, req *proto.FindRequest) (*proto.FindResponse, error) {
+		return &proto.FindResponse{}, nil
+	}
+
+	ctx := context.Background()
+	if err := actor.GetByID(ctx, "entity-1", &viewTestEntity{}, WithView("summary")); err != nil {
+		t.Errorf("unexpected error for a declared view: %v", err)
+	}
+
+	err := actor.GetByID(ctx, "entity-1", &viewTestEntity{}, WithView("sumary"))
+	if !errors.Is(err, ErrUnknownView) {
+		t.Errorf("expected ErrUnknownView, got %v", err)
+	}
+
+	if _, err = actor.Find(ctx, Type(&viewTestEntity{}), WithView("contacts")); !errors.Is(err, ErrUnknownView) {
+		t.Errorf("expected ErrUnknownView from find, got %v", err)
+	}
+
+	// types without declared views may use views configured on the server
+	if err = actor.GetByID(ctx, "entity-1", &schemaTestEntity{}, WithView("server-view")); err != nil {
+		t.Errorf("unexpected error for a type without views: %v", err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(retrieved) != 2 {
+		t.Errorf("expected only valid views to be retrieved, got %v", retrieved)
+	}
+}
@@ -1,12 +1,139 @@
 package keystone
 
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrConflict         = errors.New("entity has been updated since it was retrieved")
+	ErrNotFound         = errors.New("entity not found")
+	ErrUniqueConflict   = errors.New("unique property conflict")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrUnavailable      = errors.New("keystone unavailable")
+	ErrValidation       = errors.New("validation failed")
+	ErrLocked           = errors.New("entity locked")
+	ErrUnknownView      = errors.New("unknown view")
+	ErrChecksumMismatch = errors.New("object checksum mismatch")
+	ErrInvalidID        = errors.New("invalid keystone ID")
+)
+
+// validationConditionsMessage is returned by keystone when MatchExisting or IfUnchanged conditions fail
+const validationConditionsMessage = "Validation conditions not met"
+
 type Error struct {
 	ErrorCode    int32
 	ErrorMessage string
 	Suggestions  []string
 	Extended     []string
+
+	cause error
 }
 
 func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.cause.Error()
+	}
 	return e.ErrorMessage
 }
+
+// Unwrap returns the underlying gRPC error, if the error was returned by the transport
+func (e *Error) Unwrap() error { return e.cause }
+
+// GRPCStatus returns the gRPC status for the error, so status.Code and status.FromError continue to work
+func (e *Error) GRPCStatus() *status.Status {
+	if e.cause != nil {
+		if st, ok := status.FromError(e.cause); ok {
+			return st
+		}
+	}
+	return status.New(httpCodeToGRPC(e.ErrorCode), e.ErrorMessage)
+}
+
+// Is matches the error against the keystone sentinel errors, by error code
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.ErrorCode == http.StatusNotFound
+	case ErrUniqueConflict:
+		return e.ErrorCode == http.StatusConflict && e.ErrorMessage != validationConditionsMessage
+	case ErrConflict:
+		return (e.ErrorCode == http.StatusConflict && e.ErrorMessage == validationConditionsMessage) ||
+			e.ErrorCode == http.StatusPreconditionFailed
+	case ErrPermissionDenied:
+		return e.ErrorCode == http.StatusForbidden || e.ErrorCode == http.StatusUnauthorized
+	case ErrUnavailable:
+		return e.ErrorCode == http.StatusServiceUnavailable
+	case ErrValidation:
+		return e.ErrorCode == http.StatusBadRequest || e.ErrorCode == http.StatusUnprocessableEntity
+	case ErrLocked:
+		return e.ErrorCode == http.StatusLocked
+	}
+	return false
+}
+
+var grpcHTTPCodes = map[codes.Code]int32{
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusPreconditionFailed,
+	codes.Aborted:            http.StatusPreconditionFailed,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+func httpCodeToGRPC(code int32) codes.Code {
+	switch code {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed, http.StatusLocked:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	return codes.Unknown
+}
+
+// toError converts gRPC status errors into a keystone Error, leaving other errors unchanged
+func toError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var ksErr *Error
+	if errors.As(err, &ksErr) {
+		return err
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	return &Error{ErrorCode: grpcHTTPCodes[st.Code()], ErrorMessage: st.Message(), cause: err}
+}
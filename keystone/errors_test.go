@@ -2,9 +2,13 @@ package keystone
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"testing"
 
 	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // TestNilActor_NewMethods tests that all new Actor methods return an error when called on a nil actor.
@@ -432,3 +436,83 @@ func TestNilConnection_WithOptions(t *testing.T) {
 		}
 	})
 }
+
+func TestErrorIs(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     *Error
+		matches error
+	}{
+		{"not found", &Error{ErrorCode: http.StatusNotFound}, ErrNotFound},
+		{"unique conflict", &Error{ErrorCode: http.StatusConflict, ErrorMessage: "email already exists"}, ErrUniqueConflict},
+		{"conditions conflict", &Error{ErrorCode: http.StatusConflict, ErrorMessage: validationConditionsMessage}, ErrConflict},
+		{"forbidden", &Error{ErrorCode: http.StatusForbidden}, ErrPermissionDenied},
+		{"unauthorized", &Error{ErrorCode: http.StatusUnauthorized}, ErrPermissionDenied},
+		{"unavailable", &Error{ErrorCode: http.StatusServiceUnavailable}, ErrUnavailable},
+		{"bad request", &Error{ErrorCode: http.StatusBadRequest}, ErrValidation},
+		{"locked", &Error{ErrorCode: http.StatusLocked}, ErrLocked},
+	}
+
+	sentinels := []error{ErrNotFound, ErrUniqueConflict, ErrConflict, ErrPermissionDenied, ErrUnavailable, ErrValidation, ErrLocked}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, sentinel := range sentinels {
+				if got := errors.Is(tt.err, sentinel); got != (sentinel == tt.matches) {
+					t.Errorf("errors.Is(%v) = %t", sentinel, got)
+				}
+			}
+		})
+	}
+}
+
+func TestToError(t *testing.T) {
+	if toError(nil) != nil {
+		t.Error("expected nil error")
+	}
+
+	plain := errors.New("plain")
+	if toError(plain) != plain {
+		t.Error("expected non-status errors to be unchanged")
+	}
+
+	grpcErr := status.Error(codes.Unavailable, "connection refused")
+	err := toError(grpcErr)
+
+	var ksErr *Error
+	if !errors.As(err, &ksErr) {
+		t.Fatalf("expected *Error, got %T", err)
+	}
+	if ksErr.ErrorCode != http.StatusServiceUnavailable || ksErr.ErrorMessage != "connection refused" {
+		t.Errorf("unexpected error fields: %d %q", ksErr.ErrorCode, ksErr.ErrorMessage)
+	}
+	if !errors.Is(err, ErrUnavailable) {
+		t.Error("expected ErrUnavailable")
+	}
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("expected gRPC status to be preserved, got %v", status.Code(err))
+	}
+	if err.Error() != grpcErr.Error() {
+		t.Errorf("expected the original message, got %q", err.Error())
+	}
+}
+
+func TestErrorGRPCStatusFromCode(t *testing.T) {
+	err := &Error{ErrorCode: http.StatusNotFound, ErrorMessage: "missing"}
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", status.Code(err))
+	}
+}
+
+func TestGetReturnsSentinelErrors(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	mock.RetrieveFunc = func(_ context.Context, req *proto.EntityRequest) (*proto.EntityResponse, error) {
+		return nil, status.Error(codes.NotFound, "entity not found")
+	}
+
+	err := actor.GetByID(context.Background(), "missing", &queryTestUser{})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
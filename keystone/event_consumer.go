@@ -0,0 +1,428 @@
+package keystone
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// EventHandler processes a single event from an event stream
+type EventHandler func(ctx context.Context, evt *proto.EventStreamResponse) error
+
+// EventCheckpoint is the position of an event consumer, every event up to and including it has been processed
+type EventCheckpoint struct {
+	Time     time.Time `json:"time"`
+	EntityID string    `json:"eid,omitempty"`
+	EventID  string    `json:"tid,omitempty"`
+}
+
+// CheckpointStore persists event consumer checkpoints between runs
+type CheckpointStore interface {
+	LoadCheckpoint(ctx context.Context, name string) (*EventCheckpoint, error)
+	SaveCheckpoint(ctx context.Context, name string, checkpoint *EventCheckpoint) error
+}
+
+// akvCheckpointStore stores checkpoints in the application key value store
+type akvCheckpointStore struct {
+	actor  *Actor
+	prefix string
+}
+
+// AKVCheckpointStore stores consumer checkpoints in the application key value store, keyed by consumer name
+func AKVCheckpointStore(actor *Actor) CheckpointStore {
+	return &akvCheckpointStore{actor: actor, prefix: "event-checkpoint:"}
+}
+
+func (s *akvCheckpointStore) LoadCheckpoint(ctx context.Context, name string) (*EventCheckpoint, error) {
+	values, err := s.actor.AKVGet(ctx, s.prefix+name)
+	if err != nil {
+		return nil, err
+	}
+	stored := values[s.prefix+name].GetText()
+	if stored == "" {
+		return nil, nil
+	}
+	checkpoint := &EventCheckpoint{}
+	if err = json.Unmarshal([]byte(stored), checkpoint); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+func (s *akvCheckpointStore) SaveCheckpoint(ctx context.Context, name string, checkpoint *EventCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	_, err = s.actor.AKVPut(ctx, AKV(s.prefix+name, string(data)))
+	return err
+}
+
+type eventConsumerOptions struct {
+	eventType          *Key
+	concurrency        int
+	partitionKey       func(*proto.EventStreamResponse) string
+	store              CheckpointStore
+	checkpointInterval time.Duration
+	minBackoff         time.Duration
+	maxBackoff         time.Duration
+	handlerAttempts    int
+	onError            func(evt *proto.EventStreamResponse, err error)
+	onStreamError      func(err error)
+}
+
+// EventConsumerOption configures an EventConsumer
+type EventConsumerOption func(*eventConsumerOptions)
+
+// WithConsumerEventType only consumes events of the given type
+func WithConsumerEventType(eventType *Key) EventConsumerOption {
+	return func(o *eventConsumerOptions) {
+		o.eventType = eventType
+	}
+}
+
+// WithConsumerConcurrency processes up to n events at once, events with the same partition key are processed in order
+func WithConsumerConcurrency(n int) EventConsumerOption {
+	return func(o *eventConsumerOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithConsumerPartitionKey sets the key events are ordered by, defaulting to the entity ID
+func WithConsumerPartitionKey(key func(*proto.EventStreamResponse) string) EventConsumerOption {
+	return func(o *eventConsumerOptions) {
+		if key != nil {
+			o.partitionKey = key
+		}
+	}
+}
+
+// WithCheckpointStore persists the consumer position, so a restarted consumer skips events it has already processed
+func WithCheckpointStore(store CheckpointStore) EventConsumerOption {
+	return func(o *eventConsumerOptions) {
+		o.store = store
+	}
+}
+
+// WithCheckpointInterval sets how often the checkpoint is saved, it is always saved when the consumer stops
+func WithCheckpointInterval(interval time.Duration) EventConsumerOption {
+	return func(o *eventConsumerOptions) {
+		if interval > 0 {
+			o.checkpointInterval = interval
+		}
+	}
+}
+
+// WithReconnectBackoff sets the delay before reconnecting a failed stream, doubling from min up to max
+func WithReconnectBackoff(min, max time.Duration) EventConsumerOption {
+	return func(o *eventConsumerOptions) {
+		o.minBackoff = min
+		o.maxBackoff = max
+	}
+}
+
+// WithHandlerAttempts sets how many times a failing event is handled before it is passed to the error handler
+// and skipped. By default failing events are retried until they succeed or the consumer stops.
+func WithHandlerAttempts(attempts int, onError func(evt *proto.EventStreamResponse, err error)) EventConsumerOption {
+	return func(o *eventConsumerOptions) {
+		o.handlerAttempts = attempts
+		o.onError = onError
+	}
+}
+
+// WithStreamErrorHandler is called with every error returned by the event stream, before it is reconnected.
+// The handler is also called with the error which stops the consumer when the stream cannot be retried.
+func WithStreamErrorHandler(onStreamError func(err error)) EventConsumerOption {
+	return func(o *eventConsumerOptions) {
+		o.onStreamError = onStreamError
+	}
+}
+
+// EventConsumer is a long-running consumer of a named event stream.
+// Failed streams are reconnected with backoff, and every event is handled at least once: an event is only
+// checkpointed once it, and every event received before it, has been handled.
+type EventConsumer struct {
+	actor   *Actor
+	name    string
+	handler EventHandler
+	opts    eventConsumerOptions
+
+	lock       sync.Mutex
+	checkpoint *EventCheckpoint
+	dirty      bool
+	sequence   uint64
+	pending    map[uint64]*EventCheckpoint // received events which have not been handled
+	handled    map[uint64]bool
+	watermark  uint64 // every event up to this sequence has been handled
+}
+
+// NewEventConsumer creates a consumer of the named event stream
+func NewEventConsumer(actor *Actor, name string, handler EventHandler, opts ...EventConsumerOption) *EventConsumer {
+	options := eventConsumerOptions{
+		concurrency:        1,
+		partitionKey:       func(evt *proto.EventStreamResponse) string { return evt.GetEid() },
+		checkpointInterval: 5 * time.Second,
+		minBackoff:         100 * time.Millisecond,
+		maxBackoff:         30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &EventConsumer{actor: actor, name: name, handler: handler, opts: options}
+}
+
+// Checkpoint returns the last event processed, along with every event before it
+func (c *EventConsumer) Checkpoint() *EventCheckpoint {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.checkpoint
+}
+
+// Run consumes events until ctx is done, returning nil on a graceful shutdown.
+// Run returns the stream error when the stream is rejected as unauthenticated, denied or invalid,
+// as reconnecting cannot succeed. On shutdown Run waits for running handlers to return, then saves the checkpoint.
+// Handlers are passed ctx, and an event left unhandled at shutdown is not checkpointed.
+func (c *EventConsumer) Run(ctx context.Context) error {
+	if c.actor == nil || c.actor.connection == nil {
+		return errors.New("actor or connection is nil")
+	}
+	if c.handler == nil {
+		return errors.New("event handler is nil")
+	}
+
+	if c.opts.store != nil {
+		checkpoint, err := c.opts.store.LoadCheckpoint(ctx, c.name)
+		if err != nil {
+			return err
+		}
+		c.checkpoint = checkpoint
+	}
+	c.lock.Lock()
+	c.pending = map[uint64]*EventCheckpoint{}
+	c.handled = map[uint64]bool{}
+	c.sequence = 0
+	c.watermark = 0
+	c.lock.Unlock()
+
+	// stopped early when the stream cannot be retried
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	workers := make([]chan consumedEvent, c.opts.concurrency)
+	wg := sync.WaitGroup{}
+	for i := range workers {
+		workers[i] = make(chan consumedEvent, 16)
+		wg.Add(1)
+		go func(events chan consumedEvent) {
+			defer wg.Done()
+			for evt := range events {
+				if ctx.Err() != nil {
+					// queued events are dropped at shutdown, leaving the checkpoint before them
+					continue
+				}
+				c.handle(ctx, evt)
+			}
+		}(workers[i])
+	}
+
+	saveDone := make(chan struct{})
+	go func() {
+		defer close(saveDone)
+		ticker := time.NewTicker(c.opts.checkpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_ = c.saveCheckpoint(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	position := newStreamPosition(c.checkpoint)
+
+	var streamErr error
+	delay := c.opts.minBackoff
+	for ctx.Err() == nil {
+		received := false
+		err := c.actor.EventStream(ctx, func(evt *proto.EventStreamResponse) error {
+			received = true
+			if !position.advance(evt) {
+				// already dispatched in this run, or processed before the consumer was restarted
+				return nil
+			}
+			return c.dispatch(ctx, workers, evt)
+		}, c.name, c.opts.eventType)
+
+		if err != nil && ctx.Err() == nil {
+			if c.opts.onStreamError != nil {
+				c.opts.onStreamError(err)
+			}
+			if !retryableStreamError(err) {
+				streamErr = err
+				stop()
+				break
+			}
+		}
+		if received {
+			delay = c.opts.minBackoff
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+		delay = min(delay*2, c.opts.maxBackoff)
+	}
+
+	for _, events := range workers {
+		close(events)
+	}
+	wg.Wait()
+	<-saveDone
+
+	// the run context is done, so the final checkpoint is saved without it
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	return errors.Join(streamErr, c.saveCheckpoint(saveCtx))
+}
+
+// retryableStreamError returns false for stream errors which reconnecting cannot resolve
+func retryableStreamError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied, codes.InvalidArgument:
+		return false
+	}
+	return true
+}
+
+// streamPosition is the newest event time dispatched, with the events dispatched at that time
+type streamPosition struct {
+	time   time.Time
+	events map[string]bool
+}
+
+// newStreamPosition starts the position at the checkpoint, so events it covers are not dispatched again
+func newStreamPosition(checkpoint *EventCheckpoint) *streamPosition {
+	p := &streamPosition{events: map[string]bool{}}
+	if checkpoint != nil {
+		p.time = checkpoint.Time
+		p.events[checkpoint.EntityID+"/"+checkpoint.EventID] = true
+	}
+	return p
+}
+
+// advance returns false for events before the position, or already dispatched at the position time.
+// Other events at the position time are dispatched, as the checkpoint only records the last event.
+func (p *streamPosition) advance(evt *proto.EventStreamResponse) bool {
+	if evt.GetEvent().GetTime() == nil {
+		return true
+	}
+	at := evt.GetEvent().GetTime().AsTime()
+	key := evt.GetEid() + "/" + evt.GetEvent().GetTid()
+	switch {
+	case at.Before(p.time), at.Equal(p.time) && p.events[key]:
+		return false
+	case at.After(p.time):
+		p.time = at
+		p.events = map[string]bool{}
+	}
+	p.events[key] = true
+	return true
+}
+
+type consumedEvent struct {
+	sequence uint64
+	event    *proto.EventStreamResponse
+}
+
+// dispatch queues the event on the worker for its partition, blocking while the worker queue is full
+func (c *EventConsumer) dispatch(ctx context.Context, workers []chan consumedEvent, evt *proto.EventStreamResponse) error {
+	c.lock.Lock()
+	c.sequence++
+	consumed := consumedEvent{sequence: c.sequence, event: evt}
+	c.pending[consumed.sequence] = &EventCheckpoint{
+		Time:     evt.GetEvent().GetTime().AsTime(),
+		EntityID: evt.GetEid(),
+		EventID:  evt.GetEvent().GetTid(),
+	}
+	c.lock.Unlock()
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(c.opts.partitionKey(evt)))
+	select {
+	case workers[h.Sum32()%uint32(len(workers))] <- consumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handle processes an event, retrying failures, and marks it handled unless the consumer stopped first
+func (c *EventConsumer) handle(ctx context.Context, evt consumedEvent) {
+	delay := c.opts.minBackoff
+	for attempt := 1; ; attempt++ {
+		err := c.handler(ctx, evt.event)
+		if err == nil {
+			break
+		}
+		if c.opts.handlerAttempts > 0 && attempt >= c.opts.handlerAttempts {
+			if c.opts.onError != nil {
+				c.opts.onError(evt.event, err)
+			}
+			break
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			// left unhandled, so the checkpoint stays before this event
+			return
+		}
+		delay = min(delay*2, c.opts.maxBackoff)
+	}
+	c.markHandled(evt.sequence)
+}
+
+// markHandled advances the checkpoint past every event which has been handled in sequence
+func (c *EventConsumer) markHandled(sequence uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.handled[sequence] = true
+	for c.handled[c.watermark+1] {
+		c.watermark++
+		c.checkpoint = c.pending[c.watermark]
+		c.dirty = true
+		delete(c.handled, c.watermark)
+		delete(c.pending, c.watermark)
+	}
+}
+
+func (c *EventConsumer) saveCheckpoint(ctx context.Context) error {
+	if c.opts.store == nil {
+		return nil
+	}
+	c.lock.Lock()
+	checkpoint, dirty := c.checkpoint, c.dirty
+	c.dirty = false
+	c.lock.Unlock()
+
+	if !dirty || checkpoint == nil {
+		return nil
+	}
+	if err := c.opts.store.SaveCheckpoint(ctx, c.name, checkpoint); err != nil {
+		c.lock.Lock()
+		c.dirty = true
+		c.lock.Unlock()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,445 @@
+package keystone
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type memoryCheckpointStore struct {
+	lock        sync.Mutex
+	checkpoints map[string]*EventCheckpoint
+}
+
+func (s *memoryCheckpointStore) LoadCheckpoint(_ context.Context, name string) (*EventCheckpoint, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.checkpoints[name], nil
+}
+
+func (s *memoryCheckpointStore) SaveCheckpoint(_ context.Context, name string, checkpoint *EventCheckpoint) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.checkpoints == nil {
+		s.checkpoints = map[string]*EventCheckpoint{}
+	}
+	s.checkpoints[name] = checkpoint
+	return nil
+}
+
+func (s *memoryCheckpointStore) get(name string) *EventCheckpoint {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.checkpoints[name]
+}
+
+var consumerEpoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func consumerEvent(eid, tid string, second int) *proto.EventStreamResponse {
+	return &proto.EventStreamResponse{Eid: eid, Event: &proto.EntityEvent{
+		Tid:  tid,
+		Time: timestamppb.New(consumerEpoch.Add(time.Duration(second) * time.Second)),
+	}}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEventConsumer_Reconnects(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	var streams atomic.Int32
+	mock.EventStreamFunc = func(req *proto.EventStreamRequest, stream grpc.ServerStreamingServer[proto.EventStreamResponse]) error {
+		if req.GetStreamName() != "orders" {
+			t.Errorf("expected the orders stream, got %s", req.GetStreamName())
+		}
+		switch streams.Add(1) {
+		case 1:
+			_ = stream.Send(consumerEvent("e1", "t1", 1))
+			_ = stream.Send(consumerEvent("e2", "t2", 2))
+			return status.Error(codes.Unavailable, "stream reset")
+		case 2:
+			_ = stream.Send(consumerEvent("e1", "t3", 3))
+		}
+		<-stream.Context().Done()
+		return nil
+	}
+
+	var lock sync.Mutex
+	var handled []string
+	store := &memoryCheckpointStore{}
+	consumer := NewEventConsumer(actor, "orders", func(_ context.Context, evt *proto.EventStreamResponse) error {
+		lock.Lock()
+		defer lock.Unlock()
+		handled = append(handled, evt.GetEvent().GetTid())
+		return nil
+	}, WithCheckpointStore(store), WithReconnectBackoff(time.Millisecond, 10*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Run(ctx) }()
+
+	waitFor(t, "events to be handled", func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(handled) == 3
+	})
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("expected a graceful shutdown, got %v", err)
+	}
+
+	if streams.Load() < 2 {
+		t.Errorf("expected the stream to be reconnected, got %d streams", streams.Load())
+	}
+	if cp := store.get("orders"); cp == nil || cp.EventID != "t3" || !cp.Time.Equal(consumerEpoch.Add(3*time.Second)) {
+		t.Errorf("expected the checkpoint to be saved at t3, got %+v", cp)
+	}
+}
+
+func TestEventConsumer_ResumesFromCheckpoint(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	mock.EventStreamFunc = func(req *proto.EventStreamRequest, stream grpc.ServerStreamingServer[proto.EventStreamResponse]) error {
+		_ = stream.Send(consumerEvent("e1", "old", 1))
+		_ = stream.Send(consumerEvent("e1", "same", 5))
+		_ = stream.Send(consumerEvent("e2", "other", 5))
+		_ = stream.Send(consumerEvent("e1", "new", 6))
+		<-stream.Context().Done()
+		return nil
+	}
+
+	store := &memoryCheckpointStore{checkpoints: map[string]*EventCheckpoint{
+		"orders": {Time: consumerEpoch.Add(5 * time.Second), EntityID: "e1", EventID: "same"},
+	}}
+
+	var lock sync.Mutex
+	var handled []string
+	consumer := NewEventConsumer(actor, "orders", func(_ context.Context, evt *proto.EventStreamResponse) error {
+		lock.Lock()
+		defer lock.Unlock()
+		handled = append(handled, evt.GetEvent().GetTid())
+		return nil
+	}, WithCheckpointStore(store))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Run(ctx) }()
+
+	waitFor(t, "events to be handled", func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(handled) == 2
+	})
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// other events at the checkpoint time are handled again, as delivery is at least once
+	if handled[0] != "other" || handled[1] != "new" {
+		t.Errorf("expected events after the checkpoint, got %v", handled)
+	}
+}
+
+func TestEventConsumer_RunAgain(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	var streams atomic.Int32
+	mock.EventStreamFunc = func(req *proto.EventStreamRequest, stream grpc.ServerStreamingServer[proto.EventStreamResponse]) error {
+		if streams.Add(1) == 1 {
+			_ = stream.Send(consumerEvent("e1", "t1", 1))
+			_ = stream.Send(consumerEvent("e1", "stuck", 2))
+		} else {
+			_ = stream.Send(consumerEvent("e1", "t3", 3))
+		}
+		<-stream.Context().Done()
+		return nil
+	}
+
+	var handled atomic.Int32
+	store := &memoryCheckpointStore{}
+	consumer := NewEventConsumer(actor, "orders", func(ctx context.Context, evt *proto.EventStreamResponse) error {
+		if evt.GetEvent().GetTid() == "stuck" {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		handled.Add(1)
+		return nil
+	}, WithCheckpointStore(store))
+
+	for run := 1; run <= 2; run++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- consumer.Run(ctx) }()
+
+		waitFor(t, "events to be handled", func() bool { return handled.Load() == int32(run) })
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the first run stopped before the stuck event was handled, the second run must still advance the checkpoint
+	if cp := store.get("orders"); cp == nil || cp.EventID != "t3" {
+		t.Errorf("expected the checkpoint at t3, got %+v", cp)
+	}
+}
+
+func TestEventConsumer_ReconnectSkipsDispatched(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	var streams atomic.Int32
+	mock.EventStreamFunc = func(req *proto.EventStreamRequest, stream grpc.ServerStreamingServer[proto.EventStreamResponse]) error {
+		// the server replays the stream from the start on every connection
+		_ = stream.Send(consumerEvent("e1", "t1", 1))
+		_ = stream.Send(consumerEvent("e2", "t2", 1))
+		if streams.Add(1) == 1 {
+			return status.Error(codes.Unavailable, "stream reset")
+		}
+		_ = stream.Send(consumerEvent("e1", "t3", 2))
+		<-stream.Context().Done()
+		return nil
+	}
+
+	var lock sync.Mutex
+	var handled []string
+	var streamErrs atomic.Int32
+	consumer := NewEventConsumer(actor, "orders", func(_ context.Context, evt *proto.EventStreamResponse) error {
+		lock.Lock()
+		defer lock.Unlock()
+		handled = append(handled, evt.GetEvent().GetTid())
+		return nil
+	},
+		WithReconnectBackoff(time.Millisecond, 10*time.Millisecond),
+		WithStreamErrorHandler(func(err error) { streamErrs.Add(1) }),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Run(ctx) }()
+
+	waitFor(t, "the checkpoint", func() bool {
+		return consumer.Checkpoint() != nil && consumer.Checkpoint().EventID == "t3"
+	})
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(handled) != 3 || handled[0] != "t1" || handled[1] != "t2" || handled[2] != "t3" {
+		t.Errorf("expected events replayed on reconnect to be skipped, got %v", handled)
+	}
+	if streamErrs.Load() != 1 {
+		t.Errorf("expected the stream error to be reported once, got %d", streamErrs.Load())
+	}
+}
+
+func TestEventConsumer_StopsOnPermanentStreamError(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	var streams atomic.Int32
+	mock.EventStreamFunc = func(req *proto.EventStreamRequest, stream grpc.ServerStreamingServer[proto.EventStreamResponse]) error {
+		streams.Add(1)
+		return status.Error(codes.PermissionDenied, "stream denied")
+	}
+
+	var reported atomic.Value
+	consumer := NewEventConsumer(actor, "orders", func(_ context.Context, evt *proto.EventStreamResponse) error {
+		return nil
+	},
+		WithReconnectBackoff(time.Millisecond, time.Millisecond),
+		WithStreamErrorHandler(func(err error) { reported.Store(err) }),
+	)
+
+	done := make(chan error)
+	go func() { done <- consumer.Run(context.Background()) }()
+
+	select {
+	case err := <-done:
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected the permission denied error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the consumer to stop on a permission denied stream")
+	}
+	if streams.Load() != 1 {
+		t.Errorf("expected the stream not to be retried, got %d streams", streams.Load())
+	}
+	if reported.Load() == nil {
+		t.Error("expected the stream error to be reported")
+	}
+}
+
+func TestEventConsumer_PartitionOrdering(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	mock.EventStreamFunc = func(req *proto.EventStreamRequest, stream grpc.ServerStreamingServer[proto.EventStreamResponse]) error {
+		for i := 0; i < 20; i++ {
+			eid := []string{"a", "b", "c", "d"}[i%4]
+			_ = stream.Send(consumerEvent(eid, eid+string(rune('0'+i/4)), i))
+		}
+		<-stream.Context().Done()
+		return nil
+	}
+
+	var lock sync.Mutex
+	var inFlight, maxInFlight int
+	order := map[string][]string{}
+	consumer := NewEventConsumer(actor, "orders", func(_ context.Context, evt *proto.EventStreamResponse) error {
+		lock.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		lock.Unlock()
+
+		time.Sleep(2 * time.Millisecond)
+
+		lock.Lock()
+		defer lock.Unlock()
+		inFlight--
+		order[evt.GetEid()] = append(order[evt.GetEid()], evt.GetEvent().GetTid())
+		return nil
+	}, WithConsumerConcurrency(4))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Run(ctx) }()
+
+	waitFor(t, "events to be handled", func() bool {
+		return consumer.Checkpoint() != nil && consumer.Checkpoint().Time.Equal(consumerEpoch.Add(19*time.Second))
+	})
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	for eid, tids := range order {
+		for i, tid := range tids {
+			if want := eid + string(rune('0'+i)); tid != want {
+				t.Errorf("expected %s events in order, got %v", eid, tids)
+				break
+			}
+		}
+	}
+	if maxInFlight < 2 {
+		t.Errorf("expected events for different entities to be handled concurrently, max in flight %d", maxInFlight)
+	}
+}
+
+func TestEventConsumer_HandlerRetries(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	mock.EventStreamFunc = func(req *proto.EventStreamRequest, stream grpc.ServerStreamingServer[proto.EventStreamResponse]) error {
+		_ = stream.Send(consumerEvent("e1", "flaky", 1))
+		_ = stream.Send(consumerEvent("e1", "broken", 2))
+		<-stream.Context().Done()
+		return nil
+	}
+
+	var attempts sync.Map
+	var failed atomic.Value
+	consumer := NewEventConsumer(actor, "orders", func(_ context.Context, evt *proto.EventStreamResponse) error {
+		n, _ := attempts.LoadOrStore(evt.GetEvent().GetTid(), new(atomic.Int32))
+		count := n.(*atomic.Int32).Add(1)
+		if evt.GetEvent().GetTid() == "broken" || count < 3 {
+			return errors.New("handler failed")
+		}
+		return nil
+	},
+		WithReconnectBackoff(time.Millisecond, time.Millisecond),
+		WithHandlerAttempts(3, func(evt *proto.EventStreamResponse, err error) { failed.Store(evt.GetEvent().GetTid()) }),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Run(ctx) }()
+
+	waitFor(t, "the failed event", func() bool { return failed.Load() != nil })
+	waitFor(t, "the checkpoint", func() bool {
+		return consumer.Checkpoint() != nil && consumer.Checkpoint().EventID == "broken"
+	})
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if failed.Load() != "broken" {
+		t.Errorf("expected the broken event to fail, got %v", failed.Load())
+	}
+	if n, _ := attempts.Load("flaky"); n.(*atomic.Int32).Load() != 3 {
+		t.Errorf("expected the flaky event to be retried until it succeeded, got %d attempts", n.(*atomic.Int32).Load())
+	}
+}
+
+func TestAKVCheckpointStore(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	var lock sync.Mutex
+	stored := map[string]*proto.Value{}
+	mock.AKVPutFunc = func(_ context.Context, req *proto.AKVPutRequest) (*proto.GenericResponse, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		for _, prop := range req.GetProperties() {
+			stored[prop.GetProperty().GetName()] = prop.GetValue()
+		}
+		return &proto.GenericResponse{Success: true}, nil
+	}
+	mock.AKVGetFunc = func(_ context.Context, req *proto.AKVGetRequest) (*proto.AKVGetResponse, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		resp := &proto.AKVGetResponse{Properties: map[string]*proto.Value{}}
+		for _, name := range req.GetProperties() {
+			if v, ok := stored[name]; ok {
+				resp.Properties[name] = v
+			}
+		}
+		return resp, nil
+	}
+
+	store := AKVCheckpointStore(actor)
+	ctx := context.Background()
+	if cp, err := store.LoadCheckpoint(ctx, "orders"); err != nil || cp != nil {
+		t.Fatalf("expected no checkpoint, got %v %v", cp, err)
+	}
+
+	want := &EventCheckpoint{Time: consumerEpoch, EntityID: "e1", EventID: "t1"}
+	if err := store.SaveCheckpoint(ctx, "orders", want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.LoadCheckpoint(ctx, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Time.Equal(want.Time) || got.EntityID != want.EntityID || got.EventID != want.EventID {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
@@ -12,6 +12,8 @@ type filterRequest struct {
 	ParentEntityID string
 	PerPage        int32
 	PageNumber     int32
+	AfterID        string
 	ObjectPaths    []string
 	ListObjects    bool
+	prefetch       int
 }
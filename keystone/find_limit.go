@@ -3,13 +3,34 @@ package keystone
 type withLimit struct {
 	perPage    int32
 	pageNumber int32
+	afterID    string
 }
 
 func (f withLimit) Apply(config *filterRequest) {
 	config.PerPage = f.perPage
 	config.PageNumber = f.pageNumber
+	config.AfterID = f.afterID
 }
 
 func Limit(perPage, pageNumber int32) FindOption {
 	return withLimit{perPage: perPage, pageNumber: pageNumber}
 }
+
+// LimitAfter limits results to perPage entities after the given entity ID cursor
+func LimitAfter(perPage int32, afterID string) FindOption {
+	return withLimit{perPage: perPage, afterID: afterID}
+}
+
+type withPrefetch struct {
+	pages int
+}
+
+func (f withPrefetch) Apply(config *filterRequest) {
+	config.prefetch = f.pages
+}
+
+// Prefetch sets the number of pages an iterator will load ahead of the entity being consumed.
+// By default pages are only loaded once the previous page has been consumed.
+func Prefetch(pages int) FindOption {
+	return withPrefetch{pages: pages}
+}
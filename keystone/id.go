@@ -1,6 +1,9 @@
 package keystone
 
 import (
+	"database/sql/driver"
+	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -13,8 +16,103 @@ var k7ID = k4id.NewGenerator(k4id.TimeGeneratorNano)
 // ID is a unique identifier for a remote object
 type ID string
 
-// Time returns the time of the parent ID
+// IDKind is the format of the parent part of an ID
+type IDKind int
+
+const (
+	// IDKindUnknown is an empty or unrecognised ID
+	IDKindUnknown IDKind = iota
+	// IDKindGenerated is a time based ID generated by keystone, or with GenerateID
+	IDKindGenerated
+	// IDKindHashed is an ID derived from an external identifier with HashID
+	IDKindHashed
+)
+
+func (k IDKind) String() string {
+	switch k {
+	case IDKindGenerated:
+		return "generated"
+	case IDKindHashed:
+		return "hashed"
+	}
+	return "unknown"
+}
+
+// GenerateID returns a new time based ID, which can be set on an entity with SetKeystoneID before it is mutated
+func GenerateID() ID {
+	return ID(k7ID.New().String())
+}
+
+// ParseID validates input as a generated or hashed ID, with an optional child ID
+func ParseID(input string) (ID, error) {
+	if input == "" {
+		return "", fmt.Errorf("%w: empty", ErrInvalidID)
+	}
+	id := ID(input)
+	parent, child, hasChild := id.split()
+	if hasChild && child == "" {
+		return "", fmt.Errorf("%w: %q has an empty child ID", ErrInvalidID, input)
+	}
+	if strings.Contains(child, "#") {
+		return "", fmt.Errorf("%w: %q child ID cannot contain #", ErrInvalidID, input)
+	}
+
+	if strings.HasPrefix(parent, "#") {
+		if len(parent) < 3 || !strings.HasSuffix(parent, "#") {
+			return "", fmt.Errorf("%w: %q is not a complete hashed ID", ErrInvalidID, input)
+		}
+		if err := ValidateHashID(parent[1 : len(parent)-1]); err != nil {
+			return "", err
+		}
+		return id, nil
+	}
+	if !k4id.FromString(parent).IsValid() {
+		return "", fmt.Errorf("%w: %q does not have a valid checksum", ErrInvalidID, input)
+	}
+	return id, nil
+}
+
+// ValidateID returns an error when input is not a valid ID
+func ValidateID(input string) error {
+	_, err := ParseID(input)
+	return err
+}
+
+// ValidateHashID returns an error when input cannot be used with HashID
+func ValidateHashID(input string) error {
+	if input == "" {
+		return fmt.Errorf("%w: hashed ID input is empty", ErrInvalidID)
+	}
+	if strings.Contains(input, "#") {
+		return fmt.Errorf("%w: hashed ID input cannot contain #", ErrInvalidID)
+	}
+	return nil
+}
+
+// Kind returns the format of the parent ID
+func (id ID) Kind() IDKind {
+	parent := id.ParentID()
+	switch {
+	case len(parent) > 2 && parent[0] == '#' && parent[len(parent)-1] == '#':
+		if ValidateHashID(parent[1:len(parent)-1]) == nil {
+			return IDKindHashed
+		}
+	case parent != "" && k4id.FromString(parent).IsValid():
+		return IDKindGenerated
+	}
+	return IDKindUnknown
+}
+
+// IsChild returns true when the ID identifies a child of an entity
+func (id ID) IsChild() bool {
+	return id.ChildID() != ""
+}
+
+// Time returns the time of the parent ID, hashed IDs have no time
 func (id ID) Time() time.Time {
+	if strings.HasPrefix(string(id), "#") {
+		return time.Time{}
+	}
 	return k7ID.ExtractTime(id.ParentID())
 }
 
@@ -31,17 +129,33 @@ func (id ID) String() string {
 	return string(id)
 }
 
+// ParentID returns the entity ID without the child ID.
+// A hashed parent keeps any - in its input, so HashCID("order-1", "line") has the parent #order-1# where earlier
+// versions split at the first - and returned #order. Generated IDs, and hashed IDs without a -, are unchanged.
 func (id ID) ParentID() string {
-	split := strings.SplitN(string(id), "-", 2)
-	return split[0]
+	parent, _, _ := id.split()
+	return parent
 }
 
+// ChildID returns the child ID, or an empty string for an entity ID, split after a hashed parent as with ParentID
 func (id ID) ChildID() string {
-	split := strings.SplitN(string(id), "-", 2)
-	if len(split) > 1 {
-		return split[1]
+	_, child, _ := id.split()
+	return child
+}
+
+// split separates the parent and child IDs, hashed parents may contain - so are split after the closing #
+func (id ID) split() (parent, child string, hasChild bool) {
+	s := string(id)
+	from := 0
+	if strings.HasPrefix(s, "#") {
+		if end := strings.Index(s[1:], "#"); end >= 0 {
+			from = end + 2
+		}
+	}
+	if i := strings.Index(s[from:], "-"); i >= 0 {
+		return s[:from+i], s[from+i+1:], true
 	}
-	return ""
+	return s, "", false
 }
 
 func (id ID) Matches(input string) bool {
@@ -61,6 +175,58 @@ func assertHashID(input string) {
 	}
 }
 
+// MarshalText implements encoding.TextMarshaler, which also encodes IDs as JSON strings
+func (id ID) MarshalText() ([]byte, error) {
+	return []byte(id), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. The ID is decoded as is, without validation, so stored IDs
+// which predate the current format still decode; use ParseID or ValidateID where input must be valid.
+func (id *ID) UnmarshalText(text []byte) error {
+	*id = ID(text)
+	return nil
+}
+
+// Scan implements sql.Scanner, NULL is scanned as an empty ID. Like UnmarshalText, the ID is not validated.
+func (id *ID) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*id = ""
+		return nil
+	case string:
+		return id.UnmarshalText([]byte(v))
+	case []byte:
+		return id.UnmarshalText(v)
+	}
+	return fmt.Errorf("cannot scan %T into keystone.ID", src)
+}
+
+// Value implements driver.Valuer, an empty ID is stored as NULL
+func (id ID) Value() (driver.Value, error) {
+	if id == "" {
+		return nil, nil
+	}
+	return string(id), nil
+}
+
+// SortIDsByTime sorts ids by parent time then child time, oldest first. IDs without a time, such as hashed IDs,
+// sort first, and IDs with the same time are sorted by value.
+func SortIDsByTime(ids []ID) {
+	sort.SliceStable(ids, func(i, j int) bool {
+		return compareIDTime(ids[i], ids[j]) < 0
+	})
+}
+
+func compareIDTime(a, b ID) int {
+	if c := a.Time().Compare(b.Time()); c != 0 {
+		return c
+	}
+	if c := a.ChildTime().Compare(b.ChildTime()); c != 0 {
+		return c
+	}
+	return strings.Compare(string(a), string(b))
+}
+
 func HashID(input string) ID {
 	assertHashID(input)
 	return ID("#" + input + "#")
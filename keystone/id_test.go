@@ -1,6 +1,9 @@
 package keystone
 
 import (
+	"encoding/json"
+	"errors"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -12,3 +15,165 @@ func TestIDTime(t *testing.T) {
 		t.Errorf("Expected time to be %s got %s # %d", "2025-07-23 08:40:37", res.Format(time.DateTime), res.Unix())
 	}
 }
+
+func TestParseID(t *testing.T) {
+	generated := GenerateID()
+	tests := []struct {
+		input string
+		kind  IDKind
+		child string
+		valid bool
+	}{
+		{"8Wb9D1aSWv528IIRxCn", IDKindGenerated, "", true},
+		{generated.String(), IDKindGenerated, "", true},
+		{generated.String() + "-child", IDKindGenerated, "child", true},
+		{"#user@example.com#", IDKindHashed, "", true},
+		{"#order-123#", IDKindHashed, "", true},
+		{"#order-123#-line-1", IDKindHashed, "line-1", true},
+		{"", IDKindUnknown, "", false},
+		{"9Wb9D1aSWv528IIRxCn", IDKindUnknown, "", false},
+		{generated.String() + "-", IDKindGenerated, "", false},
+		{"#unterminated", IDKindUnknown, "", false},
+		{"##", IDKindUnknown, "", false},
+		{"#a#b#", IDKindUnknown, "", false},
+		{"#a#-b#", IDKindHashed, "b#", false},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			id, err := ParseID(test.input)
+			if !test.valid {
+				if !errors.Is(err, ErrInvalidID) {
+					t.Errorf("expected ErrInvalidID, got %v", err)
+				}
+				if kind := ID(test.input).Kind(); kind != test.kind {
+					t.Errorf("expected kind %s, got %s", test.kind, kind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id.Kind() != test.kind || id.ChildID() != test.child || id.IsChild() != (test.child != "") {
+				t.Errorf("expected %s with child %q, got %s with child %q", test.kind, test.child, id.Kind(), id.ChildID())
+			}
+		})
+	}
+}
+
+func TestIDSplitCompatibility(t *testing.T) {
+	generated := GenerateID().String()
+	tests := []struct {
+		id, parent, child string
+	}{
+		// unchanged from splitting at the first -
+		{generated, generated, ""},
+		{generated + "-child-1", generated, "child-1"},
+		{"#user@example.com#", "#user@example.com#", ""},
+		{"#user@example.com#-line-1", "#user@example.com#", "line-1"},
+		{"#unterminated-child", "#unterminated", "child"},
+		// hashed parents containing - were split inside the hash input
+		{"#order-123#", "#order-123#", ""},
+		{"#order-123#-line-1", "#order-123#", "line-1"},
+	}
+	for _, test := range tests {
+		id := ID(test.id)
+		if id.ParentID() != test.parent || id.ChildID() != test.child {
+			t.Errorf("%s: expected %q and %q, got %q and %q", test.id, test.parent, test.child, id.ParentID(), id.ChildID())
+		}
+	}
+}
+
+func TestHashIDParent(t *testing.T) {
+	id := HashCID("order-123", "line-1")
+	if id.ParentID() != "#order-123#" || id.ChildID() != "line-1" {
+		t.Errorf("expected the hashed parent to keep its -, got %q and %q", id.ParentID(), id.ChildID())
+	}
+	if !id.Time().IsZero() {
+		t.Errorf("expected hashed IDs to have no time")
+	}
+	if err := ValidateHashID("a#b"); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("expected ErrInvalidID for # in a hashed ID, got %v", err)
+	}
+}
+
+func TestGenerateID(t *testing.T) {
+	before := time.Now()
+	id := GenerateID()
+	if err := ValidateID(id.String()); err != nil {
+		t.Fatal(err)
+	}
+	if id.Time().Before(before.Add(-time.Millisecond)) || id.Time().After(time.Now().Add(time.Millisecond)) {
+		t.Errorf("expected the ID time to be now, got %s", id.Time())
+	}
+	if GenerateID() == id {
+		t.Errorf("expected generated IDs to be unique")
+	}
+}
+
+func TestIDEncoding(t *testing.T) {
+	type record struct {
+		ID     ID  `json:"id"`
+		Parent *ID `json:"parent"`
+	}
+	id := HashCID("order-123", "line-1")
+	data, err := json.Marshal(record{ID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"id":"#order-123#-line-1","parent":null}` {
+		t.Errorf("unexpected JSON %s", data)
+	}
+	decoded := record{}
+	if err = json.Unmarshal(data, &decoded); err != nil || decoded.ID != id || decoded.Parent != nil {
+		t.Errorf("expected the ID to decode, got %+v %v", decoded, err)
+	}
+	// decoding does not validate, so legacy IDs can still be read
+	if err = json.Unmarshal([]byte(`{"id":"#broken"}`), &decoded); err != nil || decoded.ID != "#broken" {
+		t.Errorf("expected invalid IDs to decode as is, got %q %v", decoded.ID, err)
+	}
+	if !errors.Is(ValidateID(decoded.ID.String()), ErrInvalidID) {
+		t.Errorf("expected the decoded ID to be left to the caller to validate")
+	}
+
+	var scanned ID
+	if err = scanned.Scan([]byte(id)); err != nil || scanned != id {
+		t.Errorf("expected to scan bytes, got %q %v", scanned, err)
+	}
+	if err = scanned.Scan("legacy id"); err != nil || scanned != "legacy id" {
+		t.Errorf("expected to scan an unvalidated string, got %q %v", scanned, err)
+	}
+	if err = scanned.Scan(nil); err != nil || scanned != "" {
+		t.Errorf("expected NULL to scan as empty, got %q %v", scanned, err)
+	}
+	if err = scanned.Scan(42); err == nil {
+		t.Errorf("expected an error scanning an int")
+	}
+	if value, _ := scanned.Value(); value != nil {
+		t.Errorf("expected an empty ID to be NULL, got %v", value)
+	}
+	if value, _ := id.Value(); value != string(id) {
+		t.Errorf("expected the ID value, got %v", value)
+	}
+}
+
+func TestSortIDsByTime(t *testing.T) {
+	first := GenerateID()
+	time.Sleep(time.Millisecond)
+	second := GenerateID()
+	hashed := HashID("external")
+
+	ids := []ID{
+		NewID(second.String(), strconv.FormatInt(2000, 36)),
+		second,
+		first,
+		hashed,
+		NewID(second.String(), strconv.FormatInt(1000, 36)),
+	}
+	SortIDsByTime(ids)
+	want := []ID{hashed, first, second, NewID(second.String(), strconv.FormatInt(1000, 36)), NewID(second.String(), strconv.FormatInt(2000, 36))}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, ids)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package keystonetest
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+func (s *Server) AKVPut(_ context.Context, req *proto.AKVPutRequest) (*proto.GenericResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	app := appKey(req.GetAuthorization().GetSource())
+	for _, p := range req.GetProperties() {
+		s.akv[app+"/"+p.GetProperty().GetName()] = clone(p.GetValue())
+	}
+	return &proto.GenericResponse{Success: true}, nil
+}
+
+func (s *Server) AKVGet(_ context.Context, req *proto.AKVGetRequest) (*proto.AKVGetResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	app := appKey(req.GetAuthorization().GetSource())
+	resp := &proto.AKVGetResponse{Summary: &proto.GenericResponse{Success: true}, Properties: make(map[string]*proto.Value)}
+	for _, name := range req.GetProperties() {
+		if v, ok := s.akv[app+"/"+name]; ok {
+			resp.Properties[name] = clone(v)
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) AKVDel(_ context.Context, req *proto.AKVDelRequest) (*proto.GenericResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	app := appKey(req.GetAuthorization().GetSource())
+	for _, name := range req.GetProperties() {
+		delete(s.akv, app+"/"+name)
+	}
+	return &proto.GenericResponse{Success: true}, nil
+}
+
+// timeEntry is a single stored AKV time track entry
+type timeEntry struct {
+	timestamp int64
+	data      []byte
+	expires   time.Time
+}
+
+func akvTimeKey(auth *proto.Authorization, workspace, key string) string {
+	return appKey(auth.GetSource()) + "/" + workspace + "/" + key
+}
+
+func (s *Server) AKVTimePut(_ context.Context, req *proto.AKVTimePutRequest) (*proto.GenericResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, entry := range req.GetEntries() {
+		key := akvTimeKey(req.GetAuthorization(), entry.GetWorkspace(), entry.GetKey())
+		stored := timeEntry{timestamp: entry.GetTimestampUnix(), data: slices.Clone(entry.GetData())}
+		if entry.GetTtlSeconds() > 0 {
+			stored.expires = time.Now().Add(time.Duration(entry.GetTtlSeconds()) * time.Second)
+		}
+		entries := slices.DeleteFunc(s.akvTime[key], func(e timeEntry) bool { return e.timestamp == stored.timestamp })
+		entries = append(entries, stored)
+		slices.SortFunc(entries, func(a, b timeEntry) int { return cmp.Compare(a.timestamp, b.timestamp) })
+		s.akvTime[key] = entries
+	}
+	return &proto.GenericResponse{Success: true}, nil
+}
+
+func (s *Server) AKVTimeGet(_ context.Context, req *proto.AKVTimeGetRequest) (*proto.AKVTimeGetResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	now := time.Now()
+	var entries []timeEntry
+	for _, e := range s.akvTime[akvTimeKey(req.GetAuthorization(), req.GetWorkspace(), req.GetKey())] {
+		if e.expires.IsZero() || e.expires.After(now) {
+			entries = append(entries, e)
+		}
+	}
+
+	var results []timeEntry
+	switch req.GetMode() {
+	case proto.AKVTimeQueryMode_AKV_TIME_QUERY_LATEST:
+		if len(entries) > 0 {
+			results = entries[len(entries)-1:]
+		}
+	case proto.AKVTimeQueryMode_AKV_TIME_QUERY_LATEST_BEFORE:
+		for i := len(entries) - 1; i >= 0; i-- {
+			if entries[i].timestamp <= req.GetPivotTimestamp() {
+				results = entries[i : i+1]
+				break
+			}
+		}
+	case proto.AKVTimeQueryMode_AKV_TIME_QUERY_EARLIEST_AFTER:
+		for i, e := range entries {
+			if e.timestamp >= req.GetPivotTimestamp() {
+				results = entries[i : i+1]
+				break
+			}
+		}
+	case proto.AKVTimeQueryMode_AKV_TIME_QUERY_RANGE:
+		results = groupTimeEntries(timeRange(entries, req.GetRangeStart(), req.GetRangeEnd()), req.GetGroupInterval(), req.GetGroupPick())
+		if req.GetLimit() > 0 && len(results) > int(req.GetLimit()) {
+			results = results[:req.GetLimit()]
+		}
+	}
+
+	resp := &proto.AKVTimeGetResponse{Summary: &proto.GenericResponse{Success: true}}
+	for _, e := range results {
+		resp.Results = append(resp.Results, &proto.AKVTimeResult{TimestampUnix: e.timestamp, Data: slices.Clone(e.data)})
+	}
+	return resp, nil
+}
+
+func (s *Server) AKVTimeDel(_ context.Context, req *proto.AKVTimeDelRequest) (*proto.GenericResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := akvTimeKey(req.GetAuthorization(), req.GetWorkspace(), req.GetKey())
+	switch req.GetMode() {
+	case proto.AKVTimeDeleteMode_AKV_TIME_DELETE_ALL:
+		delete(s.akvTime, key)
+	case proto.AKVTimeDeleteMode_AKV_TIME_DELETE_EXACT:
+		s.akvTime[key] = slices.DeleteFunc(s.akvTime[key], func(e timeEntry) bool {
+			return slices.Contains(req.GetTimestamps(), e.timestamp)
+		})
+	case proto.AKVTimeDeleteMode_AKV_TIME_DELETE_TIME_RANGE:
+		s.akvTime[key] = slices.DeleteFunc(s.akvTime[key], func(e timeEntry) bool {
+			return e.timestamp >= req.GetRangeStart() && e.timestamp <= req.GetRangeEnd()
+		})
+	default:
+		return &proto.GenericResponse{ErrorCode: 400, ErrorMessage: "delete mode is required"}, nil
+	}
+	return &proto.GenericResponse{Success: true}, nil
+}
+
+func timeRange(entries []timeEntry, start, end int64) []timeEntry {
+	var ranged []timeEntry
+	for _, e := range entries {
+		if e.timestamp >= start && (end == 0 || e.timestamp <= end) {
+			ranged = append(ranged, e)
+		}
+	}
+	return ranged
+}
+
+// groupTimeEntries picks the first or last entry within each interval bucket
+func groupTimeEntries(entries []timeEntry, interval proto.AKVTimeGroupInterval, pick proto.AKVTimeGroupPick) []timeEntry {
+	if interval == proto.AKVTimeGroupInterval_AKV_TIME_GROUP_NONE {
+		return entries
+	}
+
+	var grouped []timeEntry
+	lastBucket := int64(-1)
+	for _, e := range entries {
+		bucket := timeBucket(e.timestamp, interval)
+		switch {
+		case bucket != lastBucket:
+			grouped = append(grouped, e)
+		case pick == proto.AKVTimeGroupPick_AKV_TIME_PICK_LAST:
+			grouped[len(grouped)-1] = e
+		}
+		lastBucket = bucket
+	}
+	return grouped
+}
+
+func timeBucket(unix int64, interval proto.AKVTimeGroupInterval) int64 {
+	switch interval {
+	case proto.AKVTimeGroupInterval_AKV_TIME_GROUP_MINUTELY:
+		return unix / 60
+	case proto.AKVTimeGroupInterval_AKV_TIME_GROUP_HOURLY:
+		return unix / 3600
+	case proto.AKVTimeGroupInterval_AKV_TIME_GROUP_DAILY:
+		return unix / 86400
+	case proto.AKVTimeGroupInterval_AKV_TIME_GROUP_WEEKLY:
+		return unix / (7 * 86400)
+	case proto.AKVTimeGroupInterval_AKV_TIME_GROUP_MONTHLY:
+		t := time.Unix(unix, 0).UTC()
+		return int64(t.Year())*12 + int64(t.Month())
+	}
+	return unix
+}
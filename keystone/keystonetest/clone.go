@@ -0,0 +1,13 @@
+package keystonetest
+
+import (
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// clone deep copies a proto message, so stored state is never shared with callers
+func clone[T protobuf.Message](msg T) T {
+	if any(msg) == nil || !msg.ProtoReflect().IsValid() {
+		return msg
+	}
+	return protobuf.Clone(msg).(T)
+}
@@ -0,0 +1,429 @@
+package keystonetest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const validationConditionsMessage = "Validation conditions not met"
+
+// entity is the stored state of a single entity
+type entity struct {
+	id          string
+	schema      *proto.Schema
+	workspace   string
+	state       proto.EntityState
+	created     time.Time
+	stateChange time.Time
+	lastUpdate  time.Time
+	properties  map[string]*proto.Value
+	dynamic     map[string]*proto.Value
+	labels      map[string]string
+	events      []*proto.EntityEvent
+}
+
+func (e *entity) value(property string) *proto.Value {
+	switch property {
+	case "_created":
+		return &proto.Value{Time: timestamppb.New(e.created)}
+	case "_last_update":
+		return &proto.Value{Time: timestamppb.New(e.lastUpdate)}
+	case "_state_change":
+		return &proto.Value{Time: timestamppb.New(e.stateChange)}
+	case "_state":
+		return &proto.Value{Int: int64(e.state)}
+	}
+	if v, ok := e.properties[property]; ok {
+		return v
+	}
+	return e.dynamic[property]
+}
+
+func (e *entity) propertyType(property string) proto.Property_Type {
+	switch property {
+	case "_created", "_last_update", "_state_change":
+		return proto.Property_Time
+	case "_state":
+		return proto.Property_Number
+	}
+	for _, p := range e.schema.GetProperties() {
+		if p.GetName() == property {
+			return p.GetDataType()
+		}
+	}
+	return valueType(e.value(property))
+}
+
+// Entity returns the stored entity with all properties, and whether it exists
+func (s *Server) Entity(entityID string) (*proto.EntityResponse, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	ent, ok := s.entities[entityID]
+	if !ok {
+		return nil, false
+	}
+	return ent.response(&proto.EntityView{Labels: true, Properties: []*proto.PropertyRequest{{}}}, nil), true
+}
+
+func (s *Server) Mutate(_ context.Context, req *proto.MutateRequest) (*proto.MutateResponse, error) {
+	resp, published, err := s.mutate(req)
+	for _, evt := range published {
+		s.events.publish(evt)
+	}
+	return resp, err
+}
+
+func (s *Server) mutate(req *proto.MutateRequest) (*proto.MutateResponse, []*proto.EventStreamResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	schema, ok := s.schemas[schemaKey(req.GetSchema().GetSource(), req.GetSchema().GetKey())]
+	if !ok {
+		return nil, nil, status.Errorf(codes.NotFound, "schema %s has not been defined", req.GetSchema().GetKey())
+	}
+
+	workspace := req.GetAuthorization().GetWorkspaceId()
+	now := time.Now()
+	ent, exists := s.entities[req.GetEntityId()]
+	if exists && (ent.workspace != workspace || ent.schema.GetId() != schema.GetId()) {
+		return nil, nil, status.Errorf(codes.NotFound, "entity %s not found", req.GetEntityId())
+	}
+	if !exists {
+		ent = &entity{
+			id:          req.GetEntityId(),
+			schema:      schema,
+			workspace:   workspace,
+			state:       proto.EntityState_Active,
+			created:     now,
+			stateChange: now,
+			properties:  make(map[string]*proto.Value),
+			dynamic:     make(map[string]*proto.Value),
+			labels:      make(map[string]string),
+		}
+		if ent.id == "" {
+			ent.id = s.ids.New().String()
+		}
+	}
+
+	if !matchesAll(ent, req.GetWhere()) {
+		return &proto.MutateResponse{ErrorCode: http.StatusConflict, ErrorMessage: validationConditionsMessage}, nil, nil
+	}
+
+	mutation := req.GetMutation()
+	if conflict, property := s.uniqueConflict(ent, mutation.GetProperties()); conflict != nil {
+		if slices.Contains(req.GetOptions(), proto.MutateRequest_OnConflictIgnore) {
+			return &proto.MutateResponse{Success: true, EntityId: conflict.id}, nil, nil
+		}
+		if !slices.Contains(req.GetConflictUniquePropertyAcquire(), property) {
+			return &proto.MutateResponse{
+				ErrorCode:    http.StatusConflict,
+				ErrorMessage: fmt.Sprintf("unique property %s conflicts with entity %s", property, conflict.id),
+			}, nil, nil
+		}
+		delete(conflict.properties, property)
+	}
+
+	for _, p := range mutation.GetProperties() {
+		ent.properties[p.GetProperty()] = applyValue(ent.properties[p.GetProperty()], p.GetValue())
+	}
+	for _, p := range mutation.GetDynamicProperties() {
+		ent.dynamic[p.GetProperty()] = applyValue(ent.dynamic[p.GetProperty()], p.GetValue())
+	}
+	for _, name := range mutation.GetRemoveDynamicProperties() {
+		delete(ent.dynamic, name)
+	}
+	for _, l := range mutation.GetLabels() {
+		ent.labels[l.GetName()] = l.GetValue()
+	}
+	for _, l := range mutation.GetRemoveLabels() {
+		delete(ent.labels, l.GetName())
+	}
+	if mutation.GetState() != proto.EntityState_Invalid && mutation.GetState() != ent.state {
+		ent.state = mutation.GetState()
+		ent.stateChange = now
+	}
+
+	var published []*proto.EventStreamResponse
+	for _, evt := range mutation.GetEvents() {
+		evt = clone(evt)
+		if evt.GetTime() == nil {
+			evt.Time = timestamppb.New(now)
+		}
+		if evt.GetType() != nil && evt.GetType().GetSource() == nil {
+			evt.Type.Source = clone(req.GetAuthorization().GetSource())
+		}
+		ent.events = append(ent.events, evt)
+		published = append(published, &proto.EventStreamResponse{Ws: workspace, Eid: ent.id, Event: evt})
+	}
+
+	ent.lastUpdate = now
+	s.entities[ent.id] = ent
+	return &proto.MutateResponse{Success: true, EntityId: ent.id, TransactionId: s.ids.New().String()}, published, nil
+}
+
+// uniqueConflict returns the entity already holding a unique property value being written
+func (s *Server) uniqueConflict(ent *entity, properties []*proto.EntityProperty) (*entity, string) {
+	for _, p := range properties {
+		if !isUnique(ent.schema, p.GetProperty()) || isNull(p.GetValue()) {
+			continue
+		}
+		for _, other := range s.entities {
+			if other.id == ent.id || other.workspace != ent.workspace || other.schema.GetId() != ent.schema.GetId() {
+				continue
+			}
+			if existing := other.properties[p.GetProperty()]; !isNull(existing) && compareValues(ent.propertyType(p.GetProperty()), existing, p.GetValue()) == 0 {
+				return other, p.GetProperty()
+			}
+		}
+	}
+	return nil, ""
+}
+
+func isUnique(schema *proto.Schema, property string) bool {
+	for _, p := range schema.GetProperties() {
+		if p.GetName() == property {
+			return slices.Contains(p.GetOptions(), proto.Property_Unique)
+		}
+	}
+	return false
+}
+
+func (s *Server) Destroy(_ context.Context, req *proto.DestroyRequest) (*proto.DestroyResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ent, ok := s.entities[req.GetEid()]
+	if !ok || ent.workspace != req.GetAuthorization().GetWorkspaceId() {
+		return &proto.DestroyResponse{}, nil
+	}
+	delete(s.entities, ent.id)
+	return &proto.DestroyResponse{Destroyed: true}, nil
+}
+
+func (s *Server) Retrieve(_ context.Context, req *proto.EntityRequest) (*proto.EntityResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	workspace := req.GetAuthorization().GetWorkspaceId()
+	var found *entity
+	if req.GetEntityId() != "" {
+		if ent, ok := s.entities[req.GetEntityId()]; ok && ent.workspace == workspace {
+			found = ent
+		}
+	} else if lookup := req.GetUniqueId(); lookup != nil {
+		for _, ent := range s.entities {
+			if ent.workspace != workspace || (ent.schema.GetId() != lookup.GetSchemaId() && ent.schema.GetType() != lookup.GetSchemaId()) {
+				continue
+			}
+			if v := ent.properties[lookup.GetProperty()]; !isNull(v) && v.GetText() == lookup.GetUniqueId() {
+				found = ent
+				break
+			}
+		}
+	}
+
+	if found == nil {
+		return nil, status.Error(codes.NotFound, "entity not found")
+	}
+
+	for _, verify := range req.GetVerifyProperties() {
+		if compareValues(found.propertyType(verify.GetProperty()), found.value(verify.GetProperty()), verify.GetValue()) != 0 {
+			return nil, status.Errorf(codes.FailedPrecondition, "property %s could not be verified", verify.GetProperty())
+		}
+	}
+
+	return found.response(req.GetView(), nil), nil
+}
+
+func (s *Server) Find(_ context.Context, req *proto.FindRequest) (*proto.FindResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	matched := s.filterEntities(req.GetAuthorization(), req.GetSchema(), req.GetEntityIds(), req.GetPropertyFilters())
+	resp := &proto.FindResponse{}
+	for _, ent := range matched {
+		if !hasLabels(ent, req.GetLabelFilters()) {
+			continue
+		}
+		resp.Entities = append(resp.Entities, ent.response(req.GetView(), nil))
+	}
+	resp.TotalResults = int32(len(resp.Entities))
+	return resp, nil
+}
+
+func (s *Server) QueryIndex(_ context.Context, req *proto.QueryIndexRequest) (*proto.QueryIndexResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	matched := s.filterEntities(req.GetAuthorization(), req.GetSchema(), req.GetEntityIds(), req.GetFilters())
+	sortEntities(matched, req.GetSort())
+
+	resp := &proto.QueryIndexResponse{TotalResults: int32(len(matched))}
+	if page := req.GetPage(); page.GetPerPage() > 0 {
+		start := 0
+		if page.GetAfterId() != "" {
+			start = len(matched)
+			for i, ent := range matched {
+				if ent.id == page.GetAfterId() {
+					start = i + 1
+					break
+				}
+			}
+		} else if page.GetPageNumber() > 1 {
+			start = int((page.GetPageNumber() - 1) * page.GetPerPage())
+		}
+		start = min(start, len(matched))
+		matched = matched[start:min(start+int(page.GetPerPage()), len(matched))]
+	}
+
+	properties := req.GetProperties()
+	if properties == nil {
+		properties = []string{}
+	}
+	for _, ent := range matched {
+		resp.Entities = append(resp.Entities, ent.response(nil, properties))
+	}
+	if len(matched) > 0 {
+		resp.LastId = matched[len(matched)-1].id
+	}
+	return resp, nil
+}
+
+func (s *Server) Events(_ context.Context, req *proto.EventRequest) (*proto.EventsResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	resp := &proto.EventsResponse{}
+	ent, ok := s.entities[req.GetEntityId()]
+	if !ok || ent.workspace != req.GetAuthorization().GetWorkspaceId() {
+		return resp, nil
+	}
+
+	for _, evt := range ent.events {
+		if len(req.GetEventByType()) > 0 && !slices.ContainsFunc(req.GetEventByType(), func(k *proto.Key) bool { return keyMatches(k, evt.GetType()) }) {
+			continue
+		}
+		if window := req.GetEventsInWindow(); window != nil {
+			at := evt.GetTime().AsTime()
+			if (window.GetSince() != nil && at.Before(window.GetSince().AsTime())) || (window.GetUntil() != nil && at.After(window.GetUntil().AsTime())) {
+				continue
+			}
+		}
+		resp.Events = append(resp.Events, clone(evt))
+	}
+	return resp, nil
+}
+
+// filterEntities returns the entities of schemaKey within the workspace, matching the ids and filters, sorted by ID
+func (s *Server) filterEntities(auth *proto.Authorization, schema *proto.Key, entityIDs []string, filters []*proto.PropertyFilter) []*entity {
+	defined, ok := s.schemas[schemaKey(schema.GetSource(), schema.GetKey())]
+	if !ok {
+		return nil
+	}
+
+	var matched []*entity
+	for _, ent := range s.entities {
+		if ent.workspace != auth.GetWorkspaceId() || ent.schema.GetId() != defined.GetId() {
+			continue
+		}
+		if len(entityIDs) > 0 && !slices.Contains(entityIDs, ent.id) {
+			continue
+		}
+		if matchesAll(ent, filters) {
+			matched = append(matched, ent)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].id < matched[j].id })
+	return matched
+}
+
+func hasLabels(ent *entity, labels []*proto.EntityLabel) bool {
+	for _, l := range labels {
+		if v, ok := ent.labels[l.GetName()]; !ok || (l.GetValue() != "" && v != l.GetValue()) {
+			return false
+		}
+	}
+	return true
+}
+
+func keyMatches(filter, key *proto.Key) bool {
+	if filter == nil {
+		return true
+	}
+	if filter.GetKey() != "" && filter.GetKey() != key.GetKey() {
+		return false
+	}
+	if filter.GetSource().GetVendorId() != "" && filter.GetSource().GetVendorId() != key.GetSource().GetVendorId() {
+		return false
+	}
+	return filter.GetSource().GetAppId() == "" || filter.GetSource().GetAppId() == key.GetSource().GetAppId()
+}
+
+// response builds an entity response for the view, or for the given index properties when view is nil
+func (e *entity) response(view *proto.EntityView, indexProperties []string) *proto.EntityResponse {
+	exists := true
+	resp := &proto.EntityResponse{
+		Exists: &exists,
+		Entity: &proto.Entity{
+			EntityId:    e.id,
+			SchemaId:    e.schema.GetId(),
+			Created:     timestamppb.New(e.created),
+			StateChange: timestamppb.New(e.stateChange),
+			State:       e.state,
+			LastUpdate:  timestamppb.New(e.lastUpdate),
+		},
+	}
+
+	include := func(name string) bool { return slices.Contains(indexProperties, name) || len(indexProperties) == 0 }
+	if view != nil {
+		include = func(name string) bool {
+			for _, req := range view.GetProperties() {
+				if len(req.GetProperties()) == 0 || slices.Contains(req.GetProperties(), name) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	names := make([]string, 0, len(e.properties))
+	for name := range e.properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if include(name) {
+			resp.Properties = append(resp.Properties, &proto.EntityProperty{Property: name, Value: clone(e.properties[name])})
+		}
+	}
+
+	if view == nil {
+		return resp
+	}
+
+	for _, name := range view.GetDynamicProperties() {
+		if v, ok := e.dynamic[name]; ok {
+			resp.DynamicProperties = append(resp.DynamicProperties, &proto.EntityProperty{Property: name, Value: clone(v)})
+		}
+	}
+
+	if view.GetLabels() {
+		labels := make([]string, 0, len(e.labels))
+		for name := range e.labels {
+			labels = append(labels, name)
+		}
+		sort.Strings(labels)
+		for _, name := range labels {
+			resp.Labels = append(resp.Labels, &proto.EntityLabel{Name: name, Value: e.labels[name]})
+		}
+	}
+	return resp
+}
@@ -0,0 +1,74 @@
+package keystonetest
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+func enumKey(auth *proto.Authorization, enumType string) string {
+	return appKey(auth.GetSource()) + "/" + enumType
+}
+
+func (s *Server) EnumPut(_ context.Context, req *proto.EnumPutRequest) (*proto.GenericResponse, error) {
+	if req.GetEnum().GetType() == "" || req.GetEnum().GetKey() == "" {
+		return &proto.GenericResponse{ErrorCode: http.StatusBadRequest, ErrorMessage: "enum type and key are required"}, nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := enumKey(req.GetAuthorization(), req.GetEnum().GetType())
+	if s.enums[key] == nil {
+		s.enums[key] = make(map[string]*proto.EnumEntry)
+	}
+	s.enums[key][req.GetEnum().GetKey()] = clone(req.GetEnum())
+	return &proto.GenericResponse{Success: true}, nil
+}
+
+func (s *Server) EnumGet(_ context.Context, req *proto.EnumGetRequest) (*proto.EnumGetResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	entry, ok := s.enums[enumKey(req.GetAuthorization(), req.GetType())][req.GetKey()]
+	if !ok {
+		return &proto.EnumGetResponse{Summary: &proto.GenericResponse{ErrorCode: http.StatusNotFound, ErrorMessage: "enum not found"}}, nil
+	}
+	return &proto.EnumGetResponse{Summary: &proto.GenericResponse{Success: true}, Enum: clone(entry)}, nil
+}
+
+func (s *Server) EnumDelete(_ context.Context, req *proto.EnumDeleteRequest) (*proto.GenericResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := enumKey(req.GetAuthorization(), req.GetType())
+	if req.GetKey() == "" {
+		delete(s.enums, key)
+	} else {
+		delete(s.enums[key], req.GetKey())
+	}
+	return &proto.GenericResponse{Success: true}, nil
+}
+
+func (s *Server) EnumList(_ context.Context, req *proto.EnumListRequest) (*proto.EnumListResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	resp := &proto.EnumListResponse{Summary: &proto.GenericResponse{Success: true}}
+	for _, entry := range s.enums[enumKey(req.GetAuthorization(), req.GetType())] {
+		resp.Enums = append(resp.Enums, clone(entry))
+	}
+	sort.Slice(resp.Enums, func(i, j int) bool { return resp.Enums[i].GetKey() < resp.Enums[j].GetKey() })
+	return resp, nil
+}
+
+func (s *Server) EnumReplace(_ context.Context, req *proto.EnumReplaceRequest) (*proto.GenericResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	entries := make(map[string]*proto.EnumEntry, len(req.GetEnums()))
+	for _, entry := range req.GetEnums() {
+		entry = clone(entry)
+		entry.Type = req.GetType()
+		entries[entry.GetKey()] = entry
+	}
+	s.enums[enumKey(req.GetAuthorization(), req.GetType())] = entries
+	return &proto.GenericResponse{Success: true}, nil
+}
@@ -0,0 +1,227 @@
+package keystonetest
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+// matchesAll returns true when the entity matches every filter
+func matchesAll(ent *entity, filters []*proto.PropertyFilter) bool {
+	for _, filter := range filters {
+		if !matches(ent, filter) {
+			return false
+		}
+	}
+	return true
+}
+
+func matches(ent *entity, filter *proto.PropertyFilter) bool {
+	if len(filter.GetNested()) > 0 {
+		if !filter.GetOr() {
+			return matchesAll(ent, filter.GetNested())
+		}
+		for _, nested := range filter.GetNested() {
+			if matches(ent, nested) {
+				return true
+			}
+		}
+		return false
+	}
+
+	value := ent.value(filter.GetProperty())
+	dataType := ent.propertyType(filter.GetProperty())
+	values := filter.GetValues()
+	first := &proto.Value{}
+	if len(values) > 0 {
+		first = values[0]
+	}
+
+	switch filter.GetOperator() {
+	case proto.Operator_IsNull:
+		return isNull(value)
+	case proto.Operator_IsNotNull:
+		return !isNull(value)
+	case proto.Operator_In, proto.Operator_NotIn:
+		in := !isNull(value) && slices.ContainsFunc(values, func(v *proto.Value) bool { return compareValues(dataType, value, v) == 0 })
+		return in == (filter.GetOperator() == proto.Operator_In)
+	case proto.Operator_Contains:
+		return contains(value, first)
+	case proto.Operator_NotContains:
+		return !contains(value, first)
+	case proto.Operator_StartsWith:
+		return strings.HasPrefix(value.GetText(), first.GetText())
+	case proto.Operator_EndsWith:
+		return strings.HasSuffix(value.GetText(), first.GetText())
+	case proto.Operator_NotEqual:
+		return isNull(value) || compareValues(dataType, value, first) != 0
+	}
+
+	if isNull(value) {
+		return false
+	}
+
+	c := compareValues(dataType, value, first)
+	switch filter.GetOperator() {
+	case proto.Operator_Equal:
+		return c == 0
+	case proto.Operator_GreaterThan:
+		return c > 0
+	case proto.Operator_GreaterThanOrEqual:
+		return c >= 0
+	case proto.Operator_LessThan:
+		return c < 0
+	case proto.Operator_LessThanOrEqual:
+		return c <= 0
+	case proto.Operator_Between:
+		return len(values) > 1 && c >= 0 && compareValues(dataType, value, values[1]) <= 0
+	}
+	return false
+}
+
+func contains(value, search *proto.Value) bool {
+	if isNull(value) {
+		return false
+	}
+	if arr := value.GetArray(); !arr.IsZero() {
+		if search.GetText() != "" {
+			if slices.Contains(arr.GetStrings(), search.GetText()) {
+				return true
+			}
+			_, ok := arr.GetKeyValue()[search.GetText()]
+			return ok
+		}
+		return slices.Contains(arr.GetInts(), search.GetInt())
+	}
+	return strings.Contains(strings.ToLower(value.GetText()), strings.ToLower(search.GetText()))
+}
+
+func isNull(v *proto.Value) bool {
+	return v == nil || v.GetIsNull()
+}
+
+// valueType guesses the data type of a value with no schema definition
+func valueType(v *proto.Value) proto.Property_Type {
+	switch {
+	case v.GetKnownType() != proto.Property_Text:
+		return v.GetKnownType()
+	case v.GetTime() != nil:
+		return proto.Property_Time
+	case v.GetText() != "":
+		return proto.Property_Text
+	case v.GetInt() != 0:
+		return proto.Property_Number
+	case v.GetFloat() != 0:
+		return proto.Property_Float
+	case v.GetBool():
+		return proto.Property_Boolean
+	}
+	return proto.Property_Text
+}
+
+// compareValues compares two values of the given data type
+func compareValues(dataType proto.Property_Type, a, b *proto.Value) int {
+	switch dataType {
+	case proto.Property_Number:
+		return cmp.Compare(a.GetInt(), b.GetInt())
+	case proto.Property_Float:
+		return cmp.Compare(a.GetFloat(), b.GetFloat())
+	case proto.Property_Boolean:
+		return cmp.Compare(boolInt(a.GetBool()), boolInt(b.GetBool()))
+	case proto.Property_Time:
+		return a.GetTime().AsTime().Compare(b.GetTime().AsTime())
+	case proto.Property_Amount:
+		if c := cmp.Compare(a.GetText(), b.GetText()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.GetInt(), b.GetInt())
+	case proto.Property_SecureText:
+		return cmp.Compare(a.GetSecureText(), b.GetSecureText())
+	}
+	return cmp.Compare(a.GetText(), b.GetText())
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// sortEntities sorts entities by the requested properties, falling back to entity ID
+func sortEntities(entities []*entity, sorts []*proto.PropertySort) {
+	sort.SliceStable(entities, func(i, j int) bool {
+		for _, s := range sorts {
+			a, b := entities[i].value(s.GetProperty()), entities[j].value(s.GetProperty())
+			if isNull(a) || isNull(b) {
+				if isNull(a) == isNull(b) {
+					continue
+				}
+				return isNull(a) == s.GetNullsFirst()
+			}
+			c := compareValues(entities[i].propertyType(s.GetProperty()), a, b)
+			if c == 0 {
+				continue
+			}
+			if s.GetDescending() {
+				return c > 0
+			}
+			return c < 0
+		}
+		return entities[i].id < entities[j].id
+	})
+}
+
+// applyValue applies an incoming property value to the existing value, handling array appends and reductions
+func applyValue(existing, incoming *proto.Value) *proto.Value {
+	if incoming == nil {
+		return existing
+	}
+	if incoming.GetArrayAppend() == nil && incoming.GetArrayReduce() == nil {
+		return clone(incoming)
+	}
+
+	result := clone(incoming)
+	array := proto.NewRepeatedValue()
+	if !incoming.GetArray().IsZero() {
+		array = clone(incoming.GetArray())
+	} else if !existing.GetArray().IsZero() {
+		array = clone(existing.GetArray())
+	}
+	if array.KeyValue == nil {
+		array.KeyValue = make(map[string][]byte)
+	}
+	if array.Mixed == nil {
+		array.Mixed = make(map[string]*proto.Value)
+	}
+
+	if add := incoming.GetArrayAppend(); add != nil {
+		array.Strings = append(array.Strings, add.GetStrings()...)
+		array.Ints = append(array.Ints, add.GetInts()...)
+		for k, v := range add.GetKeyValue() {
+			array.KeyValue[k] = v
+		}
+		for k, v := range add.GetMixed() {
+			array.Mixed[k] = clone(v)
+		}
+	}
+
+	if reduce := incoming.GetArrayReduce(); reduce != nil {
+		array.Strings = slices.DeleteFunc(array.Strings, func(s string) bool { return slices.Contains(reduce.GetStrings(), s) })
+		array.Ints = slices.DeleteFunc(array.Ints, func(i int64) bool { return slices.Contains(reduce.GetInts(), i) })
+		for k := range reduce.GetKeyValue() {
+			delete(array.KeyValue, k)
+		}
+		for k := range reduce.GetMixed() {
+			delete(array.Mixed, k)
+		}
+	}
+
+	result.Array = array
+	result.ArrayAppend = nil
+	result.ArrayReduce = nil
+	return result
+}
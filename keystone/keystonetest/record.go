@@ -0,0 +1,184 @@
+package keystonetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// recording is the golden file format, holding every call in the order it was made
+type recording struct {
+	Interactions []*interaction `json:"interactions"`
+}
+
+type interaction struct {
+	Method   string            `json:"method"`
+	Stream   bool              `json:"stream,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Request  json.RawMessage   `json:"request,omitempty"`
+	Response json.RawMessage   `json:"response,omitempty"`
+	Error    *recordedError    `json:"error,omitempty"`
+	Frames   []*frame          `json:"frames,omitempty"`
+}
+
+// frame is a single message sent or received on a stream, or the end of the stream
+type frame struct {
+	Send  json.RawMessage `json:"send,omitempty"`
+	Recv  json.RawMessage `json:"recv,omitempty"`
+	Error *recordedError  `json:"error,omitempty"`
+	EOF   bool            `json:"eof,omitempty"`
+}
+
+type recordedError struct {
+	Code    codes.Code `json:"code"`
+	Message string     `json:"message"`
+}
+
+func newRecordedError(err error) *recordedError {
+	if err == nil {
+		return nil
+	}
+	st := status.Convert(err)
+	return &recordedError{Code: st.Code(), Message: st.Message()}
+}
+
+func (e *recordedError) err() error {
+	if e == nil {
+		return nil
+	}
+	return status.Error(e.Code, e.Message)
+}
+
+var marshaller = protojson.MarshalOptions{UseProtoNames: true}
+
+func marshalMessage(msg any) json.RawMessage {
+	pm, ok := msg.(protobuf.Message)
+	if !ok || pm == nil || !pm.ProtoReflect().IsValid() {
+		return nil
+	}
+	raw, err := marshaller.Marshal(pm)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+func unmarshalMessage(raw json.RawMessage, dst any) error {
+	pm, ok := dst.(protobuf.Message)
+	if !ok {
+		return errors.New("keystonetest: replay destination is not a proto message")
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	return protojson.Unmarshal(raw, pm)
+}
+
+// recordedMetadata captures the outgoing metadata keys used to route streams, such as task_name
+func recordedMetadata(ctx context.Context) map[string]string {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if vals := md.Get("task_name"); len(vals) > 0 {
+		return map[string]string{"task_name": vals[0]}
+	}
+	return nil
+}
+
+// Recorder wraps a gRPC connection to a keystone server, recording every call so it can be replayed with a Replayer
+type Recorder struct {
+	conn         grpc.ClientConnInterface
+	lock         sync.Mutex
+	interactions []*interaction
+}
+
+// NewRecorder records all calls made through conn
+func NewRecorder(conn grpc.ClientConnInterface) *Recorder {
+	return &Recorder{conn: conn}
+}
+
+// Client returns a KeystoneClient which records calls, for use with keystone.NewConnection
+func (r *Recorder) Client() proto.KeystoneClient {
+	return proto.NewKeystoneClient(r)
+}
+
+// Save writes all recorded calls to the golden file at path
+func (r *Recorder) Save(path string) error {
+	r.lock.Lock()
+	raw, err := json.MarshalIndent(recording{Interactions: r.interactions}, "", "  ")
+	r.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0644)
+}
+
+func (r *Recorder) add(i *interaction) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.interactions = append(r.interactions, i)
+}
+
+func (r *Recorder) Invoke(ctx context.Context, method string, args any, reply any, opts ...grpc.CallOption) error {
+	err := r.conn.Invoke(ctx, method, args, reply, opts...)
+	i := &interaction{Method: method, Request: marshalMessage(args), Error: newRecordedError(err)}
+	if err == nil {
+		i.Response = marshalMessage(reply)
+	}
+	r.add(i)
+	return err
+}
+
+func (r *Recorder) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	i := &interaction{Method: method, Stream: true, Metadata: recordedMetadata(ctx)}
+	stream, err := r.conn.NewStream(ctx, desc, method, opts...)
+	if err != nil {
+		i.Error = newRecordedError(err)
+		r.add(i)
+		return nil, err
+	}
+	r.add(i)
+	return &recordingStream{ClientStream: stream, recorder: r, interaction: i}, nil
+}
+
+type recordingStream struct {
+	grpc.ClientStream
+	recorder    *Recorder
+	interaction *interaction
+}
+
+func (s *recordingStream) addFrame(f *frame) {
+	s.recorder.lock.Lock()
+	defer s.recorder.lock.Unlock()
+	s.interaction.Frames = append(s.interaction.Frames, f)
+}
+
+func (s *recordingStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.addFrame(&frame{Send: marshalMessage(m)})
+	}
+	return err
+}
+
+func (s *recordingStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		s.addFrame(&frame{Recv: marshalMessage(m)})
+	case errors.Is(err, io.EOF):
+		s.addFrame(&frame{EOF: true})
+	default:
+		s.addFrame(&frame{Error: newRecordedError(err)})
+	}
+	return err
+}
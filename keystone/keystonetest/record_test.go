@@ -0,0 +1,141 @@
+package keystonetest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keystonedb/sdk-go/keystone"
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// exercise runs the same calls against a connection, returning the stored user and the streamed event
+func exercise(t *testing.T, conn *keystone.Connection, publish func()) (*testUser, *proto.EventStreamResponse) {
+	t.Helper()
+	actor := conn.Actor("ws-1", "127.0.0.1", "user-1", "go-test")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user := &testUser{Name: "Alice", Email: "alice@example.com", Age: 30}
+	if err := actor.Mutate(ctx, user, keystone.WithMutationComment("create")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded := &testUser{}
+	if err := actor.GetByID(ctx, user.GetKeystoneID(), loaded, keystone.WithProperties()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	streamCtx, stopStream := context.WithCancel(ctx)
+	received := make(chan *proto.EventStreamResponse, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = actor.EventStream(streamCtx, func(evt *proto.EventStreamResponse) error {
+			received <- evt
+			return nil
+		}, "stream", keystone.OwnKey("signed-up"))
+	}()
+	publish()
+
+	var evt *proto.EventStreamResponse
+	select {
+	case evt = <-received:
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for event")
+	}
+	stopStream()
+	<-done
+	return loaded, evt
+}
+
+func TestRecordReplay(t *testing.T) {
+	server := NewServer()
+	server.Start()
+	defer server.Close()
+
+	cc, err := grpc.NewClient("passthrough:///keystonetest",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return server.listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cc.Close() }()
+
+	recorder := NewRecorder(cc)
+	recorded, recordedEvt := exercise(t, keystone.NewConnection(recorder.Client(), "vendor", "app", "secret"), func() {
+		for server.EventSubscribers() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		server.PublishEvent("ws-1", "eid-1", &proto.EntityEvent{Type: &proto.Key{Key: "signed-up", Source: &proto.VendorApp{VendorId: "vendor", AppId: "app"}}})
+	})
+
+	golden := filepath.Join(t.TempDir(), "golden.json")
+	if err = recorder.Save(golden); err != nil {
+		t.Fatalf("unable to save recording: %v", err)
+	}
+
+	replayer, err := NewReplayer(golden)
+	if err != nil {
+		t.Fatalf("unable to load recording: %v", err)
+	}
+	conn := keystone.NewConnection(replayer.Client(), "vendor", "app", "another-token")
+	replayed, replayedEvt := exercise(t, conn, func() {})
+
+	if replayed.GetKeystoneID() != recorded.GetKeystoneID() || replayed.Name != "Alice" || replayed.Age != 30 {
+		t.Errorf("expected replayed entity to match recording, got %+v", replayed)
+	}
+	if replayedEvt.GetEid() != recordedEvt.GetEid() {
+		t.Errorf("expected replayed event %v, got %v", recordedEvt, replayedEvt)
+	}
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Errorf("expected all recorded calls to be replayed, got %v", unused)
+	}
+
+	actor := conn.Actor("ws-1", "127.0.0.1", "user-1", "go-test")
+	err = actor.GetByID(context.Background(), "unrecorded", &testUser{})
+	if !errors.Is(err, ErrUnmatchedCall) {
+		t.Errorf("expected unmatched call error, got %v", err)
+	}
+	if unmatched := replayer.Unmatched(); len(unmatched) != 1 {
+		t.Errorf("expected 1 unmatched call, got %v", unmatched)
+	}
+}
+
+func TestReplayerNormalize(t *testing.T) {
+	r := &Replayer{ignore: map[string]bool{}, unordered: map[string]bool{}}
+	UnorderedFields(DefaultUnorderedFields...)(r)
+
+	normalize := func(msg any) string { return r.normalize(marshalMessage(msg)) }
+	sorted := func(props ...string) *proto.QueryIndexRequest {
+		req := &proto.QueryIndexRequest{}
+		for _, p := range props {
+			req.Sort = append(req.Sort, &proto.PropertySort{Property: p})
+		}
+		return req
+	}
+	if normalize(sorted("name", "age")) == normalize(sorted("age", "name")) {
+		t.Errorf("expected the sort order to be significant")
+	}
+
+	mutation := func(props ...string) *proto.MutateRequest {
+		req := &proto.MutateRequest{Mutation: &proto.Mutation{}}
+		for _, p := range props {
+			req.Mutation.Properties = append(req.Mutation.Properties, &proto.EntityProperty{Property: p})
+		}
+		return req
+	}
+	if normalize(mutation("name", "age")) != normalize(mutation("age", "name")) {
+		t.Errorf("expected mutation properties to match in any order")
+	}
+
+	UnorderedFields("sort")(r)
+	if normalize(sorted("name", "age")) != normalize(sorted("age", "name")) {
+		t.Errorf("expected an unordered field to match in any order")
+	}
+}
@@ -0,0 +1,291 @@
+package keystonetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUnmatchedCall is returned by a Replayer when no recorded call matches the request
+var ErrUnmatchedCall = errors.New("keystonetest: no recorded call matches")
+
+// DefaultIgnoredFields are removed from requests before matching, as they change between runs
+var DefaultIgnoredFields = []string{"token", "trace_id", "timestamp"}
+
+// DefaultUnorderedFields are repeated fields built from maps by the SDK, so their order changes between runs.
+// Fields are named by their dotted path of proto names from the request root.
+var DefaultUnorderedFields = []string{
+	"mutation.properties",
+	"mutation.dynamic_properties",
+	"mutation.remove_dynamic_properties",
+	"mutation.labels",
+	"mutation.children",
+	"mutation.remove_children",
+	"schema.properties",
+}
+
+// ReplayOption configures a Replayer
+type ReplayOption func(*Replayer)
+
+// IgnoreFields removes fields with the given proto names, at any depth, before matching requests
+func IgnoreFields(fields ...string) ReplayOption {
+	return func(r *Replayer) {
+		for _, field := range fields {
+			r.ignore[field] = true
+		}
+	}
+}
+
+// UnorderedFields matches the repeated fields at the given dotted paths regardless of the order of their values.
+// Every other repeated field must be in the recorded order.
+func UnorderedFields(paths ...string) ReplayOption {
+	return func(r *Replayer) {
+		for _, path := range paths {
+			r.unordered[path] = true
+		}
+	}
+}
+
+// Replayer serves calls from a golden file written by a Recorder.
+// Requests are matched by method and normalized payload, with each recorded call replayed once, in order.
+type Replayer struct {
+	lock         sync.Mutex
+	interactions []*interaction
+	used         []bool
+	ignore       map[string]bool
+	unordered    map[string]bool
+	unmatched    []string
+}
+
+// NewReplayer loads the golden file at path
+func NewReplayer(path string, opts ...ReplayOption) (*Replayer, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rec := recording{}
+	if err = json.Unmarshal(raw, &rec); err != nil {
+		return nil, fmt.Errorf("keystonetest: invalid recording %s: %w", path, err)
+	}
+
+	r := &Replayer{
+		interactions: rec.Interactions,
+		used:         make([]bool, len(rec.Interactions)),
+		ignore:       make(map[string]bool),
+		unordered:    make(map[string]bool),
+	}
+	IgnoreFields(DefaultIgnoredFields...)(r)
+	UnorderedFields(DefaultUnorderedFields...)(r)
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// Client returns a KeystoneClient which replays recorded calls, for use with keystone.NewConnection
+func (r *Replayer) Client() proto.KeystoneClient {
+	return proto.NewKeystoneClient(r)
+}
+
+// Unmatched returns a description of each call which did not match a recording
+func (r *Replayer) Unmatched() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return slices.Clone(r.unmatched)
+}
+
+// Unused returns the methods of recorded calls which have not been replayed
+func (r *Replayer) Unused() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var unused []string
+	for i, used := range r.used {
+		if !used {
+			unused = append(unused, r.interactions[i].Method)
+		}
+	}
+	return unused
+}
+
+// claim marks the first unused interaction accepted by match as used
+func (r *Replayer) claim(method string, describe string, match func(*interaction) bool) (*interaction, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i, rec := range r.interactions {
+		if !r.used[i] && rec.Method == method && match(rec) {
+			r.used[i] = true
+			return rec, nil
+		}
+	}
+	call := method + " " + describe
+	r.unmatched = append(r.unmatched, call)
+	return nil, fmt.Errorf("%w: %s", ErrUnmatchedCall, call)
+}
+
+func (r *Replayer) Invoke(_ context.Context, method string, args any, reply any, _ ...grpc.CallOption) error {
+	request := r.normalize(marshalMessage(args))
+	rec, err := r.claim(method, request, func(rec *interaction) bool {
+		return !rec.Stream && r.normalize(rec.Request) == request
+	})
+	if err != nil {
+		return err
+	}
+	if rec.Error != nil {
+		return rec.Error.err()
+	}
+	return unmarshalMessage(rec.Response, reply)
+}
+
+func (r *Replayer) NewStream(ctx context.Context, _ *grpc.StreamDesc, method string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+	return &replayStream{ctx: ctx, replayer: r, method: method, metadata: recordedMetadata(ctx)}, nil
+}
+
+// normalize returns a canonical form of a recorded message, removing ignored fields and ordering unordered fields
+func (r *Replayer) normalize(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return "{}"
+	}
+	var decoded any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return string(raw)
+	}
+	out, _ := json.Marshal(r.canonical(decoded, ""))
+	return string(out)
+}
+
+// canonical normalizes v, which is found at the dotted path within the message
+func (r *Replayer) canonical(v any, path string) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			if r.ignore[k] {
+				delete(val, k)
+				continue
+			}
+			childPath := k
+			if path != "" {
+				childPath = path + "." + k
+			}
+			val[k] = r.canonical(child, childPath)
+		}
+		return val
+	case []any:
+		for i, child := range val {
+			val[i] = r.canonical(child, path)
+		}
+		if !r.unordered[path] {
+			return val
+		}
+		encoded := make([]string, len(val))
+		for i, child := range val {
+			b, _ := json.Marshal(child)
+			encoded[i] = string(b)
+		}
+		sort.Strings(encoded)
+		sorted := make([]any, len(encoded))
+		for i, e := range encoded {
+			sorted[i] = json.RawMessage(e)
+		}
+		return sorted
+	}
+	return v
+}
+
+// replayStream replays the frames of a recorded stream, binding to a recording on the first message sent or received
+type replayStream struct {
+	ctx         context.Context
+	replayer    *Replayer
+	method      string
+	metadata    map[string]string
+	interaction *interaction
+	sendPos     int
+	recvPos     int
+}
+
+func (s *replayStream) bind(firstSend string) error {
+	if s.interaction != nil {
+		return nil
+	}
+	rec, err := s.replayer.claim(s.method, firstSend, func(rec *interaction) bool {
+		if !rec.Stream || !maps.Equal(rec.Metadata, s.metadata) {
+			return false
+		}
+		if firstSend == "" {
+			return true
+		}
+		return len(rec.Frames) > 0 && rec.Frames[0].Send != nil && s.replayer.normalize(rec.Frames[0].Send) == firstSend
+	})
+	s.interaction = rec
+	return err
+}
+
+func (s *replayStream) SendMsg(m any) error {
+	sent := s.replayer.normalize(marshalMessage(m))
+	if err := s.bind(sent); err != nil {
+		return err
+	}
+	frames := s.interaction.Frames
+	for ; s.sendPos < len(frames); s.sendPos++ {
+		if frames[s.sendPos].Send == nil {
+			continue
+		}
+		expected := s.replayer.normalize(frames[s.sendPos].Send)
+		s.sendPos++
+		if expected != sent {
+			return fmt.Errorf("%w: %s sent %s, expected %s", ErrUnmatchedCall, s.method, sent, expected)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s sent %s after the recorded stream", ErrUnmatchedCall, s.method, sent)
+}
+
+func (s *replayStream) RecvMsg(m any) error {
+	if err := s.bind(""); err != nil {
+		return err
+	}
+	if s.interaction.Error != nil {
+		return s.interaction.Error.err()
+	}
+
+	frames := s.interaction.Frames
+	for ; s.recvPos < len(frames); s.recvPos++ {
+		f := frames[s.recvPos]
+		switch {
+		case f.Recv != nil:
+			s.recvPos++
+			return unmarshalMessage(f.Recv, m)
+		case f.EOF:
+			return io.EOF
+		case f.Error != nil:
+			if f.Error.Code != codes.Canceled && f.Error.Code != codes.DeadlineExceeded {
+				return f.Error.err()
+			}
+			return s.wait()
+		}
+	}
+	// the recording ended while the stream was open, or was cancelled by the client
+	return s.wait()
+}
+
+func (s *replayStream) wait() error {
+	<-s.ctx.Done()
+	return status.FromContextError(s.ctx.Err()).Err()
+}
+
+func (s *replayStream) Header() (metadata.MD, error) { return metadata.MD{}, nil }
+func (s *replayStream) Trailer() metadata.MD         { return metadata.MD{} }
+func (s *replayStream) CloseSend() error             { return nil }
+func (s *replayStream) Context() context.Context     { return s.ctx }
@@ -0,0 +1,163 @@
+// Package keystonetest provides a stateful, in-memory keystone server for unit tests.
+//
+// The server stores schemas, entities, app key values, enums, tasks and events in memory,
+// allowing code built on the keystone SDK to be tested end-to-end over an in-memory gRPC connection.
+package keystonetest
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keystonedb/sdk-go/keystone"
+	"github.com/keystonedb/sdk-go/proto"
+	"github.com/kubex/k4id"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const bufSize = 1024 * 1024
+
+// Server is an in-memory implementation of proto.KeystoneServer
+type Server struct {
+	proto.UnimplementedKeystoneServer
+
+	lock     sync.RWMutex
+	ids      k4id.Generator
+	started  time.Time
+	schemas  map[string]*proto.Schema
+	entities map[string]*entity
+	akv      map[string]*proto.Value
+	akvTime  map[string][]timeEntry
+	enums    map[string]map[string]*proto.EnumEntry
+
+	tasks  *taskBroker
+	events *eventHub
+
+	serveOnce  sync.Once
+	grpcServer *grpc.Server
+	listener   *bufconn.Listener
+}
+
+// NewServer creates an empty in-memory keystone server
+func NewServer() *Server {
+	s := &Server{
+		ids:     k4id.NewGenerator(k4id.TimeGeneratorNano),
+		started: time.Now(),
+		tasks:   newTaskBroker(),
+		events:  newEventHub(),
+	}
+	s.Reset()
+	return s
+}
+
+// NewConnection starts a new server, and returns a connection to it.
+// The server and connection are closed when the test completes.
+func NewConnection(tb testing.TB, vendorID, appID string) (*keystone.Connection, *Server) {
+	tb.Helper()
+	s := NewServer()
+	conn, err := s.Dial(vendorID, appID, "")
+	if err != nil {
+		tb.Fatalf("keystonetest: unable to dial server: %v", err)
+	}
+	tb.Cleanup(func() {
+		conn.Close()
+		s.Close()
+	})
+	return conn, s
+}
+
+// Reset removes all stored data from the server
+func (s *Server) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.schemas = make(map[string]*proto.Schema)
+	s.entities = make(map[string]*entity)
+	s.akv = make(map[string]*proto.Value)
+	s.akvTime = make(map[string][]timeEntry)
+	s.enums = make(map[string]map[string]*proto.EnumEntry)
+	s.tasks.reset()
+}
+
+// Start serves the server on an in-memory listener, it is called automatically by Dial
+func (s *Server) Start() {
+	s.serveOnce.Do(func() {
+		s.listener = bufconn.Listen(bufSize)
+		s.grpcServer = grpc.NewServer()
+		proto.RegisterKeystoneServer(s.grpcServer, s)
+		go func() { _ = s.grpcServer.Serve(s.listener) }()
+	})
+}
+
+// Dial starts the server if required, and returns a new connection to it
+func (s *Server) Dial(vendorID, appID, accessToken string, opts ...keystone.ConnOption) (*keystone.Connection, error) {
+	s.Start()
+	dialer := grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return s.listener.DialContext(ctx)
+	})
+	return keystone.NewConnectionWithOptions("passthrough:///keystonetest", vendorID, appID, accessToken,
+		append([]keystone.ConnOption{keystone.WithDialOptions(dialer)}, opts...)...)
+}
+
+// Close stops the server, closing any open streams
+func (s *Server) Close() {
+	s.events.close()
+	s.tasks.close()
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
+		_ = s.listener.Close()
+	}
+}
+
+func (s *Server) Status(_ context.Context, req *proto.Authorization) (*proto.StatusResponse, error) {
+	return &proto.StatusResponse{
+		Authenticated:       true,
+		AuthenticatedVendor: req.GetSource().GetVendorId(),
+		AuthenticatedApp:    req.GetSource().GetAppId(),
+		Version:             "keystonetest",
+		UptimeSeconds:       int64(time.Since(s.started).Seconds()),
+	}, nil
+}
+
+func (s *Server) Define(_ context.Context, req *proto.SchemaRequest) (*proto.Schema, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	schema := clone(req.GetSchema())
+	if schema == nil {
+		schema = &proto.Schema{}
+	}
+	if schema.GetSource() == nil {
+		schema.Source = clone(req.GetAuthorization().GetSource())
+	}
+
+	key := schemaKey(schema.GetSource(), schema.GetType())
+	if existing, ok := s.schemas[key]; ok {
+		schema.Id = existing.GetId()
+		schema.Created = existing.GetCreated()
+	} else {
+		schema.Id = s.ids.New().String()
+		schema.Created = timestamppb.Now()
+	}
+	s.schemas[key] = schema
+	return clone(schema), nil
+}
+
+// Schema returns the schema defined for the given type by vendorID/appID
+func (s *Server) Schema(vendorID, appID, schemaType string) (*proto.Schema, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	schema, ok := s.schemas[schemaKey(&proto.VendorApp{VendorId: vendorID, AppId: appID}, schemaType)]
+	return clone(schema), ok
+}
+
+func schemaKey(source *proto.VendorApp, schemaType string) string {
+	return appKey(source) + "/" + schemaType
+}
+
+func appKey(source *proto.VendorApp) string {
+	return source.GetVendorId() + "/" + source.GetAppId()
+}
@@ -0,0 +1,296 @@
+package keystonetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/keystonedb/sdk-go/keystone"
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+type testUser struct {
+	keystone.BaseEntity
+	Name  string
+	Email string `keystone:",unique"`
+	Age   int64  `keystone:",indexed"`
+}
+
+func newTestActor(t *testing.T) (*keystone.Actor, *Server) {
+	t.Helper()
+	conn, server := NewConnection(t, "vendor", "app")
+	actor := conn.Actor("ws-1", "127.0.0.1", "user-1", "go-test")
+	return &actor, server
+}
+
+func createUsers(t *testing.T, actor *keystone.Actor, users ...*testUser) {
+	t.Helper()
+	for _, u := range users {
+		if err := actor.Mutate(context.Background(), u, keystone.WithMutationComment("create")); err != nil {
+			t.Fatalf("unable to create user %s: %v", u.Name, err)
+		}
+	}
+}
+
+func TestServer_MutateAndRetrieve(t *testing.T) {
+	actor, server := newTestActor(t)
+
+	user := &testUser{Name: "Alice", Email: "alice@example.com", Age: 30}
+	createUsers(t, actor, user)
+	if user.GetKeystoneID() == "" {
+		t.Fatal("expected entity id to be assigned")
+	}
+
+	if _, ok := server.Schema("vendor", "app", keystone.Type(user)); !ok {
+		t.Error("expected schema to be defined")
+	}
+
+	loaded := &testUser{}
+	if err := actor.GetByID(context.Background(), user.GetKeystoneID(), loaded, keystone.WithProperties()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.Name != "Alice" || loaded.Email != "alice@example.com" || loaded.Age != 30 {
+		t.Errorf("unexpected entity %+v", loaded)
+	}
+
+	loaded.Age = 31
+	if err := actor.Mutate(context.Background(), loaded, keystone.WithMutationComment("birthday")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stored, _ := server.Entity(user.GetKeystoneID().String())
+	for _, p := range stored.GetProperties() {
+		if p.GetProperty() == "age" && p.GetValue().GetInt() != 31 {
+			t.Errorf("expected age 31, got %d", p.GetValue().GetInt())
+		}
+	}
+
+	byEmail := &testUser{}
+	if err := actor.GetByUniqueProperty(context.Background(), "alice@example.com", "email", byEmail, keystone.WithProperties("name")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if byEmail.Name != "Alice" || byEmail.Email != "" {
+		t.Errorf("expected only name to be loaded, got %+v", byEmail)
+	}
+
+	if err := actor.GetByID(context.Background(), "missing", &testUser{}); !errors.Is(err, keystone.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestServer_UniqueConflict(t *testing.T) {
+	actor, _ := newTestActor(t)
+	createUsers(t, actor, &testUser{Name: "Alice", Email: "alice@example.com"})
+
+	err := actor.Mutate(context.Background(), &testUser{Name: "Other", Email: "alice@example.com"}, keystone.WithMutationComment("create"))
+	if !errors.Is(err, keystone.ErrUniqueConflict) {
+		t.Errorf("expected unique conflict, got %v", err)
+	}
+}
+
+func TestServer_IfUnchanged(t *testing.T) {
+	actor, _ := newTestActor(t)
+	user := &testUser{Name: "Alice", Email: "alice@example.com"}
+	createUsers(t, actor, user)
+
+	first, second := &testUser{}, &testUser{}
+	for _, dst := range []*testUser{first, second} {
+		if err := actor.GetByID(context.Background(), user.GetKeystoneID(), dst, keystone.WithProperties()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	first.Name = "First"
+	if err := actor.Mutate(context.Background(), first, keystone.IfUnchanged(), keystone.WithMutationComment("rename")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second.Name = "Second"
+	if err := actor.Mutate(context.Background(), second, keystone.IfUnchanged(), keystone.WithMutationComment("rename")); !errors.Is(err, keystone.ErrConflict) {
+		t.Errorf("expected conflict, got %v", err)
+	}
+}
+
+func TestServer_FindAndQueryIndex(t *testing.T) {
+	actor, _ := newTestActor(t)
+	createUsers(t, actor,
+		&testUser{Name: "Alice", Email: "alice@example.com", Age: 30},
+		&testUser{Name: "Bob", Email: "bob@example.com", Age: 25},
+		&testUser{Name: "Carol", Email: "carol@example.com", Age: 41},
+	)
+
+	found, err := actor.Find(context.Background(), keystone.Type(testUser{}), keystone.WithProperties("name"), keystone.WhereGreaterThan("age", 26))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(found) != 2 {
+		t.Errorf("expected 2 users over 26, got %d", len(found))
+	}
+
+	users, err := keystone.Query[testUser](actor).Where(keystone.WhereIn("name", "Alice", "Bob")).Sort("age", false).All(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(users) != 2 || users[0].Name != "Bob" || users[1].Name != "Alice" {
+		t.Errorf("unexpected query results %+v", users)
+	}
+
+	var names []string
+	for entity, iterErr := range actor.IterateIndex(context.Background(), keystone.Type(testUser{}), []string{"name"}, keystone.SortBy("age", true), keystone.Limit(1, 1)) {
+		if iterErr != nil {
+			t.Fatalf("unexpected error: %v", iterErr)
+		}
+		names = append(names, entity.GetProperties()[0].GetValue().GetText())
+	}
+	if len(names) != 3 || names[0] != "Carol" || names[2] != "Bob" {
+		t.Errorf("unexpected iteration order %v", names)
+	}
+
+	other := actor.Connection().Actor("ws-2", "127.0.0.1", "user-1", "go-test")
+	if count, _ := keystone.Query[testUser](&other).Count(context.Background()); count != 0 {
+		t.Errorf("expected workspaces to be isolated, got %d", count)
+	}
+}
+
+func TestServer_AKV(t *testing.T) {
+	actor, _ := newTestActor(t)
+	ctx := context.Background()
+
+	if _, err := actor.AKVPut(ctx, keystone.AKV("greeting", "hello")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	values, err := actor.AKVGet(ctx, "greeting", "missing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values["greeting"].GetText() != "hello" || values["missing"] != nil {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	base := time.Unix(1700000000, 0)
+	for i := 0; i < 3; i++ {
+		_, err = actor.AKVTimePut(ctx, keystone.AKVTimeEntry{Key: "temp", Timestamp: base.Add(time.Duration(i) * time.Hour), Data: []byte{byte(i)}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	latest, err := actor.AKVTimeGetLatest(ctx, "temp", "")
+	if err != nil || latest == nil || latest.Data[0] != 2 {
+		t.Fatalf("unexpected latest %v %v", latest, err)
+	}
+	at, err := actor.AKVTimeGetAt(ctx, "temp", "", base.Add(90*time.Minute))
+	if err != nil || at == nil || at.Data[0] != 1 {
+		t.Fatalf("unexpected value at time %v %v", at, err)
+	}
+	ranged, err := actor.AKVTimeGet(ctx, keystone.AKVTimeQuery{Key: "temp", Mode: keystone.TimeQueryRange, RangeStart: base, RangeEnd: base.Add(time.Hour)})
+	if err != nil || len(ranged) != 2 {
+		t.Fatalf("unexpected range %v %v", ranged, err)
+	}
+
+	if _, err = actor.AKVTimeDel(ctx, "temp", "", keystone.TimeDeleteAll); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if latest, _ = actor.AKVTimeGetLatest(ctx, "temp", ""); latest != nil {
+		t.Errorf("expected timeline to be deleted, got %v", latest)
+	}
+}
+
+func TestServer_Enums(t *testing.T) {
+	actor, _ := newTestActor(t)
+	ctx := context.Background()
+
+	if err := actor.EnumPut(ctx, "colour", "red", "Red", "", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := actor.EnumPut(ctx, "colour", "blue", "Blue", "", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	list, err := actor.EnumList(ctx, "colour")
+	if err != nil || len(list) != 2 || list[0].GetKey() != "blue" {
+		t.Fatalf("unexpected enum list %v %v", list, err)
+	}
+	if err = actor.EnumDelete(ctx, "colour", "red"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = actor.EnumGet(ctx, "colour", "red"); err == nil {
+		t.Error("expected deleted enum to be missing")
+	}
+}
+
+func TestServer_Tasks(t *testing.T) {
+	actor, server := newTestActor(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, id := range []string{"task-1", "task-2"} {
+		if err := actor.TaskPush(ctx, "email", id, map[string]string{"id": id}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	attempts := map[string]int{}
+	done := make(chan error, 1)
+	go func() {
+		done <- actor.TaskStream(ctx, "email", func(task *proto.TaskResponse) error {
+			attempts[task.GetTaskId()]++
+			if task.GetTaskId() == "task-1" && attempts["task-1"] == 1 {
+				return errors.New("retry")
+			}
+			return nil
+		})
+	}()
+
+	for len(server.AckedTasks("email")) < 2 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for tasks to be acked")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	acked := server.AckedTasks("email")
+	if len(acked) != 2 || acked[0] != "task-2" || acked[1] != "task-1" {
+		t.Errorf("expected nacked task to be redelivered, got %v", acked)
+	}
+	if attempts["task-1"] != 2 || len(server.PendingTasks("email")) != 0 {
+		t.Errorf("unexpected attempts %v", attempts)
+	}
+}
+
+func TestServer_EventStream(t *testing.T) {
+	actor, server := newTestActor(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan *proto.EventStreamResponse, 1)
+	go func() {
+		_ = actor.EventStream(ctx, func(evt *proto.EventStreamResponse) error {
+			received <- evt
+			return nil
+		}, "stream", keystone.OwnKey("signed-up"))
+	}()
+
+	for server.EventSubscribers() == 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("event stream did not connect")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	user := &testUser{Name: "Alice", Email: "alice@example.com"}
+	user.AddEvent("ignored", nil)
+	user.AddEvent("signed-up", map[string]string{"plan": "pro"})
+	createUsers(t, actor, user)
+
+	select {
+	case evt := <-received:
+		if evt.GetEid() != user.GetKeystoneID().String() || evt.GetEvent().GetData()["plan"] != "pro" {
+			t.Errorf("unexpected event %v", evt)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for event")
+	}
+}
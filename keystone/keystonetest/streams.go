@@ -0,0 +1,244 @@
+package keystonetest
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"sync"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// taskBroker queues pushed tasks by task name, until they are acked by a task stream
+type taskBroker struct {
+	lock    sync.Mutex
+	pending map[string][]*proto.TaskResponse
+	acked   map[string][]string
+	notify  chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newTaskBroker() *taskBroker {
+	return &taskBroker{notify: make(chan struct{}), done: make(chan struct{})}
+}
+
+func (b *taskBroker) reset() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.pending = make(map[string][]*proto.TaskResponse)
+	b.acked = make(map[string][]string)
+}
+
+func (b *taskBroker) close() {
+	b.once.Do(func() { close(b.done) })
+}
+
+func (b *taskBroker) push(taskName string, task *proto.TaskResponse) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.pending[taskName] = append(b.pending[taskName], task)
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+func (b *taskBroker) ack(taskName, taskID string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.acked[taskName] = append(b.acked[taskName], taskID)
+}
+
+// next blocks until a task is available for taskName, the context is done, or the broker is closed
+func (b *taskBroker) next(ctx context.Context, taskName string) (*proto.TaskResponse, bool) {
+	for {
+		b.lock.Lock()
+		if queue := b.pending[taskName]; len(queue) > 0 {
+			b.pending[taskName] = queue[1:]
+			b.lock.Unlock()
+			return queue[0], true
+		}
+		notify := b.notify
+		b.lock.Unlock()
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return nil, false
+		case <-b.done:
+			return nil, false
+		}
+	}
+}
+
+// PendingTasks returns the IDs of tasks waiting to be delivered for taskName
+func (s *Server) PendingTasks(taskName string) []string {
+	s.tasks.lock.Lock()
+	defer s.tasks.lock.Unlock()
+	var ids []string
+	for _, task := range s.tasks.pending[taskName] {
+		ids = append(ids, task.GetTaskId())
+	}
+	return ids
+}
+
+// AckedTasks returns the IDs of tasks successfully acked for taskName, in the order they were acked
+func (s *Server) AckedTasks(taskName string) []string {
+	s.tasks.lock.Lock()
+	defer s.tasks.lock.Unlock()
+	return append([]string(nil), s.tasks.acked[taskName]...)
+}
+
+func (s *Server) PushTask(_ context.Context, req *proto.PushTaskRequest) (*proto.GenericResponse, error) {
+	if req.GetTaskName() == "" {
+		return &proto.GenericResponse{ErrorCode: http.StatusBadRequest, ErrorMessage: "task name is required"}, nil
+	}
+	taskID := req.GetTaskId()
+	if taskID == "" {
+		taskID = s.ids.New().String()
+	}
+	s.tasks.push(req.GetTaskName(), &proto.TaskResponse{
+		Ws:     req.GetAuthorization().GetWorkspaceId(),
+		TaskId: taskID,
+		Data:   maps.Clone(req.GetData()),
+	})
+	return &proto.GenericResponse{Success: true}, nil
+}
+
+// TaskStream delivers tasks one at a time, waiting for each to be acked.
+// Tasks which are not acked are returned to the back of the queue.
+func (s *Server) TaskStream(stream grpc.BidiStreamingServer[proto.TaskAckRequest, proto.TaskResponse]) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	names := md.Get("task_name")
+	if len(names) == 0 || names[0] == "" {
+		return status.Error(codes.InvalidArgument, "task_name metadata is required")
+	}
+	taskName := names[0]
+
+	for {
+		task, ok := s.tasks.next(stream.Context(), taskName)
+		if !ok {
+			return nil
+		}
+		if err := stream.Send(task); err != nil {
+			s.tasks.push(taskName, task)
+			return err
+		}
+		ack, err := stream.Recv()
+		if err != nil {
+			s.tasks.push(taskName, task)
+			return nil
+		}
+		if ack.GetAcked() {
+			s.tasks.ack(taskName, task.GetTaskId())
+		} else {
+			s.tasks.push(taskName, task)
+		}
+	}
+}
+
+// eventHub fans out published events to open event streams
+type eventHub struct {
+	lock        sync.Mutex
+	subscribers map[*eventSubscriber]struct{}
+	done        chan struct{}
+	once        sync.Once
+}
+
+type eventSubscriber struct {
+	request *proto.EventStreamRequest
+	events  chan *proto.EventStreamResponse
+	done    <-chan struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subscribers: make(map[*eventSubscriber]struct{}), done: make(chan struct{})}
+}
+
+func (h *eventHub) close() {
+	h.once.Do(func() { close(h.done) })
+}
+
+func (h *eventHub) subscribe(ctx context.Context, req *proto.EventStreamRequest) (*eventSubscriber, func()) {
+	sub := &eventSubscriber{request: req, events: make(chan *proto.EventStreamResponse, 100), done: ctx.Done()}
+	h.lock.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.lock.Unlock()
+	return sub, func() {
+		h.lock.Lock()
+		delete(h.subscribers, sub)
+		h.lock.Unlock()
+	}
+}
+
+func (h *eventHub) count() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return len(h.subscribers)
+}
+
+func (h *eventHub) publish(evt *proto.EventStreamResponse) {
+	h.lock.Lock()
+	var matched []*eventSubscriber
+	for sub := range h.subscribers {
+		if sub.matches(evt) {
+			matched = append(matched, sub)
+		}
+	}
+	h.lock.Unlock()
+
+	for _, sub := range matched {
+		select {
+		case sub.events <- clone(evt):
+		case <-sub.done:
+		case <-h.done:
+		}
+	}
+}
+
+func (sub *eventSubscriber) matches(evt *proto.EventStreamResponse) bool {
+	req := sub.request
+	if !req.GetAllWorkspaces() && req.GetAuthorization().GetWorkspaceId() != evt.GetWs() {
+		return false
+	}
+	if req.GetEid() != "" && req.GetEid() != evt.GetEid() {
+		return false
+	}
+	return keyMatches(req.GetEventType(), evt.GetEvent().GetType())
+}
+
+// PublishEvent sends an event to all matching event streams, without storing it against an entity
+func (s *Server) PublishEvent(workspaceID, entityID string, evt *proto.EntityEvent) {
+	evt = clone(evt)
+	if evt.GetTime() == nil {
+		evt.Time = timestamppb.Now()
+	}
+	s.events.publish(&proto.EventStreamResponse{Ws: workspaceID, Eid: entityID, Event: evt})
+}
+
+// EventSubscribers returns the number of open event streams
+func (s *Server) EventSubscribers() int {
+	return s.events.count()
+}
+
+func (s *Server) EventStream(req *proto.EventStreamRequest, stream grpc.ServerStreamingServer[proto.EventStreamResponse]) error {
+	sub, unsubscribe := s.events.subscribe(stream.Context(), req)
+	defer unsubscribe()
+
+	for {
+		select {
+		case evt := <-sub.events:
+			if err := stream.Send(evt); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-s.events.done:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,350 @@
+// Package keystonevet provides a go vet style analyzer for keystone entity struct tags.
+//
+// It reports the same tag issues as keystone.ValidateType at build time: unknown and conflicting tag options,
+// property names which collide once normalized, and field types keystone cannot store.
+// Types supported through reflectors registered at runtime cannot be seen by the analyzer.
+package keystonevet
+
+import (
+	"go/ast"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"github.com/keystonedb/sdk-go/keystone"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const keystonePackage = "github.com/keystonedb/sdk-go/keystone"
+
+// Analyzer checks the keystone struct tags of entity definitions
+var Analyzer = &analysis.Analyzer{
+	Name:     "keystonetag",
+	Doc:      "check keystone struct tags for unknown options, conflicts, property name collisions and unsupported field types",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	insp.Preorder([]ast.Node{(*ast.TypeSpec)(nil)}, func(n ast.Node) {
+		spec := n.(*ast.TypeSpec)
+		st, ok := spec.Type.(*ast.StructType)
+		if !ok {
+			return
+		}
+		typ, ok := pass.TypesInfo.TypeOf(spec.Type).(*types.Struct)
+		if !ok || !isEntity(typ) {
+			return
+		}
+
+		c := &checker{pass: pass, seen: map[string]string{}, visiting: map[*types.Struct]bool{}}
+		c.checkStruct(typ, st, "", "")
+	})
+	return nil, nil
+}
+
+// isEntity returns true if the struct has keystone tags, or embeds a keystone base entity
+func isEntity(st *types.Struct) bool {
+	for i := 0; i < st.NumFields(); i++ {
+		if _, ok := reflect.StructTag(st.Tag(i)).Lookup("keystone"); ok {
+			return true
+		}
+		if st.Field(i).Embedded() && isKeystoneType(st.Field(i).Type(), "BaseEntity", "BaseChildEntity") {
+			return true
+		}
+	}
+	return false
+}
+
+type checker struct {
+	pass     *analysis.Pass
+	seen     map[string]string      // property name to the field which declared it
+	visiting map[*types.Struct]bool // structs being checked, to detect recursive types
+}
+
+// checkStruct reports issues with the fields of st; syntax is nil for structs declared elsewhere,
+// which are only checked for collisions and unsupported types, as their tags are checked where declared
+func (c *checker) checkStruct(st *types.Struct, syntax *ast.StructType, fieldPrefix, propertyPrefix string) {
+	c.visiting[st] = true
+	defer delete(c.visiting, st)
+
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		tag := reflect.StructTag(st.Tag(i)).Get("keystone")
+		pos := field.Pos()
+		if syntax != nil {
+			if f := fieldSyntax(syntax, field.Name()); f != nil {
+				pos = f.Pos()
+			}
+		}
+
+		if field.Embedded() {
+			// promoted fields are mapped as if declared on the struct
+			if embedded, ok := field.Type().Underlying().(*types.Struct); ok && !isKeystoneType(field.Type()) && !c.visiting[embedded] {
+				c.checkStruct(embedded, nil, fieldPrefix, propertyPrefix)
+			}
+			continue
+		}
+		if !field.Exported() {
+			continue
+		}
+
+		fieldPath := fieldPrefix + field.Name()
+		if syntax != nil {
+			for _, issue := range keystone.ValidateTag(field.Name(), tag) {
+				c.pass.Reportf(pos, "keystone: %s", issue.Message)
+			}
+		}
+
+		name := propertyName(field.Name(), tag)
+		if name == "" || strings.HasPrefix(name, "_") {
+			continue
+		}
+		if propertyPrefix != "" {
+			name = propertyPrefix + "." + name
+		}
+		if existing, ok := c.seen[name]; ok {
+			c.pass.Reportf(pos, "keystone: property %s of %s collides with %s", name, fieldPath, existing)
+		} else {
+			c.seen[name] = fieldPath
+		}
+
+		if hasTagOption(tag, "json") {
+			if !jsonEncodable(field.Type(), map[types.Type]bool{}) {
+				c.pass.Reportf(pos, "keystone: %s cannot be encoded as JSON, %s will not be stored",
+					types.TypeString(field.Type(), types.RelativeTo(c.pass.Pkg)), fieldPath)
+			}
+			continue
+		}
+
+		switch nested, supported := storable(field.Type()); {
+		case nested != nil && c.visiting[nested]:
+			c.pass.Reportf(pos, "keystone: recursive type %s, %s cannot be stored as nested properties",
+				types.TypeString(field.Type(), types.RelativeTo(c.pass.Pkg)), fieldPath)
+		case nested != nil:
+			c.checkStruct(nested, nestedSyntax(syntax, field.Name()), fieldPath+".", name)
+		case !supported && jsonCandidate(field.Type()):
+			c.pass.Reportf(pos, "keystone: unsupported field type %s, %s will not be stored unless tagged with the json option",
+				types.TypeString(field.Type(), types.RelativeTo(c.pass.Pkg)), fieldPath)
+		case !supported:
+			c.pass.Reportf(pos, "keystone: unsupported field type %s, %s will not be stored",
+				types.TypeString(field.Type(), types.RelativeTo(c.pass.Pkg)), fieldPath)
+		}
+	}
+}
+
+// propertyName returns the keystone property name for a field, empty when the field is ignored
+func propertyName(fieldName, tag string) string {
+	name := strings.TrimSpace(strings.Split(tag, ",")[0])
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return keystone.PropertyName(fieldName)
+	}
+	return strings.ToLower(name)
+}
+
+// storable returns whether keystone can store the type, or the struct to map as prefixed properties
+func storable(t types.Type) (*types.Struct, bool) {
+	for {
+		ptr, ok := t.Underlying().(*types.Pointer)
+		if !ok {
+			break
+		}
+		t = ptr.Elem()
+	}
+
+	if isNamed(t, "time", "Time") || isNamed(t, "google.golang.org/protobuf/types/known/timestamppb", "Timestamp") {
+		return nil, true
+	}
+	if hasMethod(t, "MarshalValue") {
+		return nil, true
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return nil, keystone.StorableKind(reflectKind(u.Kind()))
+	case *types.Map:
+		key, ok := u.Key().Underlying().(*types.Basic)
+		if ok && key.Kind() == types.String {
+			if elem, ok := u.Elem().Underlying().(*types.Basic); ok && keystone.StorableMapKind(reflectKind(elem.Kind())) {
+				return nil, true
+			}
+			if bytes, ok := u.Elem().Underlying().(*types.Slice); ok && isBasic(bytes.Elem(), types.Uint8) {
+				return nil, true
+			}
+		}
+	case *types.Slice:
+		if hasMethod(u.Elem(), "ChildID") && hasMethod(u.Elem(), "SetChildID") {
+			// nested children are stored separately to the entity properties
+			return nil, true
+		}
+		if elem, ok := u.Elem().Underlying().(*types.Basic); ok && keystone.StorableSliceKind(reflectKind(elem.Kind())) {
+			return nil, true
+		}
+		if isNamed(u.Elem(), "time", "Time") {
+			return nil, true
+		}
+	case *types.Struct:
+		return u, true
+	}
+	return nil, false
+}
+
+// basicKinds maps the basic types to the reflect kinds used by keystone to choose a reflector
+var basicKinds = map[types.BasicKind]reflect.Kind{
+	types.Bool:    reflect.Bool,
+	types.Int:     reflect.Int,
+	types.Int8:    reflect.Int8,
+	types.Int16:   reflect.Int16,
+	types.Int32:   reflect.Int32,
+	types.Int64:   reflect.Int64,
+	types.Uint:    reflect.Uint,
+	types.Uint8:   reflect.Uint8,
+	types.Uint16:  reflect.Uint16,
+	types.Uint32:  reflect.Uint32,
+	types.Uint64:  reflect.Uint64,
+	types.Uintptr: reflect.Uintptr,
+	types.Float32: reflect.Float32,
+	types.Float64: reflect.Float64,
+	types.String:  reflect.String,
+}
+
+// reflectKind returns the reflect kind of a basic type, reflect.Invalid for kinds keystone never stores
+func reflectKind(kind types.BasicKind) reflect.Kind {
+	if k, ok := basicKinds[kind]; ok {
+		return k
+	}
+	return reflect.Invalid
+}
+
+// jsonEncodable returns true if values of t can be round-tripped through encoding/json
+func jsonEncodable(t types.Type, seen map[types.Type]bool) bool {
+	if seen[t] {
+		return true
+	}
+	seen[t] = true
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch u.Kind() {
+		case types.Complex64, types.Complex128, types.UnsafePointer:
+			return false
+		}
+	case *types.Chan, *types.Signature:
+		return false
+	case *types.Pointer:
+		return jsonEncodable(u.Elem(), seen)
+	case *types.Slice:
+		return jsonEncodable(u.Elem(), seen)
+	case *types.Array:
+		return jsonEncodable(u.Elem(), seen)
+	case *types.Map:
+		key, ok := u.Key().Underlying().(*types.Basic)
+		if (!ok || key.Info()&(types.IsString|types.IsInteger) == 0) && !hasMethod(u.Key(), "MarshalText") {
+			return false
+		}
+		return jsonEncodable(u.Elem(), seen)
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			field := u.Field(i)
+			if field.Exported() && !field.Embedded() && reflect.StructTag(u.Tag(i)).Get("json") != "-" &&
+				!jsonEncodable(field.Type(), seen) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// jsonCandidate returns true for slices, maps and arrays which could be stored with the json option
+func jsonCandidate(t types.Type) bool {
+	for {
+		ptr, ok := t.Underlying().(*types.Pointer)
+		if !ok {
+			break
+		}
+		t = ptr.Elem()
+	}
+	switch t.Underlying().(type) {
+	case *types.Slice, *types.Map, *types.Array:
+		return jsonEncodable(t, map[types.Type]bool{})
+	}
+	return false
+}
+
+func isBasic(t types.Type, kind types.BasicKind) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Kind() == kind
+}
+
+// hasTagOption returns true if the keystone tag includes the option
+func hasTagOption(tag, option string) bool {
+	for i, part := range strings.Split(tag, ",") {
+		if i > 0 && strings.TrimSpace(part) == option {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMethod(t types.Type, name string) bool {
+	if _, ok := t.Underlying().(*types.Pointer); !ok {
+		t = types.NewPointer(t)
+	}
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name)
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+func isNamed(t types.Type, pkg, name string) bool {
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	return named.Obj().Pkg().Path() == pkg && named.Obj().Name() == name
+}
+
+// isKeystoneType returns true if t is declared by the keystone package, optionally with one of the given names
+func isKeystoneType(t types.Type, names ...string) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if len(names) == 0 {
+		named, ok := t.(*types.Named)
+		return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == keystonePackage
+	}
+	for _, name := range names {
+		if isNamed(t, keystonePackage, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldSyntax(st *ast.StructType, name string) *ast.Field {
+	for _, f := range st.Fields.List {
+		for _, n := range f.Names {
+			if n.Name == name {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// nestedSyntax returns the syntax of an anonymous struct field, so its tags are checked with the parent
+func nestedSyntax(st *ast.StructType, name string) *ast.StructType {
+	if st == nil {
+		return nil
+	}
+	if f := fieldSyntax(st, name); f != nil {
+		if nested, ok := f.Type.(*ast.StructType); ok {
+			return nested
+		}
+	}
+	return nil
+}
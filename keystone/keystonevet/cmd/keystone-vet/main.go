@@ -0,0 +1,16 @@
+// Command keystone-vet checks keystone entity struct tags.
+//
+// It is a separate module, so the analysis dependencies are not required by the sdk.
+// It can be run directly, or through go vet:
+//
+//	go vet -vettool=$(which keystone-vet) ./...
+package main
+
+import (
+	"github.com/keystonedb/sdk-go/keystone/keystonevet"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(keystonevet.Analyzer)
+}
@@ -0,0 +1,29 @@
+module github.com/keystonedb/sdk-go/keystone/keystonevet
+
+go 1.24.0
+
+// the analyzer requires the sdk release it checks, go.work builds against the local sdk during development
+require (
+	github.com/keystonedb/sdk-go v0.0.0-20261018010951-1d6e8a4b0384
+	golang.org/x/tools v0.38.0
+)
+
+require (
+	github.com/alexsergivan/transliterator v1.0.1 // indirect
+	github.com/gorilla/schema v1.4.1 // indirect
+	github.com/kubex/k4id v0.0.0-20250723085823-229fb2f370fd // indirect
+	github.com/packaged/environment v1.1.0 // indirect
+	github.com/packaged/helpers-go v0.0.0-20251202110759-284b6f76f045 // indirect
+	github.com/packaged/logger/v3 v3.3.0 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	go.uber.org/zap v1.27.1 // indirect
+	golang.org/x/crypto v0.45.0 // indirect
+	golang.org/x/mod v0.29.0 // indirect
+	golang.org/x/net v0.47.0 // indirect
+	golang.org/x/sync v0.18.0 // indirect
+	golang.org/x/sys v0.38.0 // indirect
+	golang.org/x/text v0.31.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20251202230838-ff82c1b0f217 // indirect
+	google.golang.org/grpc v1.77.0 // indirect
+	google.golang.org/protobuf v1.36.10 // indirect
+)
@@ -0,0 +1,58 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/keystonedb/sdk-go/keystone"
+)
+
+type Address struct {
+	Line1 string
+	Zip   string `keystone:"line1"` // want `keystone: property line1 of Zip collides with Line1` `keystone: property address.line1 of Address.Zip collides with Address.Line1`
+}
+
+type Child struct{}
+
+func (c *Child) ChildID() string      { return "" }
+func (c *Child) SetChildID(id string) {}
+
+type User struct {
+	keystone.BaseEntity
+	Email    string `keystone:",uniqe"`            // want `keystone: unknown tag option "uniqe"`
+	Password string `keystone:",verify,immutable"` // want `keystone: verify and immutable conflict`
+	UserID   string
+	UserId   string // want `keystone: property user_id of UserId collides with UserID`
+	Name     string `keystone:"Name"` // want `keystone: property name "Name" is stored as "name"`
+	Balance  keystone.Amount
+	Joined   time.Time
+	Address  Address
+	Children []Child
+	Updates  chan string // want `keystone: unsupported field type chan string, Updates will not be stored`
+	Hooks    []func()    // want `keystone: unsupported field type \[\]func\(\), Hooks will not be stored$`
+	Ignored  chan string `keystone:"-"`
+	internal chan string
+
+	Addresses []Address          // want `keystone: unsupported field type \[\]Address, Addresses will not be stored unless tagged with the json option`
+	Labels    map[string]Address // want `keystone: unsupported field type map\[string\]Address, Labels will not be stored unless tagged with the json option`
+	Lookup    map[int]string     // want `keystone: unsupported field type map\[int\]string, Lookup will not be stored unless tagged with the json option`
+	Checksum  [16]byte           // want `keystone: unsupported field type \[16\]byte, Checksum will not be stored unless tagged with the json option`
+	Offices   []Address          `keystone:",json"`
+	Files     map[string][]byte
+	Scores    []float64
+	Flags     []bool
+	Visits    []time.Time
+	Home      Address             `keystone:",json"`
+	Secret    Address             `keystone:",verify,json"` // want `keystone: verify and json conflict`
+	Channels  map[string]chan int `keystone:",json"`        // want `keystone: map\[string\]chan int cannot be encoded as JSON, Channels will not be stored`
+}
+
+// NotAnEntity has no keystone tags, so is not checked
+type NotAnEntity struct {
+	Updates chan string
+}
+
+// Node refers to itself, so cannot be mapped as nested properties
+type Node struct {
+	Name string `keystone:",indexed"`
+	Next *Node  // want `keystone: recursive type \*Node, Next cannot be stored as nested properties`
+}
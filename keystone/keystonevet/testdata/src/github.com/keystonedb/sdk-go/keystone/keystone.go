@@ -0,0 +1,12 @@
+// Package keystone is a stub of the keystone types the analyzer recognises
+package keystone
+
+type BaseEntity struct{ _entityID string }
+
+type BaseChildEntity struct{ BaseEntity }
+
+type Value struct{}
+
+type Amount struct{ units int64 }
+
+func (a *Amount) MarshalValue() (*Value, error) { return nil, nil }
@@ -52,7 +52,7 @@ func Marshal(v interface{}) (map[Property]*proto.Value, error) {
 		}
 
 		currentVal := val.FieldByIndex(field.Index)
-		ref := GetReflector(field.Type, currentVal)
+		ref := fieldReflector(field, currentVal)
 		if ref != nil {
 			if vRef, vRefOk := ref.(valueMarshalReflector); vRefOk && vRef.IsZero() {
 				continue
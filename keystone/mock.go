@@ -46,6 +46,11 @@ type MockServer struct {
 	AKVGetFunc           func(context.Context, *proto.AKVGetRequest) (*proto.AKVGetResponse, error)
 	AKVPutFunc           func(context.Context, *proto.AKVPutRequest) (*proto.GenericResponse, error)
 	AKVDelFunc           func(context.Context, *proto.AKVDelRequest) (*proto.GenericResponse, error)
+	AKVTimePutFunc       func(context.Context, *proto.AKVTimePutRequest) (*proto.GenericResponse, error)
+	AKVTimeGetFunc       func(context.Context, *proto.AKVTimeGetRequest) (*proto.AKVTimeGetResponse, error)
+	AKVTimeDelFunc       func(context.Context, *proto.AKVTimeDelRequest) (*proto.GenericResponse, error)
+	EventStreamFunc      func(*proto.EventStreamRequest, grpc.ServerStreamingServer[proto.EventStreamResponse]) error
+	TaskStreamFunc       func(grpc.BidiStreamingServer[proto.TaskAckRequest, proto.TaskResponse]) error
 	PushTaskFunc         func(context.Context, *proto.PushTaskRequest) (*proto.GenericResponse, error)
 	EnumPutFunc          func(context.Context, *proto.EnumPutRequest) (*proto.GenericResponse, error)
 	EnumGetFunc          func(context.Context, *proto.EnumGetRequest) (*proto.EnumGetResponse, error)
@@ -292,6 +297,41 @@ func (m *MockServer) AKVDel(ctx context.Context, req *proto.AKVDelRequest) (*pro
 	return m.AKVDelFunc(ctx, req)
 }
 
+func (m *MockServer) AKVTimePut(ctx context.Context, req *proto.AKVTimePutRequest) (*proto.GenericResponse, error) {
+	if m.AKVTimePutFunc == nil {
+		return m.UnimplementedKeystoneServer.AKVTimePut(ctx, req)
+	}
+	return m.AKVTimePutFunc(ctx, req)
+}
+
+func (m *MockServer) AKVTimeGet(ctx context.Context, req *proto.AKVTimeGetRequest) (*proto.AKVTimeGetResponse, error) {
+	if m.AKVTimeGetFunc == nil {
+		return m.UnimplementedKeystoneServer.AKVTimeGet(ctx, req)
+	}
+	return m.AKVTimeGetFunc(ctx, req)
+}
+
+func (m *MockServer) AKVTimeDel(ctx context.Context, req *proto.AKVTimeDelRequest) (*proto.GenericResponse, error) {
+	if m.AKVTimeDelFunc == nil {
+		return m.UnimplementedKeystoneServer.AKVTimeDel(ctx, req)
+	}
+	return m.AKVTimeDelFunc(ctx, req)
+}
+
+func (m *MockServer) EventStream(req *proto.EventStreamRequest, stream grpc.ServerStreamingServer[proto.EventStreamResponse]) error {
+	if m.EventStreamFunc == nil {
+		return m.UnimplementedKeystoneServer.EventStream(req, stream)
+	}
+	return m.EventStreamFunc(req, stream)
+}
+
+func (m *MockServer) TaskStream(stream grpc.BidiStreamingServer[proto.TaskAckRequest, proto.TaskResponse]) error {
+	if m.TaskStreamFunc == nil {
+		return m.UnimplementedKeystoneServer.TaskStream(stream)
+	}
+	return m.TaskStreamFunc(stream)
+}
+
 func (m *MockServer) PushTask(ctx context.Context, req *proto.PushTaskRequest) (*proto.GenericResponse, error) {
 	if m.PushTaskFunc == nil {
 		return m.UnimplementedKeystoneServer.PushTask(ctx, req)
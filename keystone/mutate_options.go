@@ -1,7 +1,9 @@
 package keystone
 
 import (
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/keystonedb/sdk-go/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -103,6 +105,48 @@ func MatchExisting(options ...FindOption) MutateOption {
 	return matchExisting{findOptions: options}
 }
 
+var ErrUnknownLastUpdate = errors.New("entity last update is unknown, retrieve the entity before using IfUnchanged")
+
+type lastUpdateProvider interface {
+	LastUpdated() time.Time
+}
+
+// IfUnchanged only applies the mutation if the entity has not been updated since it was retrieved.
+// A failed check returns an error matching ErrConflict.
+func IfUnchanged() MutateOption {
+	return ifUnchanged{}
+}
+
+// IfUnchangedSince only applies the mutation if the entity was last updated at lastUpdated.
+// A failed check returns an error matching ErrConflict.
+func IfUnchangedSince(lastUpdated time.Time) MutateOption {
+	return ifUnchanged{lastUpdated: lastUpdated, fixed: true}
+}
+
+type ifUnchanged struct {
+	lastUpdated time.Time
+	fixed       bool
+}
+
+func (m ifUnchanged) apply(mutate *proto.MutateRequest) {
+	if m.lastUpdated.IsZero() {
+		return
+	}
+	mutate.Where = append(mutate.Where, &proto.PropertyFilter{
+		Property: PropertyLastUpdate,
+		Operator: proto.Operator_Equal,
+		Values:   []*proto.Value{{Time: timestamppb.New(m.lastUpdated)}},
+	})
+}
+
+// forSource takes the last update from the entity being mutated, unless a time was given
+func (m ifUnchanged) forSource(src interface{}) ifUnchanged {
+	if provider, ok := src.(lastUpdateProvider); ok && !m.fixed {
+		m.lastUpdated = provider.LastUpdated()
+	}
+	return m
+}
+
 type prepareObjects struct {
 	objects []*EntityObject
 }
@@ -135,6 +179,7 @@ func (m prepareObjects) ObserveMutation(response *proto.MutateResponse) {
 			if obj.GetPath() == respObj.GetPath() && respObj.GetUrl() != "" {
 				obj.uploadURL = respObj.GetUrl()
 				obj.uploadHeaders = respObj.GetUploadHeaders()
+				obj.entityID = response.GetEntityId()
 				break
 			}
 		}
@@ -0,0 +1,334 @@
+package keystone
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UploadPart is a stored part of a multipart upload
+type UploadPart struct {
+	Number int    `json:"number"` // 1 based part number
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	ETag   string `json:"etag,omitempty"`
+}
+
+// MultipartUploadState is the progress of a multipart upload, persisted so an interrupted upload can be resumed
+type MultipartUploadState struct {
+	EntityID string       `json:"entity_id"`
+	Path     string       `json:"path"`
+	UploadID string       `json:"upload_id"`
+	Size     int64        `json:"size"`
+	PartSize int64        `json:"part_size"`
+	Parts    []UploadPart `json:"parts"` // parts which have been stored
+}
+
+// MultipartSigner starts multipart uploads, signs a URL for each part, and completes the upload once every part is
+// stored. AbortUpload discards the stored parts of an upload which will not be resumed.
+//
+// Signed part URLs are not carried by the mutation response: MutateResponse.SignedObjectUrls holds a single upload
+// URL per object, and the API has no calls to start, complete or abort a multipart upload. Until it does, the signer
+// is implemented by the caller, usually with the object store SDK and its own credentials.
+type MultipartSigner interface {
+	StartUpload(ctx context.Context, obj *EntityObject) (uploadID string, err error)
+	PartURL(ctx context.Context, obj *EntityObject, uploadID string, part int) (url string, headers map[string]string, err error)
+	CompleteUpload(ctx context.Context, obj *EntityObject, uploadID string, parts []UploadPart) error
+	AbortUpload(ctx context.Context, obj *EntityObject, uploadID string) error
+}
+
+// MultipartStateStore persists multipart upload state between runs, keyed by entity ID and object path
+type MultipartStateStore interface {
+	LoadUpload(ctx context.Context, entityID, path string) (*MultipartUploadState, error)
+	SaveUpload(ctx context.Context, state *MultipartUploadState) error
+	DeleteUpload(ctx context.Context, entityID, path string) error
+}
+
+// fileStateStore stores multipart upload state as JSON files in a directory
+type fileStateStore struct {
+	dir string
+}
+
+// FileUploadStateStore stores multipart upload state as JSON files in dir
+func FileUploadStateStore(dir string) MultipartStateStore {
+	return fileStateStore{dir: dir}
+}
+
+func (s fileStateStore) file(entityID, path string) string {
+	sum := sha256.Sum256([]byte(entityID + "/" + path))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+func (s fileStateStore) LoadUpload(_ context.Context, entityID, path string) (*MultipartUploadState, error) {
+	data, err := os.ReadFile(s.file(entityID, path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	state := &MultipartUploadState{}
+	if err = json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	if state.EntityID != entityID || state.Path != path {
+		return nil, nil
+	}
+	return state, nil
+}
+
+func (s fileStateStore) SaveUpload(_ context.Context, state *MultipartUploadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	// written to a temporary file first, so a crash mid-write leaves the previous state
+	file := s.file(state.EntityID, state.Path)
+	if err = os.WriteFile(file+".tmp", data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+func (s fileStateStore) DeleteUpload(_ context.Context, entityID, path string) error {
+	err := os.Remove(s.file(entityID, path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+type multipartOptions struct {
+	partSize    int64
+	concurrency int
+	store       MultipartStateStore
+	transfer    []TransferOption
+}
+
+// MultipartOption configures a multipart upload
+type MultipartOption func(*multipartOptions)
+
+// WithPartSize sets the size of each part, defaulting to 16MiB
+func WithPartSize(size int64) MultipartOption {
+	return func(o *multipartOptions) {
+		if size > 0 {
+			o.partSize = size
+		}
+	}
+}
+
+// WithPartConcurrency sets how many parts are uploaded at once, defaulting to 4
+func WithPartConcurrency(n int) MultipartOption {
+	return func(o *multipartOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithUploadStateStore persists the upload state, so an interrupted upload resumes from the parts already stored
+func WithUploadStateStore(store MultipartStateStore) MultipartOption {
+	return func(o *multipartOptions) {
+		o.store = store
+	}
+}
+
+// WithPartTransfer applies transfer options to each part upload, progress is reported across the whole object,
+// with one callback at a time while parts upload in parallel
+func WithPartTransfer(opts ...TransferOption) MultipartOption {
+	return func(o *multipartOptions) {
+		o.transfer = append(o.transfer, opts...)
+	}
+}
+
+// UploadMultipart uploads size bytes of content in parts, uploading parts in parallel and retrying each part on
+// failure. With a state store, a failed or interrupted upload is resumed by calling UploadMultipart again, the state
+// is keyed by the entity ID of the upload, so the object must be prepared with PrepareUploads.
+// Without a state store, a failed upload is aborted so its stored parts are not left behind.
+func (e *EntityObject) UploadMultipart(ctx context.Context, content io.ReaderAt, size int64, signer MultipartSigner, opts ...MultipartOption) error {
+	if signer == nil {
+		return errors.New("multipart signer is nil")
+	}
+	options := multipartOptions{partSize: 16 << 20, concurrency: 4}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.store != nil && e.GetEntityID() == "" {
+		return errors.New("multipart upload state requires the entity ID; prepare the upload with PrepareUploads first")
+	}
+
+	state, err := e.multipartState(ctx, signer, options, size)
+	if err != nil {
+		return err
+	}
+
+	err = e.uploadParts(ctx, content, size, signer, options, state)
+	if err != nil && options.store == nil {
+		// the upload cannot be resumed, so the stored parts are discarded
+		abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if abortErr := signer.AbortUpload(abortCtx, e, state.UploadID); abortErr != nil {
+			return errors.Join(err, abortErr)
+		}
+	}
+	return err
+}
+
+// uploadParts uploads the parts missing from state, then completes the upload
+func (e *EntityObject) uploadParts(ctx context.Context, content io.ReaderAt, size int64, signer MultipartSigner,
+	options multipartOptions, state *MultipartUploadState) error {
+	transfer := newTransferOptions(options.transfer)
+
+	stored := map[int]bool{}
+	transferred := int64(0)
+	for _, part := range state.Parts {
+		stored[part.Number] = true
+		transferred += part.Size
+	}
+	var pending []UploadPart
+	partCount := max(1, int((size+state.PartSize-1)/state.PartSize))
+	for number := 1; number <= partCount; number++ {
+		if !stored[number] {
+			offset := int64(number-1) * state.PartSize
+			pending = append(pending, UploadPart{Number: number, Offset: offset, Size: min(state.PartSize, size-offset)})
+		}
+	}
+
+	partCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lock := sync.Mutex{}
+	var uploadErr error
+	parts := make(chan UploadPart)
+	wg := sync.WaitGroup{}
+	for i := 0; i < options.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range parts {
+				partTransfer := transfer
+				partTransfer.contentLength = part.Size
+				if transfer.progress != nil {
+					sent := int64(0)
+					partTransfer.progress = func(n, _ int64) {
+						// reported under the lock, so callbacks are not made concurrently
+						lock.Lock()
+						defer lock.Unlock()
+						transferred += n - sent
+						sent = n
+						transfer.progress(transferred, size)
+					}
+				}
+
+				etag, partErr := e.uploadPart(partCtx, signer, state.UploadID, part, content, partTransfer)
+
+				lock.Lock()
+				if partErr != nil {
+					if uploadErr == nil {
+						uploadErr = partErr
+						cancel()
+					}
+				} else {
+					part.ETag = etag
+					state.Parts = append(state.Parts, part)
+					if options.store != nil {
+						// saved under the lock, so saves are ordered
+						if saveErr := options.store.SaveUpload(ctx, state); saveErr != nil && uploadErr == nil {
+							uploadErr = saveErr
+							cancel()
+						}
+					}
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for _, part := range pending {
+		select {
+		case parts <- part:
+		case <-partCtx.Done():
+			break dispatch
+		}
+	}
+	close(parts)
+	wg.Wait()
+
+	if uploadErr != nil {
+		return uploadErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	sort.Slice(state.Parts, func(i, j int) bool { return state.Parts[i].Number < state.Parts[j].Number })
+	if err := signer.CompleteUpload(ctx, e, state.UploadID, state.Parts); err != nil {
+		return err
+	}
+	if options.store != nil {
+		return options.store.DeleteUpload(ctx, e.GetEntityID(), e.GetPath())
+	}
+	return nil
+}
+
+// multipartState returns the stored state of the upload, or starts a new upload when there is none to resume
+func (e *EntityObject) multipartState(ctx context.Context, signer MultipartSigner, options multipartOptions, size int64) (*MultipartUploadState, error) {
+	if options.store != nil {
+		state, err := options.store.LoadUpload(ctx, e.GetEntityID(), e.GetPath())
+		if err != nil {
+			return nil, err
+		}
+		// the stored parts can only be reused for the same content layout
+		if state != nil && state.Size == size && state.PartSize == options.partSize && state.UploadID != "" {
+			return state, nil
+		}
+		if state != nil && state.UploadID != "" {
+			if err = signer.AbortUpload(ctx, e, state.UploadID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	uploadID, err := signer.StartUpload(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	state := &MultipartUploadState{
+		EntityID: e.GetEntityID(), Path: e.GetPath(), UploadID: uploadID, Size: size, PartSize: options.partSize,
+	}
+	if options.store != nil {
+		if err = options.store.SaveUpload(ctx, state); err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+// uploadPart uploads a single part, returning the ETag of the stored part
+func (e *EntityObject) uploadPart(ctx context.Context, signer MultipartSigner, uploadID string, part UploadPart,
+	content io.ReaderAt, options transferOptions) (string, error) {
+	url, headers, err := signer.PartURL(ctx, e, uploadID, part.Number)
+	if err != nil {
+		return "", err
+	}
+	respHeader, err := putContent(ctx, url, headers, options, http.Header{}, func() (io.ReadCloser, int64, error) {
+		return io.NopCloser(io.NewSectionReader(content, part.Offset, part.Size)), part.Size, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.Trim(respHeader.Get("ETag"), `"`), nil
+}
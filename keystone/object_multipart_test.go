@@ -0,0 +1,202 @@
+package keystone
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+// testMultipartStore stores parts uploaded to signed part URLs, and assembles them on completion
+type testMultipartStore struct {
+	lock      sync.Mutex
+	server    *httptest.Server
+	parts     map[int][]byte
+	attempts  map[int]int
+	failures  map[int]int // part number to the status returned on its next upload
+	starts    int
+	aborted   []string
+	completed []byte
+}
+
+func newTestMultipartStore(t *testing.T) *testMultipartStore {
+	s := &testMultipartStore{parts: map[int][]byte{}, attempts: map[int]int{}, failures: map[int]int{}}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		number, _ := strconv.Atoi(r.URL.Query().Get("part"))
+		body, _ := io.ReadAll(r.Body)
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.attempts[number]++
+		if status, ok := s.failures[number]; ok {
+			delete(s.failures, number)
+			w.WriteHeader(status)
+			return
+		}
+		if r.Header.Get("X-Upload-Id") != "upload-1" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		s.parts[number] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, number))
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *testMultipartStore) StartUpload(_ context.Context, obj *EntityObject) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.starts++
+	return "upload-1", nil
+}
+
+func (s *testMultipartStore) PartURL(_ context.Context, obj *EntityObject, uploadID string, part int) (string, map[string]string, error) {
+	return s.server.URL + "/" + obj.GetPath() + "?part=" + strconv.Itoa(part), map[string]string{"X-Upload-Id": uploadID}, nil
+}
+
+func (s *testMultipartStore) CompleteUpload(_ context.Context, obj *EntityObject, uploadID string, parts []UploadPart) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var assembled []byte
+	for i, part := range parts {
+		if part.Number != i+1 || part.ETag != fmt.Sprintf("etag-%d", part.Number) {
+			return errors.New("parts out of order or missing an etag")
+		}
+		assembled = append(assembled, s.parts[part.Number]...)
+	}
+	s.completed = assembled
+	return nil
+}
+
+func (s *testMultipartStore) AbortUpload(_ context.Context, obj *EntityObject, uploadID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.aborted = append(s.aborted, uploadID)
+	return nil
+}
+
+func TestEntityObject_UploadMultipart(t *testing.T) {
+	store := newTestMultipartStore(t)
+	store.failures[2] = http.StatusServiceUnavailable
+
+	content := []byte(strings.Repeat("abcdefghij", 100))
+	obj := NewUpload("exports/large.csv", proto.ObjectType_Standard)
+
+	// progress callbacks are serialised, so the callback needs no lock
+	var progress, total int64
+	var calling atomic.Int32
+	concurrent := false
+	err := obj.UploadMultipart(context.Background(), bytes.NewReader(content), int64(len(content)), store,
+		WithPartSize(300), WithPartConcurrency(3),
+		WithPartTransfer(WithHTTPClient(store.server.Client()), WithTransferRetries(2, time.Millisecond),
+			WithProgress(func(transferred, size int64) {
+				if calling.Add(1) > 1 {
+					concurrent = true
+				}
+				defer calling.Add(-1)
+				time.Sleep(time.Millisecond)
+				progress, total = max(progress, transferred), size
+			})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(store.completed, content) {
+		t.Errorf("expected the parts to assemble the content, got %d bytes", len(store.completed))
+	}
+	if len(store.parts) != 4 || len(store.parts[4]) != 100 {
+		t.Errorf("expected 4 parts with a short final part, got %d parts", len(store.parts))
+	}
+	if store.attempts[2] != 2 {
+		t.Errorf("expected the failed part to be retried, got %d attempts", store.attempts[2])
+	}
+	if progress != int64(len(content)) || total != int64(len(content)) {
+		t.Errorf("expected progress across the object, got %d of %d", progress, total)
+	}
+	if concurrent {
+		t.Errorf("expected progress callbacks not to be made concurrently")
+	}
+}
+
+func TestEntityObject_UploadMultipartResume(t *testing.T) {
+	store := newTestMultipartStore(t)
+	state := FileUploadStateStore(t.TempDir())
+	content := []byte(strings.Repeat("0123456789", 100))
+	obj := NewUpload("exports/resume.bin", proto.ObjectType_Standard)
+	obj.entityID = "entity-1"
+
+	// the third part is rejected, so the first run fails part way
+	store.failures[3] = http.StatusForbidden
+	opts := []MultipartOption{
+		WithPartSize(250), WithPartConcurrency(1), WithUploadStateStore(state),
+		WithPartTransfer(WithHTTPClient(store.server.Client()), WithTransferRetries(0, 0)),
+	}
+	err := obj.UploadMultipart(context.Background(), bytes.NewReader(content), int64(len(content)), store, opts...)
+	var transferErr *TransferError
+	if !errors.As(err, &transferErr) || transferErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected the rejected part to fail the upload, got %v", err)
+	}
+
+	saved, err := state.LoadUpload(context.Background(), obj.GetEntityID(), obj.GetPath())
+	if err != nil || saved == nil || saved.UploadID != "upload-1" || len(saved.Parts) != 2 {
+		t.Fatalf("expected the stored parts to be saved, got %+v %v", saved, err)
+	}
+	if other, _ := state.LoadUpload(context.Background(), "entity-2", obj.GetPath()); other != nil {
+		t.Errorf("expected the state of another entity's object at the same path to be separate")
+	}
+	if len(store.aborted) != 0 {
+		t.Errorf("expected a resumable upload not to be aborted, got %v", store.aborted)
+	}
+
+	if err = obj.UploadMultipart(context.Background(), bytes.NewReader(content), int64(len(content)), store, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if store.starts != 1 {
+		t.Errorf("expected the upload to be resumed, got %d starts", store.starts)
+	}
+	if store.attempts[1] != 1 || store.attempts[2] != 1 {
+		t.Errorf("expected stored parts not to be uploaded again, got %v", store.attempts)
+	}
+	if !bytes.Equal(store.completed, content) {
+		t.Errorf("expected the resumed upload to assemble the content")
+	}
+	if saved, _ = state.LoadUpload(context.Background(), obj.GetEntityID(), obj.GetPath()); saved != nil {
+		t.Errorf("expected the state to be removed once the upload completed")
+	}
+}
+
+func TestEntityObject_UploadMultipartAbort(t *testing.T) {
+	store := newTestMultipartStore(t)
+	store.failures[2] = http.StatusForbidden
+	content := []byte(strings.Repeat("0123456789", 50))
+	obj := NewUpload("exports/abort.bin", proto.ObjectType_Standard)
+
+	err := obj.UploadMultipart(context.Background(), bytes.NewReader(content), int64(len(content)), store,
+		WithPartSize(250), WithPartConcurrency(1),
+		WithPartTransfer(WithHTTPClient(store.server.Client()), WithTransferRetries(0, 0)))
+	if err == nil {
+		t.Fatal("expected the rejected part to fail the upload")
+	}
+	if len(store.aborted) != 1 || store.aborted[0] != "upload-1" {
+		t.Errorf("expected the upload to be aborted without a state store, got %v", store.aborted)
+	}
+
+	// resumable state is keyed by entity, which is only known once the upload is prepared
+	err = obj.UploadMultipart(context.Background(), bytes.NewReader(content), int64(len(content)), store,
+		WithUploadStateStore(FileUploadStateStore(t.TempDir())))
+	if err == nil {
+		t.Error("expected a state store to require the entity ID")
+	}
+}
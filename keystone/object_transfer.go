@@ -0,0 +1,407 @@
+package keystone
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+const (
+	// ContentMD5Header is the base64 MD5 of the content, sent on uploads WithChecksums and verified by S3 and GCS
+	ContentMD5Header = "Content-MD5"
+	// S3SHA256Header is the header S3 verifies the base64 SHA256 of uploaded content against, see WithSHA256Header
+	S3SHA256Header = "X-Amz-Checksum-Sha256"
+)
+
+// TransferError is returned when an object store responds to an upload or download with an error status
+type TransferError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *TransferError) Error() string {
+	return "object transfer failed, status " + strconv.Itoa(e.StatusCode) + ": " + e.Body
+}
+
+// TransferProgress is called as object content is transferred, total is -1 when the size is unknown
+type TransferProgress func(transferred, total int64)
+
+type transferOptions struct {
+	client        *http.Client
+	progress      TransferProgress
+	retries       int
+	backoff       time.Duration
+	checksums     bool
+	sha256Header  string
+	contentLength int64
+}
+
+// TransferOption configures an object upload or download
+type TransferOption func(*transferOptions)
+
+// WithHTTPClient sets the client used to transfer objects, defaulting to http.DefaultClient
+func WithHTTPClient(client *http.Client) TransferOption {
+	return func(o *transferOptions) {
+		if client != nil {
+			o.client = client
+		}
+	}
+}
+
+// WithProgress calls progress as content is transferred, restarting from zero when an upload is retried
+func WithProgress(progress TransferProgress) TransferOption {
+	return func(o *transferOptions) {
+		o.progress = progress
+	}
+}
+
+// WithTransferRetries sets how many times a transfer failing with a network error or 5xx status is retried
+func WithTransferRetries(retries int, backoff time.Duration) TransferOption {
+	return func(o *transferOptions) {
+		o.retries = retries
+		o.backoff = backoff
+	}
+}
+
+// WithChecksums sends the MD5 of uploaded content, which requires the content to be an io.ReadSeeker
+func WithChecksums() TransferOption {
+	return func(o *transferOptions) {
+		o.checksums = true
+	}
+}
+
+// WithSHA256Header also sends the SHA256 of uploaded content WithChecksums, in the header verified by the object
+// store, such as S3SHA256Header. Stores which sign upload URLs may require the header to be included in the signature.
+func WithSHA256Header(header string) TransferOption {
+	return func(o *transferOptions) {
+		o.sha256Header = header
+	}
+}
+
+// WithContentLength sets the upload size, when it cannot be determined from the content
+func WithContentLength(length int64) TransferOption {
+	return func(o *transferOptions) {
+		o.contentLength = length
+	}
+}
+
+func newTransferOptions(opts []TransferOption) transferOptions {
+	options := transferOptions{client: http.DefaultClient, retries: 3, backoff: 200 * time.Millisecond, contentLength: -1}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// UploadStream uploads content to the signed upload URL without buffering it in memory.
+// Uploads are retried when content is an io.ReadSeeker, otherwise the first failure is returned.
+func (e *EntityObject) UploadStream(ctx context.Context, content io.Reader, opts ...TransferOption) error {
+	if e.GetUploadURL() == "" {
+		return errors.New("upload URL is empty; call the API to initialize upload first")
+	}
+	options := newTransferOptions(opts)
+
+	seeker, seekable := content.(io.ReadSeeker)
+	var start int64
+	if seekable {
+		var err error
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			return err
+		}
+		if options.contentLength < 0 {
+			end, err := seeker.Seek(0, io.SeekEnd)
+			if err != nil {
+				return err
+			}
+			options.contentLength = end - start
+		}
+	} else {
+		// the content cannot be read again, so the upload cannot be retried
+		options.retries = 0
+		if lener, ok := content.(interface{ Len() int }); ok && options.contentLength < 0 {
+			options.contentLength = int64(lener.Len())
+		}
+	}
+
+	headers := http.Header{}
+	if options.checksums {
+		if !seekable {
+			return errors.New("checksums require the content to be an io.ReadSeeker")
+		}
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return err
+		}
+		md5Hash, sha256Hash := md5.New(), sha256.New()
+		if _, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), seeker); err != nil {
+			return err
+		}
+		headers.Set(ContentMD5Header, base64.StdEncoding.EncodeToString(md5Hash.Sum(nil)))
+		if options.sha256Header != "" {
+			headers.Set(options.sha256Header, base64.StdEncoding.EncodeToString(sha256Hash.Sum(nil)))
+		}
+	}
+
+	return e.put(ctx, options, headers, func() (io.ReadCloser, int64, error) {
+		if seekable {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, 0, err
+			}
+		}
+		return io.NopCloser(content), options.contentLength, nil
+	})
+}
+
+// CopyFromURLContext streams the source URL to the signed upload URL, fetching the source again for each retry
+func (e *EntityObject) CopyFromURLContext(ctx context.Context, source string, opts ...TransferOption) error {
+	if e.GetUploadURL() == "" {
+		return errors.New("upload URL is empty; call the API to initialize upload first")
+	}
+	options := newTransferOptions(opts)
+	return e.put(ctx, options, http.Header{}, func() (io.ReadCloser, int64, error) {
+		return openRemoteFile(ctx, options.client, source)
+	})
+}
+
+// put uploads the content returned by open to the upload URL, open is called for each attempt
+func (e *EntityObject) put(ctx context.Context, options transferOptions, headers http.Header, open func() (io.ReadCloser, int64, error)) error {
+	_, err := putContent(ctx, e.GetUploadURL(), e.uploadHeaders, options, headers, open)
+	return err
+}
+
+// putContent uploads the content returned by open to url, returning the response headers of the successful attempt
+func putContent(ctx context.Context, url string, uploadHeaders map[string]string, options transferOptions, headers http.Header,
+	open func() (io.ReadCloser, int64, error)) (http.Header, error) {
+	var respHeader http.Header
+	err := retryTransfer(ctx, options, func() error {
+		body, length, err := open()
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		if length < 0 {
+			length = options.contentLength
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, &progressReader{
+			reader: body, total: length, progress: options.progress,
+		})
+		if err != nil {
+			return err
+		}
+		if length == 0 {
+			req.Body = http.NoBody
+		}
+		if length >= 0 {
+			req.ContentLength = length
+		}
+		for k, v := range headers {
+			req.Header[k] = v
+		}
+		for k, v := range uploadHeaders {
+			req.Header.Set(k, v)
+		}
+
+		resp, err := options.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		respHeader = resp.Header
+		return transferStatusError(resp)
+	})
+	return respHeader, err
+}
+
+// DownloadObject streams an object returned by WithObjects to dst, verifying the MD5 and CRC32C when the object has them.
+// Failed downloads are resumed with a range request, or by skipping the content already written when ranges are
+// not supported.
+func DownloadObject(ctx context.Context, obj *proto.EntityObject, dst io.Writer, opts ...TransferOption) error {
+	if obj.GetUrl() == "" {
+		return errors.New("object has no download URL; retrieve it using WithObjects")
+	}
+	options := newTransferOptions(opts)
+
+	md5Hash, crcHash := md5.New(), crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	written := int64(0)
+	total := obj.GetSize()
+	if total == 0 {
+		total = -1
+	}
+
+	err := retryTransfer(ctx, options, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, obj.GetUrl(), nil)
+		if err != nil {
+			return err
+		}
+		if written > 0 {
+			req.Header.Set("Range", "bytes="+strconv.FormatInt(written, 10)+"-")
+		}
+		resp, err := options.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if err = transferStatusError(resp); err != nil {
+			return err
+		}
+
+		if written > 0 && resp.StatusCode != http.StatusPartialContent {
+			// the range was ignored, so skip the content already written
+			if _, err = io.CopyN(io.Discard, resp.Body, written); err != nil {
+				return err
+			}
+		}
+		if total < 0 && resp.StatusCode == http.StatusOK && resp.ContentLength >= 0 {
+			total = resp.ContentLength
+		}
+
+		n, err := io.Copy(io.MultiWriter(writeErrorWriter{dst}, md5Hash, crcHash), &progressReader{
+			reader: resp.Body, transferred: written, total: total, progress: options.progress,
+		})
+		written += n
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = verifyChecksum(obj.GetMd5(), md5Hash); err != nil {
+		return err
+	}
+	return verifyChecksum(obj.GetCrc32C(), crcHash)
+}
+
+// Download streams the object at path to dst, the object must be retrieved using WithObjects
+func (e *EmbeddedObjects) Download(ctx context.Context, path string, dst io.Writer, opts ...TransferOption) error {
+	obj := e.GetObject(path)
+	if obj == nil {
+		return fmt.Errorf("object %s was not retrieved", path)
+	}
+	return DownloadObject(ctx, obj, dst, opts...)
+}
+
+// retryTransfer retries attempt with backoff while it fails with a network error or 5xx status
+func retryTransfer(ctx context.Context, options transferOptions, attempt func() error) error {
+	delay := options.backoff
+	for try := 0; ; try++ {
+		err := attempt()
+		if err == nil || try >= options.retries || !retryableTransferError(err) || ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// retryableTransferError returns true for network errors and 5xx statuses, errors writing the destination and
+// reading local content are not retried
+func retryableTransferError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var writeErr *destinationError
+	if errors.As(err, &writeErr) {
+		return false
+	}
+	var statusErr *TransferError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// destinationError is an error writing downloaded content to the caller's writer
+type destinationError struct {
+	err error
+}
+
+func (e *destinationError) Error() string { return e.err.Error() }
+func (e *destinationError) Unwrap() error { return e.err }
+
+// writeErrorWriter marks write errors as destination errors, so they are not mistaken for network errors
+type writeErrorWriter struct {
+	writer io.Writer
+}
+
+func (w writeErrorWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	if err != nil {
+		err = &destinationError{err: err}
+	}
+	return n, err
+}
+
+func transferStatusError(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return &TransferError{StatusCode: resp.StatusCode, Body: string(body)}
+}
+
+// verifyChecksum compares a hash with an expected base64 or hex checksum, an empty checksum is not verified
+func verifyChecksum(expected string, h hash.Hash) error {
+	if expected == "" {
+		return nil
+	}
+	sum := h.Sum(nil)
+	if expected == base64.StdEncoding.EncodeToString(sum) || expected == hex.EncodeToString(sum) {
+		return nil
+	}
+	return ErrChecksumMismatch
+}
+
+func openRemoteFile(ctx context.Context, client *http.Client, url string) (io.ReadCloser, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err = transferStatusError(resp); err != nil {
+		resp.Body.Close()
+		return nil, 0, err
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// progressReader reports the bytes read to a progress callback
+type progressReader struct {
+	reader      io.Reader
+	transferred int64
+	total       int64
+	progress    TransferProgress
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.transferred += int64(n)
+		if r.progress != nil {
+			r.progress(r.transferred, r.total)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,284 @@
+package keystone
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+// objectStore stands in for a signed URL object store
+type objectStore struct {
+	lock        sync.Mutex
+	objects     map[string][]byte
+	headers     http.Header
+	failPuts    int
+	failGets    int
+	cutGetsAt   int
+	ignoreRange bool
+	puts        int
+	gets        int
+}
+
+func newObjectStore(t *testing.T) (*objectStore, *httptest.Server) {
+	store := &objectStore{objects: map[string][]byte{}}
+	server := httptest.NewServer(http.HandlerFunc(store.serve))
+	t.Cleanup(server.Close)
+	return store, server
+}
+
+func (s *objectStore) serve(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		s.puts++
+		body, _ := io.ReadAll(r.Body)
+		if s.failPuts > 0 {
+			s.failPuts--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.ContentLength != int64(len(body)) {
+			http.Error(w, "content length mismatch", http.StatusBadRequest)
+			return
+		}
+		s.objects[r.URL.Path] = body
+		s.headers = r.Header.Clone()
+	case http.MethodGet:
+		s.gets++
+		if s.failGets > 0 {
+			s.failGets--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		data, ok := s.objects[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if rng := r.Header.Get("Range"); rng != "" && !s.ignoreRange {
+			from, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			data = data[from:]
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		if s.cutGetsAt > 0 && s.cutGetsAt < len(data) {
+			// drop the connection part way through the body
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			_, _ = w.Write(data[:s.cutGetsAt])
+			s.cutGetsAt = 0
+			panic(http.ErrAbortHandler)
+		}
+		_, _ = w.Write(data)
+	}
+}
+
+func (s *objectStore) object(path string) []byte {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.objects[path]
+}
+
+func TestEntityObject_UploadStream(t *testing.T) {
+	store, server := newObjectStore(t)
+	store.failPuts = 2
+
+	content := bytes.Repeat([]byte("keystone"), 1024)
+	obj := NewUpload("media/video.mp4", proto.ObjectType_Standard)
+	obj.uploadURL = server.URL + "/media/video.mp4"
+	obj.uploadHeaders = map[string]string{"X-Signed": "yes"}
+
+	var lastProgress, lastTotal int64
+	err := obj.UploadStream(context.Background(), bytes.NewReader(content),
+		WithHTTPClient(server.Client()),
+		WithTransferRetries(3, time.Millisecond),
+		WithChecksums(),
+		WithSHA256Header(S3SHA256Header),
+		WithProgress(func(transferred, total int64) { lastProgress, lastTotal = transferred, total }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(store.object("/media/video.mp4"), content) {
+		t.Errorf("expected the content to be uploaded")
+	}
+	if store.puts != 3 {
+		t.Errorf("expected the upload to be retried after 5xx responses, got %d attempts", store.puts)
+	}
+	md5Sum, shaSum := md5.Sum(content), sha256.Sum256(content)
+	if got := store.headers.Get(ContentMD5Header); got != base64.StdEncoding.EncodeToString(md5Sum[:]) {
+		t.Errorf("expected the MD5 header, got %q", got)
+	}
+	if got := store.headers.Get("x-amz-checksum-sha256"); got != base64.StdEncoding.EncodeToString(shaSum[:]) {
+		t.Errorf("expected the SHA256 header, got %q", got)
+	}
+	if store.headers.Get("X-Signed") != "yes" {
+		t.Errorf("expected the upload headers to be sent")
+	}
+	if lastProgress != int64(len(content)) || lastTotal != int64(len(content)) {
+		t.Errorf("expected progress to reach %d, got %d of %d", len(content), lastProgress, lastTotal)
+	}
+}
+
+func TestEntityObject_UploadStreamErrors(t *testing.T) {
+	store, server := newObjectStore(t)
+	obj := NewUpload("file.txt", proto.ObjectType_Standard)
+
+	if err := obj.UploadStream(context.Background(), strings.NewReader("x")); err == nil {
+		t.Errorf("expected an error without an upload URL")
+	}
+	obj.uploadURL = server.URL + "/file.txt"
+
+	// a reader which cannot be rewound is not retried
+	store.failPuts = 1
+	err := obj.UploadStream(context.Background(), io.MultiReader(strings.NewReader("data")),
+		WithHTTPClient(server.Client()), WithContentLength(4), WithTransferRetries(3, time.Millisecond))
+	var transferErr *TransferError
+	if !errors.As(err, &transferErr) || transferErr.StatusCode != http.StatusServiceUnavailable || store.puts != 1 {
+		t.Errorf("expected the 503 to be returned without a retry, got %v after %d attempts", err, store.puts)
+	}
+
+	if err = obj.UploadStream(context.Background(), io.MultiReader(strings.NewReader("data")), WithChecksums()); err == nil {
+		t.Errorf("expected checksums to require a seekable reader")
+	}
+}
+
+func TestEntityObject_CopyFromURLContext(t *testing.T) {
+	store, server := newObjectStore(t)
+	store.objects["/source.csv"] = []byte("a,b,c\n1,2,3\n")
+	store.failPuts = 1
+
+	obj := NewUpload("copy.csv", proto.ObjectType_Standard)
+	obj.uploadURL = server.URL + "/copy.csv"
+	err := obj.CopyFromURLContext(context.Background(), server.URL+"/source.csv",
+		WithHTTPClient(server.Client()), WithTransferRetries(1, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(store.object("/copy.csv")); got != "a,b,c\n1,2,3\n" {
+		t.Errorf("expected the source to be copied, got %q", got)
+	}
+
+	resp, err := obj.CopyFromURL(server.URL + "/source.csv")
+	if err = UploadError(resp, err); err != nil {
+		t.Errorf("expected CopyFromURL to stream the source, got %v", err)
+	}
+}
+
+func TestDownloadObject(t *testing.T) {
+	store, server := newObjectStore(t)
+	content := bytes.Repeat([]byte("0123456789"), 500)
+	store.objects["/export.bin"] = content
+	md5Sum := md5.Sum(content)
+
+	tests := []struct {
+		name        string
+		cutAt       int
+		failGets    int
+		ignoreRange bool
+	}{
+		{"complete", 0, 0, false},
+		{"retried", 0, 2, false},
+		{"resumed with range", 1200, 0, false},
+		{"resumed without range", 1200, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store.lock.Lock()
+			store.cutGetsAt, store.failGets, store.ignoreRange = test.cutAt, test.failGets, test.ignoreRange
+			store.lock.Unlock()
+
+			obj := &proto.EntityObject{
+				Path: "export.bin",
+				Url:  server.URL + "/export.bin",
+				Size: int64(len(content)),
+				Md5:  base64.StdEncoding.EncodeToString(md5Sum[:]),
+			}
+			var progress int64
+			buf := &bytes.Buffer{}
+			err := DownloadObject(context.Background(), obj, buf, WithHTTPClient(server.Client()),
+				WithTransferRetries(3, time.Millisecond),
+				WithProgress(func(transferred, total int64) { progress = transferred }))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), content) {
+				t.Errorf("expected the object content, got %d bytes", buf.Len())
+			}
+			if progress != int64(len(content)) {
+				t.Errorf("expected progress to reach %d, got %d", len(content), progress)
+			}
+		})
+	}
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write([]byte) (int, error) { return 0, w.err }
+
+func TestDownloadObject_WriteError(t *testing.T) {
+	store, server := newObjectStore(t)
+	store.objects["/file.txt"] = []byte("content")
+
+	diskFull := errors.New("no space left on device")
+	obj := &proto.EntityObject{Path: "file.txt", Url: server.URL + "/file.txt"}
+	err := DownloadObject(context.Background(), obj, failingWriter{err: diskFull}, WithHTTPClient(server.Client()),
+		WithTransferRetries(3, time.Millisecond))
+	if !errors.Is(err, diskFull) {
+		t.Errorf("expected the write error, got %v", err)
+	}
+	if store.gets != 1 {
+		t.Errorf("expected the download not to be retried, got %d requests", store.gets)
+	}
+}
+
+func TestRetryableTransferError(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{&TransferError{StatusCode: http.StatusBadGateway}, true},
+		{&TransferError{StatusCode: http.StatusForbidden}, false},
+		{&net.OpError{Op: "read", Err: errors.New("connection reset")}, true},
+		{io.ErrUnexpectedEOF, true},
+		{context.Canceled, false},
+		{&destinationError{err: io.ErrShortWrite}, false},
+		{errors.New("seek failed"), false},
+	}
+	for _, test := range tests {
+		if got := retryableTransferError(test.err); got != test.retryable {
+			t.Errorf("%v: expected retryable %v, got %v", test.err, test.retryable, got)
+		}
+	}
+}
+
+func TestDownloadObject_ChecksumMismatch(t *testing.T) {
+	store, server := newObjectStore(t)
+	store.objects["/file.txt"] = []byte("content")
+
+	objects := EmbeddedObjects{}
+	objects.addObject(&proto.EntityObject{Path: "file.txt", Url: server.URL + "/file.txt", Md5: "not-the-md5"})
+	err := objects.Download(context.Background(), "file.txt", io.Discard, WithHTTPClient(server.Client()))
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+	if err = objects.Download(context.Background(), "missing.txt", io.Discard); err == nil {
+		t.Errorf("expected an error for an object which was not retrieved")
+	}
+}
@@ -2,6 +2,7 @@ package keystone
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -24,6 +25,7 @@ type EntityObject struct {
 	metadata           map[string]string
 	uploadHeaders      map[string]string
 	data               []byte
+	entityID           string
 }
 
 func NewUpload(path string, storageClass proto.ObjectType) *EntityObject {
@@ -32,10 +34,11 @@ func NewUpload(path string, storageClass proto.ObjectType) *EntityObject {
 
 func NewUploadFromURL(path, remoteUrl string, storageClass proto.ObjectType) (*EntityObject, error) {
 	eo := &EntityObject{path: path, metadata: make(map[string]string), storageClass: storageClass}
-	data, err := getRemoteFile(remoteUrl)
+	data, _, err := openRemoteFile(context.Background(), http.DefaultClient, remoteUrl)
 	if err != nil {
 		return nil, err
 	}
+	defer data.Close()
 	fileContent, readErr := io.ReadAll(data)
 	if readErr != nil {
 		return nil, readErr
@@ -85,6 +88,11 @@ func (e *EntityObject) GetUploadURL() string {
 	return e.uploadURL
 }
 
+// GetEntityID returns the ID of the entity the upload was prepared for, set along with the upload URL
+func (e *EntityObject) GetEntityID() string {
+	return e.entityID
+}
+
 func (e *EntityObject) ReadyForUpload() bool {
 	return e.uploadURL != ""
 }
@@ -102,20 +110,24 @@ func (e *EntityObject) Upload(content io.Reader) (*http.Response, error) {
 	return http.DefaultClient.Do(req)
 }
 
+// CopyFromURL streams the source URL to the upload URL, see CopyFromURLContext for retries and progress
 func (e *EntityObject) CopyFromURL(source string) (*http.Response, error) {
 	if e.GetUploadURL() == "" {
 		return nil, errors.New("upload URL is empty; call the API to initialize upload first")
 	}
 
-	src, err := getRemoteFile(source)
+	src, length, err := openRemoteFile(context.Background(), http.DefaultClient, source)
 	if err != nil {
 		return nil, err
 	}
+	// the source is read by http.Client.Do below, before the function returns
+	defer src.Close()
 
 	req, err := http.NewRequest(http.MethodPut, e.GetUploadURL(), src)
 	if err != nil {
 		return nil, err
 	}
+	req.ContentLength = length
 
 	// If we have headers provided for the upload, apply them
 	if e.uploadHeaders != nil {
@@ -127,26 +139,6 @@ func (e *EntityObject) CopyFromURL(source string) (*http.Response, error) {
 	return http.DefaultClient.Do(req)
 }
 
-func getRemoteFile(url string) (io.Reader, error) {
-	// Fetch the source content
-	srcResp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	// Ensure the source response body is closed after we finish the upload request
-	// (http.Client.Do below will read from this body before the function returns)
-	defer srcResp.Body.Close()
-
-	if srcResp.StatusCode < 200 || srcResp.StatusCode >= 300 {
-		b, _ := io.ReadAll(srcResp.Body)
-		return nil, errors.New("failed to download source: status " + srcResp.Status + " body: " + string(b))
-	}
-
-	buf := bytes.NewBuffer(nil)
-	_, err = io.Copy(buf, srcResp.Body)
-	return buf, err
-}
-
 func (e *EntityObject) UploadToJson(content interface{}) (*http.Response, error) {
 	jsn, err := json.Marshal(content)
 	if err != nil {
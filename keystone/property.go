@@ -132,7 +132,11 @@ func mergeDefinitions(a, b proto.PropertyDefinition) proto.PropertyDefinition {
 }
 
 func getFieldOptions(f reflect.StructField) fieldOptions {
-	tag := f.Tag.Get("keystone")
+	return parseFieldTag(f.Name, f.Tag.Get("keystone"))
+}
+
+// parseFieldTag parses a keystone struct tag for the named field
+func parseFieldTag(fieldName, tag string) fieldOptions {
 	opt := fieldOptions{}
 
 	tagParts := strings.Split(tag, ",")
@@ -140,8 +144,9 @@ func getFieldOptions(f reflect.StructField) fieldOptions {
 		part = strings.TrimSpace(part)
 		if i == 0 {
 			if part == "" {
-				opt.name = PropertyName(f.Name)
+				opt.name = PropertyName(fieldName)
 			} else if part == "-" {
+				opt.ignoredWithOptions = len(tagParts) > 1
 				return opt
 			} else {
 				opt.name = strings.ToLower(part)
@@ -151,6 +156,8 @@ func getFieldOptions(f reflect.StructField) fieldOptions {
 		switch part {
 		case "omitempty":
 			opt.omitempty = true
+		case "json":
+			opt.json = true
 
 		case "unique":
 			opt.unique = true
@@ -181,6 +188,13 @@ func getFieldOptions(f reflect.StructField) fieldOptions {
 			opt.personalData = true
 		case "user":
 			opt.userInputData = true
+
+		default:
+			if view, ok := strings.CutPrefix(part, "view="); ok && view != "" {
+				opt.views = append(opt.views, view)
+			} else if part != "" {
+				opt.unknown = append(opt.unknown, part)
+			}
 		}
 	}
 	return opt
@@ -191,6 +205,7 @@ type fieldOptions struct {
 
 	// marshal
 	omitempty bool
+	json      bool // store the value as JSON bytes
 
 	// options
 	unique        bool
@@ -210,6 +225,14 @@ type fieldOptions struct {
 	// Data classification
 	personalData  bool
 	userInputData bool
+
+	// views the property is included in
+	views []string
+
+	// tag parts which were not understood, reported by ValidateType
+	unknown []string
+	// options were given on a field ignored with "-"
+	ignoredWithOptions bool
 }
 
 func (fOpt fieldOptions) definition() proto.PropertyDefinition {
@@ -0,0 +1,185 @@
+package keystone
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sort"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+// QueryBuilder builds a find or index query for a registered type, unmarshalling the results into T
+type QueryBuilder[T any] struct {
+	actor      *Actor
+	properties []string
+	retrieve   []RetrieveOption
+	options    []FindOption
+	useIndex   bool
+}
+
+// Query creates a query builder for T, with the schema type and properties taken from its TypeDefinition
+func Query[T any](actor *Actor) *QueryBuilder[T] {
+	return &QueryBuilder[T]{actor: actor}
+}
+
+// Where adds find options, such as property filters, to the query
+func (q *QueryBuilder[T]) Where(options ...FindOption) *QueryBuilder[T] {
+	for _, opt := range options {
+		if opt != nil {
+			q.options = append(q.options, opt)
+		}
+	}
+	return q
+}
+
+// Sort orders the results by the given property
+func (q *QueryBuilder[T]) Sort(property string, descending bool) *QueryBuilder[T] {
+	q.options = append(q.options, SortBy(property, descending))
+	return q
+}
+
+// Page limits the results to a single page, with page numbers starting at 1
+func (q *QueryBuilder[T]) Page(perPage, pageNumber int32) *QueryBuilder[T] {
+	q.options = append(q.options, Limit(perPage, pageNumber))
+	return q
+}
+
+// Properties overrides the properties retrieved, which default to all properties on the type definition
+func (q *QueryBuilder[T]) Properties(properties ...string) *QueryBuilder[T] {
+	q.properties = properties
+	return q
+}
+
+// With adds retrieve options to the query, these are ignored when querying the index
+func (q *QueryBuilder[T]) With(retrieve ...RetrieveOption) *QueryBuilder[T] {
+	q.retrieve = append(q.retrieve, retrieve...)
+	return q
+}
+
+// FromIndex queries the index rather than finding entities
+func (q *QueryBuilder[T]) FromIndex() *QueryBuilder[T] {
+	q.useIndex = true
+	return q
+}
+
+// All returns every entity matching the query
+func (q *QueryBuilder[T]) All(ctx context.Context) ([]*T, error) {
+	schema, err := q.definition(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entities, err := q.run(ctx, schema.Type, q.options, q.propertyList(schema))
+	if err != nil {
+		return nil, err
+	}
+
+	var dst []*T
+	err = UnmarshalToSlice(&dst, entities...)
+	return dst, err
+}
+
+// First returns the first entity matching the query, or nil if there are no matches
+func (q *QueryBuilder[T]) First(ctx context.Context) (*T, error) {
+	schema, err := q.definition(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	options := append(append([]FindOption{}, q.options...), Limit(1, 1))
+	entities, err := q.run(ctx, schema.Type, options, q.propertyList(schema))
+	if err != nil || len(entities) == 0 {
+		return nil, err
+	}
+
+	dst := new(T)
+	return dst, Unmarshal(entities[0], dst)
+}
+
+// Iterate walks every entity matching the query, see Actor.IterateIndex and Actor.IterateFind
+func (q *QueryBuilder[T]) Iterate(ctx context.Context) iter.Seq2[*T, error] {
+	schema, err := q.definition(ctx)
+	if err != nil {
+		return func(yield func(*T, error) bool) { yield(nil, err) }
+	}
+	if q.useIndex {
+		return IterateAs[T](q.actor.IterateIndex(ctx, schema.Type, q.propertyList(schema), q.options...))
+	}
+	return IterateAs[T](q.actor.IterateFind(ctx, schema.Type, RetrieveOptions(q.retrieveOptions(q.propertyList(schema))...), q.options...))
+}
+
+// Count returns the total number of entities matching the query, read from the index with a single entity page.
+// Label and relation filters cannot be applied by the index, so with those the matching IDs are found first.
+func (q *QueryBuilder[T]) Count(ctx context.Context) (int32, error) {
+	schema, err := q.definition(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	fReq := &filterRequest{}
+	for _, opt := range q.options {
+		opt.Apply(fReq)
+	}
+	options := append(append([]FindOption{}, q.options...), Limit(1, 1))
+	if len(fReq.Labels) > 0 || fReq.RelationOf != nil {
+		result, err := q.actor.FindWithResult(ctx, schema.Type, nil, options...)
+		if err != nil {
+			return 0, err
+		}
+		return result.TotalResults, nil
+	}
+
+	resp, err := q.actor.QueryIndexWithResult(ctx, schema.Type, nil, options...)
+	if err != nil {
+		return 0, err
+	}
+	return resp.GetTotalResults(), nil
+}
+
+func (q *QueryBuilder[T]) definition(ctx context.Context) (TypeDefinition, error) {
+	if q.actor == nil || q.actor.connection == nil {
+		return TypeDefinition{}, errors.New("actor or connection is nil")
+	}
+	return q.actor.connection.ensureType(ctx, new(T))
+}
+
+func (q *QueryBuilder[T]) propertyList(schema TypeDefinition) []string {
+	if q.properties != nil {
+		return q.properties
+	}
+
+	var props []string
+	for p := range schema.Properties {
+		if p.Name() == "" || p.HydrateOnly() {
+			continue
+		}
+		props = append(props, p.Name())
+	}
+	sort.Strings(props)
+	return props
+}
+
+// retrieveOptions returns the retrieve options for a find, retrieving the given properties
+func (q *QueryBuilder[T]) retrieveOptions(properties []string) []RetrieveOption {
+	if len(properties) == 0 {
+		return q.retrieve
+	}
+	return append([]RetrieveOption{WithProperties(properties...)}, q.retrieve...)
+}
+
+func (q *QueryBuilder[T]) run(ctx context.Context, schemaType string, options []FindOption, properties []string) ([]*proto.EntityResponse, error) {
+	if q.useIndex {
+		resp, err := q.actor.QueryIndexWithResult(ctx, schemaType, properties, options...)
+		if err != nil {
+			return nil, err
+		}
+		return resp.GetEntities(), nil
+	}
+
+	result, err := q.actor.FindWithResult(ctx, schemaType, RetrieveOptions(q.retrieveOptions(properties)...), options...)
+	if err != nil {
+		return nil, err
+	}
+	return result.Entities, nil
+}
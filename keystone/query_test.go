@@ -0,0 +1,125 @@
+package keystone
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+type queryTestUser struct {
+	BaseEntity
+	Name  string
+	Email string
+}
+
+func queryTestResponse(id, name string) *proto.EntityResponse {
+	return &proto.EntityResponse{
+		Entity: &proto.Entity{EntityId: id},
+		Properties: []*proto.EntityProperty{
+			{Property: "name", Value: &proto.Value{Text: name}},
+		},
+	}
+}
+
+func TestQuery_All(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	mock.QueryIndexFunc = func(_ context.Context, req *proto.QueryIndexRequest) (*proto.QueryIndexResponse, error) {
+		if len(req.GetSort()) != 1 || req.GetSort()[0].GetProperty() != "name" {
+			t.Fatalf("expected the name sort on the index, got %v", req.GetSort())
+		}
+		return &proto.QueryIndexResponse{Entities: []*proto.EntityResponse{
+			{Entity: &proto.Entity{EntityId: "u2"}},
+			{Entity: &proto.Entity{EntityId: "u1"}},
+		}, TotalResults: 2}, nil
+	}
+	mock.FindFunc = func(_ context.Context, req *proto.FindRequest) (*proto.FindResponse, error) {
+		if req.GetSchema().GetKey() != "query-test-user" {
+			t.Fatalf("expected schema query-test-user, got %q", req.GetSchema().GetKey())
+		}
+		props := req.GetView().GetProperties()
+		if len(props) != 1 || len(props[0].GetProperties()) != 2 {
+			t.Fatalf("expected email and name properties, got %v", props)
+		}
+		if len(req.GetPropertyFilters()) != 1 || req.GetPropertyFilters()[0].GetProperty() != "email" {
+			t.Fatalf("expected email filter, got %v", req.GetPropertyFilters())
+		}
+		return &proto.FindResponse{Entities: []*proto.EntityResponse{
+			queryTestResponse("u1", "Zed"),
+			queryTestResponse("u2", "Amy"),
+		}}, nil
+	}
+
+	users, err := Query[queryTestUser](actor).
+		Where(WhereEndsWith("email", "@example.com")).
+		Sort("name", false).
+		All(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(users))
+	}
+	if users[0].Name != "Amy" || users[0].GetKeystoneID() != "u2" {
+		t.Errorf("expected Amy (u2) first, got %s (%s)", users[0].Name, users[0].GetKeystoneID())
+	}
+}
+
+func TestQuery_FirstAndCount(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	mock.QueryIndexFunc = func(_ context.Context, req *proto.QueryIndexRequest) (*proto.QueryIndexResponse, error) {
+		if req.GetPage().GetPerPage() == 1 {
+			return &proto.QueryIndexResponse{Entities: []*proto.EntityResponse{queryTestResponse("u1", "Amy")}, TotalResults: 3}, nil
+		}
+		return &proto.QueryIndexResponse{TotalResults: 3}, nil
+	}
+
+	user, err := Query[queryTestUser](actor).FromIndex().First(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user == nil || user.Name != "Amy" {
+		t.Fatalf("expected Amy, got %+v", user)
+	}
+
+	count, err := Query[queryTestUser](actor).FromIndex().Count(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected count of 3, got %d", count)
+	}
+
+	// counting a find query reads the total from a single entity index page
+	mock.FindFunc = func(_ context.Context, req *proto.FindRequest) (*proto.FindResponse, error) {
+		t.Errorf("expected count not to find entities")
+		return &proto.FindResponse{}, nil
+	}
+	mock.QueryIndexFunc = func(_ context.Context, req *proto.QueryIndexRequest) (*proto.QueryIndexResponse, error) {
+		if req.GetPage().GetPerPage() != 1 || len(req.GetFilters()) != 1 {
+			t.Errorf("expected a filtered single entity page, got %v", req)
+		}
+		return &proto.QueryIndexResponse{Entities: []*proto.EntityResponse{queryTestResponse("u1", "Amy")}, TotalResults: 1200}, nil
+	}
+	if count, err = Query[queryTestUser](actor).Where(WhereEquals("name", "Amy")).Count(context.Background()); err != nil || count != 1200 {
+		t.Errorf("expected count of 1200, got %d %v", count, err)
+	}
+}
+
+func TestQuery_FirstNoResults(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	mock.QueryIndexFunc = func(_ context.Context, req *proto.QueryIndexRequest) (*proto.QueryIndexResponse, error) {
+		return &proto.QueryIndexResponse{}, nil
+	}
+
+	user, err := Query[queryTestUser](actor).First(context.Background())
+	if err != nil || user != nil {
+		t.Fatalf("expected nil user and error, got %+v, %v", user, err)
+	}
+}
@@ -0,0 +1,46 @@
+package reflector
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+// JSON stores values which have no native keystone type, such as slices of structs, as JSON bytes
+type JSON struct{}
+
+func (e JSON) ToProto(value reflect.Value) (*proto.Value, error) {
+	value = Deref(value)
+	if !value.IsValid() {
+		return &proto.Value{KnownType: proto.Property_Bytes}, nil
+	}
+	if (value.Kind() == reflect.Slice || value.Kind() == reflect.Map) && value.IsNil() {
+		return &proto.Value{KnownType: proto.Property_Bytes}, nil
+	}
+	data, err := json.Marshal(value.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return &proto.Value{Raw: data, KnownType: proto.Property_Bytes}, nil
+}
+
+func (e JSON) SetValue(value *proto.Value, onto reflect.Value) error {
+	if value == nil {
+		return nil
+	}
+	if len(value.GetRaw()) == 0 {
+		onto.Set(reflect.Zero(onto.Type()))
+		return nil
+	}
+	newVal := reflect.New(onto.Type())
+	if err := json.Unmarshal(value.GetRaw(), newVal.Interface()); err != nil {
+		return err
+	}
+	onto.Set(newVal.Elem())
+	return nil
+}
+
+func (e JSON) PropertyDefinition() proto.PropertyDefinition {
+	return proto.PropertyDefinition{DataType: proto.Property_Bytes}
+}
@@ -0,0 +1,51 @@
+package reflector
+
+import (
+	"reflect"
+	"testing"
+)
+
+type jsonAddress struct {
+	Line1 string `json:"line1"`
+	Zip   string `json:"zip"`
+}
+
+func TestJSON(t *testing.T) {
+	addresses := []jsonAddress{{Line1: "1 High St", Zip: "AB1"}, {Line1: "2 Low Rd"}}
+	var nilAddresses []jsonAddress
+
+	tests := []struct {
+		name   string
+		input  any
+		expect string
+	}{
+		{"slice", addresses, `[{"line1":"1 High St","zip":"AB1"},{"line1":"2 Low Rd","zip":""}]`},
+		{"slice pointer", &addresses, `[{"line1":"1 High St","zip":"AB1"},{"line1":"2 Low Rd","zip":""}]`},
+		{"nil slice", nilAddresses, ""},
+		{"empty slice", []jsonAddress{}, "[]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ref := JSON{}
+			val, err := ref.ToProto(reflect.ValueOf(test.input))
+			if err != nil {
+				t.Fatalf("JSON.ToProto returned error: %v", err)
+			}
+			if string(val.GetRaw()) != test.expect {
+				t.Errorf("JSON.ToProto returned %s, want %s", val.GetRaw(), test.expect)
+			}
+
+			refVal := reflect.ValueOf(new([]jsonAddress)).Elem()
+			if err = ref.SetValue(val, refVal); err != nil {
+				t.Fatalf("JSON.SetValue returned error: %v", err)
+			}
+			want := reflect.ValueOf(test.input)
+			if want.Kind() == reflect.Pointer {
+				want = want.Elem()
+			}
+			if !reflect.DeepEqual(refVal.Interface(), want.Interface()) {
+				t.Errorf("JSON.SetValue returned %v, want %v", refVal.Interface(), want.Interface())
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package reflector
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FloatSlice stores float slices as repeated mixed values, keyed by index
+type FloatSlice struct{}
+
+func (e FloatSlice) ToProto(value reflect.Value) (*proto.Value, error) {
+	return sliceToMixed(value, func(elem reflect.Value) *proto.Value {
+		return &proto.Value{Float: elem.Float(), KnownType: proto.Property_Float}
+	})
+}
+
+func (e FloatSlice) SetValue(value *proto.Value, onto reflect.Value) error {
+	return mixedToSlice(value, onto, func(v *proto.Value, elem reflect.Value) {
+		elem.SetFloat(v.GetFloat())
+	})
+}
+
+func (e FloatSlice) PropertyDefinition() proto.PropertyDefinition {
+	return proto.PropertyDefinition{DataType: proto.Property_KeyMixed}
+}
+
+// BoolSlice stores bool slices as repeated mixed values, keyed by index
+type BoolSlice struct{}
+
+func (e BoolSlice) ToProto(value reflect.Value) (*proto.Value, error) {
+	return sliceToMixed(value, func(elem reflect.Value) *proto.Value {
+		return &proto.Value{Bool: elem.Bool(), KnownType: proto.Property_Boolean}
+	})
+}
+
+func (e BoolSlice) SetValue(value *proto.Value, onto reflect.Value) error {
+	return mixedToSlice(value, onto, func(v *proto.Value, elem reflect.Value) {
+		elem.SetBool(v.GetBool())
+	})
+}
+
+func (e BoolSlice) PropertyDefinition() proto.PropertyDefinition {
+	return proto.PropertyDefinition{DataType: proto.Property_KeyMixed}
+}
+
+// TimeSlice stores time slices as repeated mixed values, keyed by index
+type TimeSlice struct{}
+
+func (e TimeSlice) ToProto(value reflect.Value) (*proto.Value, error) {
+	return sliceToMixed(value, func(elem reflect.Value) *proto.Value {
+		tme, _ := elem.Interface().(time.Time)
+		return &proto.Value{Time: timestamppb.New(tme), KnownType: proto.Property_Time}
+	})
+}
+
+func (e TimeSlice) SetValue(value *proto.Value, onto reflect.Value) error {
+	return mixedToSlice(value, onto, func(v *proto.Value, elem reflect.Value) {
+		elem.Set(reflect.ValueOf(v.GetTime().AsTime()))
+	})
+}
+
+func (e TimeSlice) PropertyDefinition() proto.PropertyDefinition {
+	return proto.PropertyDefinition{DataType: proto.Property_KeyMixed}
+}
+
+func sliceToMixed(value reflect.Value, toProto func(reflect.Value) *proto.Value) (*proto.Value, error) {
+	value = Deref(value)
+	ret := &proto.Value{Array: proto.NewRepeatedValue(), KnownType: proto.Property_KeyMixed}
+	if !value.IsValid() {
+		return ret, nil
+	}
+	if value.Kind() != reflect.Slice {
+		return nil, UnsupportedTypeError
+	}
+	for i := 0; i < value.Len(); i++ {
+		ret.Array.Mixed[strconv.Itoa(i)] = toProto(value.Index(i))
+	}
+	return ret, nil
+}
+
+func mixedToSlice(value *proto.Value, onto reflect.Value, setElem func(*proto.Value, reflect.Value)) error {
+	if onto.Kind() == reflect.Pointer {
+		if onto.IsNil() {
+			onto.Set(reflect.New(onto.Type().Elem()))
+		}
+		onto = onto.Elem()
+	}
+
+	mixed := value.GetArray().GetMixed()
+	indexes := make([]int, 0, len(mixed))
+	for key := range mixed {
+		if i, err := strconv.Atoi(key); err == nil {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Ints(indexes)
+
+	elemSlice := reflect.MakeSlice(onto.Type(), len(indexes), len(indexes))
+	for i, index := range indexes {
+		setElem(mixed[strconv.Itoa(index)], elemSlice.Index(i))
+	}
+	onto.Set(elemSlice)
+	return nil
+}
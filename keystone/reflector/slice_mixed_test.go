@@ -0,0 +1,75 @@
+package reflector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+type repeatedReflector interface {
+	ToProto(reflect.Value) (*proto.Value, error)
+	SetValue(*proto.Value, reflect.Value) error
+}
+
+func TestRepeatedSlices(t *testing.T) {
+	type scores []float32
+	now := time.Now().UTC()
+	floats := []float64{1.5, 0, -2.25}
+
+	tests := []struct {
+		name  string
+		ref   repeatedReflector
+		input any
+	}{
+		{"floats", FloatSlice{}, floats},
+		{"floats pointer", FloatSlice{}, &floats},
+		{"named float32", FloatSlice{}, scores{0.5, 3}},
+		{"bools", BoolSlice{}, []bool{true, false, true}},
+		{"times", TimeSlice{}, []time.Time{now, now.Add(time.Hour)}},
+		{"empty", FloatSlice{}, []float64{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			val, err := test.ref.ToProto(reflect.ValueOf(test.input))
+			if err != nil {
+				t.Fatalf("ToProto returned error: %v", err)
+			}
+			want := reflect.ValueOf(test.input)
+			if want.Kind() == reflect.Pointer {
+				want = want.Elem()
+			}
+			if len(val.GetArray().GetMixed()) != want.Len() {
+				t.Errorf("ToProto returned %d values, want %d", len(val.GetArray().GetMixed()), want.Len())
+			}
+
+			refVal := reflect.New(want.Type()).Elem()
+			if err = test.ref.SetValue(val, refVal); err != nil {
+				t.Fatalf("SetValue returned error: %v", err)
+			}
+			if !reflect.DeepEqual(refVal.Interface(), want.Interface()) {
+				t.Errorf("SetValue returned %v, want %v", refVal.Interface(), want.Interface())
+			}
+		})
+	}
+}
+
+func TestRepeatedSlices_Order(t *testing.T) {
+	// more than 10 values, so keys do not sort lexically
+	input := make([]float64, 12)
+	for i := range input {
+		input[i] = float64(i)
+	}
+	val, err := FloatSlice{}.ToProto(reflect.ValueOf(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []float64
+	if err = (FloatSlice{}).SetValue(val, reflect.ValueOf(&out).Elem()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, input) {
+		t.Errorf("expected the slice order to be kept, got %v", out)
+	}
+}
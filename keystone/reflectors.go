@@ -1,8 +1,10 @@
 package keystone
 
 import (
+	"encoding"
 	"errors"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/keystonedb/sdk-go/keystone/reflector"
@@ -63,6 +65,13 @@ var sliceKindReflector = map[reflect.Kind]Reflector{
 	reflect.Int64:  reflector.IntSlice{},
 }
 
+// repeatedSliceKindReflector stores slices without a native keystone type as repeated mixed values
+var repeatedSliceKindReflector = map[reflect.Kind]Reflector{
+	reflect.Float32: reflector.FloatSlice{},
+	reflect.Float64: reflector.FloatSlice{},
+	reflect.Bool:    reflector.BoolSlice{},
+}
+
 var typeReflector = map[reflect.Type]Reflector{
 	timeType:         reflector.Time{},
 	refTimeType:      reflector.Time{},
@@ -70,6 +79,35 @@ var typeReflector = map[reflect.Type]Reflector{
 	refTimestampType: reflector.Timestamp{},
 }
 
+var (
+	registeredReflectorLock sync.RWMutex
+	registeredReflectors    = map[reflect.Type]Reflector{}
+)
+
+// RegisterReflector sets the reflector used to store values of t, taking precedence over the built-in reflectors.
+// Pointers to t use the same reflector, the reflector is only passed values of t, with nil pointers passed as the
+// zero value. Registering a nil reflector removes the registration.
+func RegisterReflector(t reflect.Type, ref Reflector) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	registeredReflectorLock.Lock()
+	defer registeredReflectorLock.Unlock()
+	if ref == nil {
+		delete(registeredReflectors, t)
+		return
+	}
+	registeredReflectors[t] = registeredReflector{t: t, ref: ref}
+}
+
+func registeredReflectorFor(t reflect.Type) (Reflector, bool) {
+	registeredReflectorLock.RLock()
+	defer registeredReflectorLock.RUnlock()
+	ref, ok := registeredReflectors[t]
+	return ref, ok
+}
+
 func GetReflector(t reflect.Type, v reflect.Value) Reflector {
 	// Unwrap pointer types so primitive pointers use the same reflectors
 	for t.Kind() == reflect.Pointer {
@@ -79,6 +117,10 @@ func GetReflector(t reflect.Type, v reflect.Value) Reflector {
 		t = t.Elem()
 	}
 
+	if ref, ok := registeredReflectorFor(t); ok {
+		return ref
+	}
+
 	if ref, ok := typeReflector[t]; ok {
 		return ref
 	}
@@ -101,17 +143,19 @@ func GetReflector(t reflect.Type, v reflect.Value) Reflector {
 	}
 
 	if t.Kind() == reflect.Slice {
-		if t.Elem().Implements(NestedChildType) {
+		if t.Elem().Implements(NestedChildType) || reflect.PointerTo(t.Elem()).Implements(NestedChildType) {
 			// Do not marshal nested children
 			return nil
 		}
 
 		if ref, ok := sliceKindReflector[t.Elem().Kind()]; ok {
 			return ref
-		} else {
-			//TODO: Add support for other slice types, maybe json blobs?
+		}
+		if ref, ok := repeatedSliceReflector(t.Elem()); ok {
+			return ref
 		}
 	}
+
 	if t.Implements(valueMarshalerType) {
 		// Ensure we don't pass an invalid reflect.Value to newValueMarshalReflector
 		if !v.IsValid() {
@@ -140,6 +184,112 @@ func GetReflector(t reflect.Type, v reflect.Value) Reflector {
 	return nil
 }
 
+// repeatedSliceReflector returns the reflector storing slices of elem as repeated mixed values
+func repeatedSliceReflector(elem reflect.Type) (Reflector, bool) {
+	if elem == timeType {
+		return reflector.TimeSlice{}, true
+	}
+	if reflect.PointerTo(elem).Implements(valueMarshalerType) {
+		return nil, false
+	}
+	ref, ok := repeatedSliceKindReflector[elem.Kind()]
+	return ref, ok
+}
+
+// StorableKind returns true if the built-in reflectors store values of kind
+func StorableKind(kind reflect.Kind) bool {
+	_, ok := kindReflector[kind]
+	return ok
+}
+
+// StorableMapKind returns true if the built-in reflectors store string keyed maps with values of kind
+func StorableMapKind(kind reflect.Kind) bool {
+	_, ok := mapKindReflector[kind]
+	return ok
+}
+
+// StorableSliceKind returns true if the built-in reflectors store slices with elements of kind
+func StorableSliceKind(kind reflect.Kind) bool {
+	if _, ok := sliceKindReflector[kind]; ok {
+		return true
+	}
+	_, ok := repeatedSliceKindReflector[kind]
+	return ok
+}
+
+// jsonEncodable returns true if values of t can be round-tripped through encoding/json
+func jsonEncodable(t reflect.Type) bool {
+	return jsonEncodableType(t, map[reflect.Type]bool{})
+}
+
+func jsonEncodableType(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return true
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return jsonEncodableType(t.Elem(), seen)
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			if !t.Key().Implements(reflect.TypeFor[encoding.TextMarshaler]()) {
+				return false
+			}
+		}
+		return jsonEncodableType(t.Elem(), seen)
+	case reflect.Struct:
+		for _, field := range reflect.VisibleFields(t) {
+			if field.IsExported() && !field.Anonymous && field.Tag.Get("json") != "-" && !jsonEncodableType(field.Type, seen) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// fieldReflector returns the reflector for a struct field, which is JSON when the field is tagged with the json option.
+// Slices, maps and arrays without a native keystone type are only stored as JSON when tagged, so the schema of
+// existing fields is never changed implicitly.
+func fieldReflector(field reflect.StructField, v reflect.Value) Reflector {
+	if getFieldOptions(field).json {
+		return reflector.JSON{}
+	}
+	return GetReflector(field.Type, v)
+}
+
+// registeredReflector dereferences pointers, so registered reflectors only handle values of their type
+type registeredReflector struct {
+	t   reflect.Type
+	ref Reflector
+}
+
+func (r registeredReflector) ToProto(value reflect.Value) (*proto.Value, error) {
+	value = reflector.Deref(value)
+	if !value.IsValid() {
+		value = reflect.Zero(r.t)
+	}
+	return r.ref.ToProto(value)
+}
+
+func (r registeredReflector) SetValue(value *proto.Value, onto reflect.Value) error {
+	for onto.Kind() == reflect.Pointer {
+		if onto.IsNil() {
+			onto.Set(reflect.New(onto.Type().Elem()))
+		}
+		onto = onto.Elem()
+	}
+	return r.ref.SetValue(value, onto)
+}
+
+func (r registeredReflector) PropertyDefinition() proto.PropertyDefinition {
+	return r.ref.PropertyDefinition()
+}
+
 type valueMarshalReflector struct {
 	marshal ValueMarshaler
 }
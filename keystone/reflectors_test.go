@@ -1,10 +1,14 @@
 package keystone
 
 import (
+	"net/netip"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/keystonedb/sdk-go/keystone/reflector"
+	"github.com/keystonedb/sdk-go/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -52,3 +56,206 @@ func TestGetReflector(t *testing.T) {
 		})
 	}
 }
+
+// addrReflector stores netip.Addr values as text
+type addrReflector struct{}
+
+func (addrReflector) ToProto(value reflect.Value) (*proto.Value, error) {
+	addr := value.Interface().(netip.Addr)
+	if !addr.IsValid() {
+		return &proto.Value{}, nil
+	}
+	return &proto.Value{Text: addr.String()}, nil
+}
+
+func (addrReflector) SetValue(value *proto.Value, onto reflect.Value) error {
+	if value.GetText() == "" {
+		onto.Set(reflect.ValueOf(netip.Addr{}))
+		return nil
+	}
+	addr, err := netip.ParseAddr(value.GetText())
+	if err != nil {
+		return err
+	}
+	onto.Set(reflect.ValueOf(addr))
+	return nil
+}
+
+func (addrReflector) PropertyDefinition() proto.PropertyDefinition {
+	return proto.PropertyDefinition{DataType: proto.Property_Text}
+}
+
+type addrEntity struct {
+	BaseEntity
+	Addr     netip.Addr
+	Previous *netip.Addr
+	Missing  *netip.Addr
+}
+
+func TestRegisterReflector(t *testing.T) {
+	RegisterReflector(reflect.TypeFor[*netip.Addr](), addrReflector{})
+	t.Cleanup(func() { RegisterReflector(reflect.TypeFor[netip.Addr](), nil) })
+
+	previous := netip.MustParseAddr("10.0.0.1")
+	entity := addrEntity{Addr: netip.MustParseAddr("192.168.1.1"), Previous: &previous}
+
+	props, err := Marshal(entity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := props[NewProperty("addr")].GetText(); got != "192.168.1.1" {
+		t.Errorf("expected addr to be marshaled with the registered reflector, got %q", got)
+	}
+	if got := props[NewProperty("previous")].GetText(); got != "10.0.0.1" {
+		t.Errorf("expected pointers to use the registered reflector, got %q", got)
+	}
+	if _, ok := props[NewProperty("missing")]; !ok {
+		t.Errorf("expected a nil pointer to be marshaled as the zero value")
+	}
+
+	hydrated := addrEntity{}
+	if err = UnmarshalProperties(props, &hydrated); err != nil {
+		t.Fatal(err)
+	}
+	if hydrated.Addr != entity.Addr || hydrated.Previous == nil || *hydrated.Previous != previous {
+		t.Errorf("expected the registered reflector to unmarshal, got %v %v", hydrated.Addr, hydrated.Previous)
+	}
+
+	defs, err := MapProperties(addrEntity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def, ok := defs[NewProperty("addr")]; !ok || def.DataType != proto.Property_Text {
+		t.Errorf("expected addr to be defined as text, got %v", defs)
+	}
+
+	if akv := AKV("addr", entity.Addr); akv.Value.GetText() != "192.168.1.1" || akv.Property.DataType != proto.Property_Text {
+		t.Errorf("expected AKV to use the registered reflector, got %v", akv)
+	}
+
+	RegisterReflector(reflect.TypeFor[netip.Addr](), nil)
+	if GetReflector(reflect.TypeFor[netip.Addr](), reflect.Value{}) != nil {
+		t.Errorf("expected the reflector to be unregistered")
+	}
+}
+
+func TestRegisterReflector_Concurrent(t *testing.T) {
+	t.Cleanup(func() { RegisterReflector(reflect.TypeFor[netip.Prefix](), nil) })
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterReflector(reflect.TypeFor[netip.Prefix](), reflector.String{})
+		}()
+		go func() {
+			defer wg.Done()
+			_ = GetReflector(reflect.TypeFor[netip.Prefix](), reflect.Value{})
+		}()
+	}
+	wg.Wait()
+}
+
+type sliceAddress struct {
+	Line1 string
+	City  string
+}
+
+type sliceEntity struct {
+	BaseEntity
+	Addresses []sliceAddress          `keystone:",json"`
+	Offices   map[string]sliceAddress `keystone:",json"`
+	Scores    []float64
+	Flags     []bool
+	Visits    []time.Time
+	Grid      [][]int      `keystone:",json"`
+	Home      sliceAddress `keystone:",json"`
+
+	// without the json option these are not stored, as before JSON storage was supported
+	Branches []sliceAddress
+	Regions  map[string]sliceAddress
+	Checksum [16]byte
+}
+
+func TestSliceReflectors(t *testing.T) {
+	visit := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	entity := sliceEntity{
+		Addresses: []sliceAddress{{Line1: "1 High St", City: "London"}, {Line1: "2 Low Rd"}},
+		Offices:   map[string]sliceAddress{"hq": {City: "Leeds"}},
+		Scores:    []float64{1.5, 2},
+		Flags:     []bool{true, false},
+		Visits:    []time.Time{visit},
+		Grid:      [][]int{{1, 2}, {3}},
+		Home:      sliceAddress{Line1: "3 Home Ln", City: "York"},
+	}
+
+	props, err := Marshal(entity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, untagged := range []string{"branches", "regions", "checksum"} {
+		if _, stored := props[NewProperty(untagged)]; stored {
+			t.Errorf("expected %s not to be stored without the json option", untagged)
+		}
+	}
+	if got := string(props[NewProperty("addresses")].GetRaw()); got != `[{"Line1":"1 High St","City":"London"},{"Line1":"2 Low Rd","City":""}]` {
+		t.Errorf("expected addresses to be stored as JSON, got %s", got)
+	}
+	if got := props[NewProperty("scores")].GetArray().GetMixed(); len(got) != 2 || got["1"].GetFloat() != 2 {
+		t.Errorf("expected scores to be stored as repeated values, got %v", got)
+	}
+	if _, nested := props[NewPrefixProperty("home", "city")]; nested {
+		t.Errorf("expected the json tag to store home as JSON, not nested properties")
+	}
+
+	hydrated := sliceEntity{}
+	if err = UnmarshalProperties(props, &hydrated); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(hydrated, entity) {
+		t.Errorf("expected the entity to round trip, got %+v", hydrated)
+	}
+
+	defs, err := MapProperties(sliceEntity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]proto.Property_Type{
+		"addresses": proto.Property_Bytes,
+		"offices":   proto.Property_Bytes,
+		"scores":    proto.Property_KeyMixed,
+		"flags":     proto.Property_KeyMixed,
+		"visits":    proto.Property_KeyMixed,
+		"grid":      proto.Property_Bytes,
+		"home":      proto.Property_Bytes,
+	}
+	for name, dataType := range want {
+		if def, ok := defs[NewProperty(name)]; !ok || def.DataType != dataType {
+			t.Errorf("expected %s to be defined as %s, got %v", name, dataType, def.DataType)
+		}
+	}
+	for _, untagged := range []string{"branches", "regions", "checksum"} {
+		if _, defined := defs[NewProperty(untagged)]; defined {
+			t.Errorf("expected %s not to be defined without the json option", untagged)
+		}
+	}
+}
+
+func TestStorableKinds(t *testing.T) {
+	for _, kind := range []reflect.Kind{reflect.String, reflect.Bool, reflect.Int64, reflect.Uint32, reflect.Float32} {
+		if !StorableKind(kind) {
+			t.Errorf("expected %s to be storable", kind)
+		}
+	}
+	for _, kind := range []reflect.Kind{reflect.Uint64, reflect.Complex64, reflect.Chan} {
+		if StorableKind(kind) {
+			t.Errorf("expected %s not to be storable", kind)
+		}
+	}
+	if !StorableMapKind(reflect.Bool) || StorableMapKind(reflect.Float64) {
+		t.Error("expected map kinds to match the map reflectors")
+	}
+	if !StorableSliceKind(reflect.String) || !StorableSliceKind(reflect.Float64) || StorableSliceKind(reflect.Uint64) {
+		t.Error("expected slice kinds to match the slice and repeated reflectors")
+	}
+}
@@ -0,0 +1,345 @@
+package keystone
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// SchemaChangeKind classifies the impact of a schema change on existing data and clients
+type SchemaChangeKind int
+
+const (
+	// SchemaChangeAdditive adds to the schema without affecting existing data
+	SchemaChangeAdditive SchemaChangeKind = iota
+	// SchemaChangeOptionOnly changes options or metadata, without changing how data is stored
+	SchemaChangeOptionOnly
+	// SchemaChangeBreaking may invalidate existing data or clients
+	SchemaChangeBreaking
+)
+
+func (k SchemaChangeKind) String() string {
+	switch k {
+	case SchemaChangeAdditive:
+		return "additive"
+	case SchemaChangeOptionOnly:
+		return "option-only"
+	case SchemaChangeBreaking:
+		return "breaking"
+	}
+	return fmt.Sprintf("SchemaChangeKind(%d)", int(k))
+}
+
+// breakingPropertyOptions are constraints which existing data may not satisfy when added to a property
+var breakingPropertyOptions = []proto.Property_Option{
+	proto.Property_Unique,
+	proto.Property_Required,
+	proto.Property_Immutable,
+	proto.Property_Primary,
+}
+
+// SchemaChange is a single difference between a local type and its server schema
+type SchemaChange struct {
+	Type     string
+	Property string // empty for changes to the schema itself
+	Kind     SchemaChangeKind
+	Detail   string
+}
+
+func (c SchemaChange) String() string {
+	target := c.Type
+	if c.Property != "" {
+		target += "." + c.Property
+	}
+	return fmt.Sprintf("[%s] %s: %s", c.Kind, target, c.Detail)
+}
+
+// SchemaSource provides the current server schema for a type, returning nil when the type has not been defined
+type SchemaSource interface {
+	ServerSchema(ctx context.Context, schemaType string) (*proto.Schema, error)
+}
+
+// SchemaSnapshot is a set of server schemas keyed by type, which can be committed alongside code for review
+type SchemaSnapshot map[string]*proto.Schema
+
+// ServerSchema returns the schema for schemaType from the snapshot
+func (s SchemaSnapshot) ServerSchema(_ context.Context, schemaType string) (*proto.Schema, error) {
+	return s[schemaType], nil
+}
+
+// LoadSchemaSnapshot reads a snapshot written by SchemaSnapshot.Save, a missing file is an empty snapshot
+func LoadSchemaSnapshot(path string) (SchemaSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return SchemaSnapshot{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("reading schema snapshot: %w", err)
+	}
+
+	snapshot := make(SchemaSnapshot, len(raw))
+	for schemaType, msg := range raw {
+		schema := &proto.Schema{}
+		if err = protojson.Unmarshal(msg, schema); err != nil {
+			return nil, fmt.Errorf("reading schema snapshot %s: %w", schemaType, err)
+		}
+		snapshot[schemaType] = schema
+	}
+	return snapshot, nil
+}
+
+// Save writes the snapshot to path as JSON
+func (s SchemaSnapshot) Save(path string) error {
+	marshal := protojson.MarshalOptions{UseProtoNames: true}
+	raw := make(map[string]json.RawMessage, len(s))
+	for schemaType, schema := range s {
+		msg, err := marshal.Marshal(schema)
+		if err != nil {
+			return err
+		}
+		raw[schemaType] = msg
+	}
+
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// SetSchemaSource sets where SchemaPlan reads server schemas from, usually a snapshot saved from SchemaPlan.Apply
+func (c *Connection) SetSchemaSource(source SchemaSource) {
+	c.typeLock.Lock()
+	defer c.typeLock.Unlock()
+	c.schemaSource = source
+}
+
+// SchemaPlan is a reviewable set of changes between local types and their server schemas
+type SchemaPlan struct {
+	Changes []SchemaChange
+	defines []*proto.SchemaRequest // local schemas with changes, defined by Apply
+	conn    *Connection
+}
+
+// SchemaPlan diffs the local definitions of types against their server schemas, read from the schema source.
+// Schemas returned when the connection syncs a type are not used, as the sync has already applied the local schema.
+// Types unknown to the schema source are planned as new.
+func (c *Connection) SchemaPlan(ctx context.Context, types ...interface{}) (*SchemaPlan, error) {
+	c.typeLock.Lock()
+	source := c.schemaSource
+	c.typeLock.Unlock()
+	if source == nil {
+		return nil, errors.New("schema plan requires a schema source, see SetSchemaSource")
+	}
+
+	plan := &SchemaPlan{conn: c}
+	for _, t := range types {
+		definition := Define(t)
+		local := definition.Schema()
+		remote, err := source.ServerSchema(ctx, local.GetType())
+		if err != nil {
+			return nil, fmt.Errorf("schema plan %s: %w", local.GetType(), err)
+		}
+
+		changes := DiffSchema(remote, local)
+		if len(changes) > 0 {
+			plan.Changes = append(plan.Changes, changes...)
+			plan.defines = append(plan.defines, &proto.SchemaRequest{Schema: local, Views: definition.Views})
+		}
+	}
+	return plan, nil
+}
+
+// HasBreaking returns true if any change in the plan is breaking
+func (p *SchemaPlan) HasBreaking() bool {
+	return len(p.Breaking()) > 0
+}
+
+// Breaking returns the breaking changes in the plan
+func (p *SchemaPlan) Breaking() []SchemaChange {
+	var breaking []SchemaChange
+	for _, change := range p.Changes {
+		if change.Kind == SchemaChangeBreaking {
+			breaking = append(breaking, change)
+		}
+	}
+	return breaking
+}
+
+// String renders the plan one change per line, for review
+func (p *SchemaPlan) String() string {
+	if len(p.Changes) == 0 {
+		return "No schema changes"
+	}
+	lines := make([]string, len(p.Changes))
+	for i, change := range p.Changes {
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Apply defines every changed type with the server, returning their server schemas to merge into the next snapshot
+func (p *SchemaPlan) Apply(ctx context.Context) (SchemaSnapshot, error) {
+	if p.conn == nil {
+		return nil, fmt.Errorf("schema plan has no connection")
+	}
+
+	snapshot := make(SchemaSnapshot, len(p.defines))
+	for _, define := range p.defines {
+		resp, err := p.conn.Define(ctx, &proto.SchemaRequest{
+			Authorization: p.conn.authorization(),
+			Schema:        define.GetSchema(),
+			Views:         define.GetViews(),
+		})
+		if err != nil {
+			return snapshot, fmt.Errorf("define schema %s: %w", define.GetSchema().GetType(), err)
+		}
+		snapshot[define.GetSchema().GetType()] = resp
+	}
+	return snapshot, nil
+}
+
+// DiffSchema lists the changes required to move the remote schema to local, remote may be nil for a new type
+func DiffSchema(remote, local *proto.Schema) []SchemaChange {
+	schemaType := local.GetType()
+	if remote == nil {
+		return []SchemaChange{{Type: schemaType, Kind: SchemaChangeAdditive, Detail: "new schema"}}
+	}
+
+	var changes []SchemaChange
+	schemaChange := func(kind SchemaChangeKind, format string, args ...interface{}) {
+		changes = append(changes, SchemaChange{Type: schemaType, Kind: kind, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	if remote.GetKsType() != local.GetKsType() {
+		schemaChange(SchemaChangeBreaking, "keystone type %s -> %s", remote.GetKsType(), local.GetKsType())
+	}
+	if remote.GetIsChild() != local.GetIsChild() {
+		schemaChange(SchemaChangeBreaking, "child entity %t -> %t", remote.GetIsChild(), local.GetIsChild())
+	}
+	if added, removed := diffOptions(remote.GetOptions(), local.GetOptions()); len(added)+len(removed) > 0 {
+		schemaChange(SchemaChangeOptionOnly, "options %s", describeOptions(added, removed))
+	}
+	for _, field := range []struct{ name, remote, local string }{
+		{"name", remote.GetName(), local.GetName()},
+		{"description", remote.GetDescription(), local.GetDescription()},
+		{"singular", remote.GetSingular(), local.GetSingular()},
+		{"plural", remote.GetPlural(), local.GetPlural()},
+	} {
+		// the server fills in names the client leaves empty
+		if field.local != "" && field.local != field.remote {
+			schemaChange(SchemaChangeOptionOnly, "%s %q -> %q", field.name, field.remote, field.local)
+		}
+	}
+
+	remoteProps := make(map[string]*proto.Property, len(remote.GetProperties()))
+	for _, prop := range remote.GetProperties() {
+		remoteProps[prop.GetName()] = prop
+	}
+	localProps := make(map[string]*proto.Property, len(local.GetProperties()))
+	for _, prop := range local.GetProperties() {
+		localProps[prop.GetName()] = prop
+	}
+
+	for name, prop := range localProps {
+		if existing, ok := remoteProps[name]; ok {
+			changes = append(changes, diffProperty(schemaType, existing, prop)...)
+			continue
+		}
+		change := SchemaChange{Type: schemaType, Property: name, Kind: SchemaChangeAdditive, Detail: "new " + describeProperty(prop)}
+		if slices.Contains(prop.GetOptions(), proto.Property_Required) {
+			change.Kind = SchemaChangeBreaking
+			change.Detail = "new required " + describeProperty(prop)
+		}
+		changes = append(changes, change)
+	}
+
+	for name, prop := range remoteProps {
+		if _, ok := localProps[name]; ok {
+			continue
+		}
+		change := SchemaChange{Type: schemaType, Property: name, Kind: SchemaChangeBreaking, Detail: "removed " + describeProperty(prop)}
+		if slices.Contains(prop.GetOptions(), proto.Property_Deprecated) {
+			change.Kind = SchemaChangeOptionOnly
+			change.Detail = "removed deprecated " + describeProperty(prop)
+		}
+		changes = append(changes, change)
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Property < changes[j].Property
+	})
+	return changes
+}
+
+func diffProperty(schemaType string, remote, local *proto.Property) []SchemaChange {
+	var changes []SchemaChange
+	name := local.GetName()
+	if remote.GetDataType() != local.GetDataType() {
+		changes = append(changes, SchemaChange{Type: schemaType, Property: name, Kind: SchemaChangeBreaking,
+			Detail: fmt.Sprintf("data type %s -> %s", remote.GetDataType(), local.GetDataType())})
+	}
+	if remote.GetExtendedType() != local.GetExtendedType() {
+		changes = append(changes, SchemaChange{Type: schemaType, Property: name, Kind: SchemaChangeBreaking,
+			Detail: fmt.Sprintf("extended type %s -> %s", remote.GetExtendedType(), local.GetExtendedType())})
+	}
+
+	added, removed := diffOptions(remote.GetOptions(), local.GetOptions())
+	if len(added)+len(removed) == 0 {
+		return changes
+	}
+	kind := SchemaChangeOptionOnly
+	for _, opt := range added {
+		if slices.Contains(breakingPropertyOptions, opt) {
+			kind = SchemaChangeBreaking
+		}
+	}
+	return append(changes, SchemaChange{Type: schemaType, Property: name, Kind: kind,
+		Detail: "options " + describeOptions(added, removed)})
+}
+
+// diffOptions returns the options in local but not remote, and in remote but not local
+func diffOptions[T comparable](remote, local []T) (added, removed []T) {
+	for _, opt := range local {
+		if !slices.Contains(remote, opt) && !slices.Contains(added, opt) {
+			added = append(added, opt)
+		}
+	}
+	for _, opt := range remote {
+		if !slices.Contains(local, opt) && !slices.Contains(removed, opt) {
+			removed = append(removed, opt)
+		}
+	}
+	return added, removed
+}
+
+func describeOptions[T fmt.Stringer](added, removed []T) string {
+	var parts []string
+	for _, opt := range added {
+		parts = append(parts, "+"+opt.String())
+	}
+	for _, opt := range removed {
+		parts = append(parts, "-"+opt.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+func describeProperty(prop *proto.Property) string {
+	desc := "property (" + prop.GetDataType().String()
+	if prop.GetExtendedType() != proto.Property_ExtendedNone {
+		desc += " " + prop.GetExtendedType().String()
+	}
+	return desc + ")"
+}
@@ -0,0 +1,185 @@
+package keystone
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/keystonedb/sdk-go/proto"
+)
+
+type schemaPlanEntity struct {
+	BaseEntity
+	Name  string
+	Email string `keystone:",unique"`
+	Age   int64
+}
+
+func planSchema(props ...*proto.Property) *proto.Schema {
+	return &proto.Schema{Type: "schema-plan-entity", Name: "Schema Plan Entity", Properties: props}
+}
+
+func findChange(changes []SchemaChange, property string) (SchemaChange, bool) {
+	for _, change := range changes {
+		if change.Property == property {
+			return change, true
+		}
+	}
+	return SchemaChange{}, false
+}
+
+func TestDiffSchema(t *testing.T) {
+	remote := planSchema(
+		&proto.Property{Name: "name", DataType: proto.Property_Text},
+		&proto.Property{Name: "email", DataType: proto.Property_Text},
+		&proto.Property{Name: "age", DataType: proto.Property_Text},
+		&proto.Property{Name: "legacy", DataType: proto.Property_Text, Options: []proto.Property_Option{proto.Property_Deprecated}},
+		&proto.Property{Name: "nickname", DataType: proto.Property_Text},
+	)
+	local := planSchema(
+		&proto.Property{Name: "name", DataType: proto.Property_Text, Options: []proto.Property_Option{proto.Property_Searchable}},
+		&proto.Property{Name: "email", DataType: proto.Property_Text, Options: []proto.Property_Option{proto.Property_Unique}},
+		&proto.Property{Name: "age", DataType: proto.Property_Number},
+		&proto.Property{Name: "bio", DataType: proto.Property_Text},
+		&proto.Property{Name: "country", DataType: proto.Property_Text, Options: []proto.Property_Option{proto.Property_Required}},
+	)
+
+	tests := []struct {
+		property string
+		kind     SchemaChangeKind
+	}{
+		{"name", SchemaChangeOptionOnly},
+		{"email", SchemaChangeBreaking},
+		{"age", SchemaChangeBreaking},
+		{"bio", SchemaChangeAdditive},
+		{"country", SchemaChangeBreaking},
+		{"legacy", SchemaChangeOptionOnly},
+		{"nickname", SchemaChangeBreaking},
+	}
+
+	changes := DiffSchema(remote, local)
+	if len(changes) != len(tests) {
+		t.Fatalf("expected %d changes, got %d: %v", len(tests), len(changes), changes)
+	}
+	for _, test := range tests {
+		change, ok := findChange(changes, test.property)
+		if !ok {
+			t.Errorf("expected a change to %s", test.property)
+		} else if change.Kind != test.kind {
+			t.Errorf("expected %s change to %s, got %s (%s)", test.kind, test.property, change.Kind, change.Detail)
+		}
+	}
+}
+
+func TestDiffSchema_Unchanged(t *testing.T) {
+	schema := planSchema(&proto.Property{Name: "name", DataType: proto.Property_Text})
+	if changes := DiffSchema(schema, schema); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+	if changes := DiffSchema(nil, schema); len(changes) != 1 || changes[0].Kind != SchemaChangeAdditive {
+		t.Errorf("expected a single additive change for a new schema, got %v", changes)
+	}
+}
+
+func TestSchemaSnapshot_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	snapshot := SchemaSnapshot{"schema-plan-entity": planSchema(
+		&proto.Property{Name: "email", DataType: proto.Property_Text, Options: []proto.Property_Option{proto.Property_Unique}},
+	)}
+	if err := snapshot.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSchemaSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := DiffSchema(loaded["schema-plan-entity"], snapshot["schema-plan-entity"]); len(changes) != 0 {
+		t.Errorf("expected loaded snapshot to match, got %v", changes)
+	}
+
+	missing, err := LoadSchemaSnapshot(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || len(missing) != 0 {
+		t.Errorf("expected an empty snapshot for a missing file, got %v %v", missing, err)
+	}
+}
+
+func TestSchemaPlan(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	conn := actor.connection
+
+	var defined []string
+	mock.DefineFunc = func(_ context.Context, req *proto.SchemaRequest) (*proto.Schema, error) {
+		defined = append(defined, req.GetSchema().GetType())
+		return req.GetSchema(), nil
+	}
+
+	local := Define(&schemaPlanEntity{}).Schema()
+	remote := planSchema()
+	for _, prop := range local.GetProperties() {
+		if prop.GetName() != "email" {
+			remote.Properties = append(remote.Properties, prop)
+		}
+	}
+	remote.Properties = append(remote.Properties, &proto.Property{Name: "email", DataType: proto.Property_Text})
+	conn.SetSchemaSource(SchemaSnapshot{"schema-plan-entity": remote})
+
+	plan, err := conn.SchemaPlan(context.Background(), &schemaPlanEntity{}, &schemaTestEntity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.HasBreaking() || len(plan.Breaking()) != 1 || plan.Breaking()[0].Property != "email" {
+		t.Errorf("expected the unique email to be breaking, got\n%s", plan)
+	}
+	if len(defined) != 0 {
+		t.Errorf("planning should not define schemas, defined %v", defined)
+	}
+
+	snapshot, err := plan.Apply(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defined) != 2 || len(snapshot) != 2 {
+		t.Errorf("expected both changed types to be defined, got %v", defined)
+	}
+
+	conn.SetSchemaSource(snapshot)
+	plan, err = conn.SchemaPlan(context.Background(), &schemaPlanEntity{}, &schemaTestEntity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 0 {
+		t.Errorf("expected no changes after apply, got\n%s", plan)
+	}
+}
+
+func TestSchemaPlan_SyncedType(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	conn := actor.connection
+
+	if _, err := conn.SchemaPlan(context.Background(), &schemaTestEntity{}); err == nil {
+		t.Errorf("expected an error without a schema source")
+	}
+
+	remote := Define(&schemaTestEntity{}).Schema()
+	remote.Properties = append(remote.Properties, &proto.Property{Name: "removed", DataType: proto.Property_Text})
+	conn.SetSchemaSource(SchemaSnapshot{remote.GetType(): remote})
+
+	// syncing the type applies the local schema, which should not hide the change from the plan
+	mock.DefineFunc = func(_ context.Context, req *proto.SchemaRequest) (*proto.Schema, error) {
+		return req.GetSchema(), nil
+	}
+	if _, err := conn.ensureType(context.Background(), &schemaTestEntity{}); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := conn.SchemaPlan(context.Background(), &schemaTestEntity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change, ok := findChange(plan.Changes, "removed"); !ok || change.Kind != SchemaChangeBreaking {
+		t.Errorf("expected the removed property to be breaking, got\n%s", plan)
+	}
+}
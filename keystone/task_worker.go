@@ -0,0 +1,350 @@
+package keystone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// TaskAttemptKey is the task data key holding the attempt number of a re-pushed task
+	TaskAttemptKey = "keystone_attempt"
+	// TaskErrorKey is the task data key holding the last handler error of a dead-lettered task
+	TaskErrorKey = "keystone_error"
+	// TaskOriginKey is the task data key holding the ID of the task a re-pushed task was derived from
+	TaskOriginKey = "keystone_origin"
+)
+
+// TaskHandler processes a single task, the task is retried when an error is returned
+type TaskHandler func(ctx context.Context, task *proto.TaskResponse) error
+
+// TaskAttempt returns the attempt number of a task, starting at 1
+func TaskAttempt(task *proto.TaskResponse) int {
+	attempt, err := strconv.Atoi(task.GetData()[TaskAttemptKey])
+	if err != nil || attempt < 1 {
+		return 1
+	}
+	return attempt
+}
+
+// TaskOriginID returns the ID of the task as first pushed, before any retries or dead lettering
+func TaskOriginID(task *proto.TaskResponse) string {
+	if origin := task.GetData()[TaskOriginKey]; origin != "" {
+		return origin
+	}
+	return task.GetTaskId()
+}
+
+type taskWorkerOptions struct {
+	concurrency  int
+	timeout      time.Duration
+	attempts     int
+	deadLetter   string
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	drainTimeout time.Duration
+}
+
+// TaskWorkerOption configures a TaskWorker
+type TaskWorkerOption func(*taskWorkerOptions)
+
+// WithTaskConcurrency runs n handlers at once, each on its own task stream
+func WithTaskConcurrency(n int) TaskWorkerOption {
+	return func(o *taskWorkerOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithTaskTimeout sets how long a handler may run before the task is failed, zero disables the timeout.
+// The handler context is cancelled at the timeout, and the task is not retried until the handler has returned.
+func WithTaskTimeout(timeout time.Duration) TaskWorkerOption {
+	return func(o *taskWorkerOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithTaskAttempts sets how many times a task is handled before it is moved to the dead letter task
+func WithTaskAttempts(attempts int) TaskWorkerOption {
+	return func(o *taskWorkerOptions) {
+		if attempts > 0 {
+			o.attempts = attempts
+		}
+	}
+}
+
+// WithDeadLetterTask sets the task name failed tasks are pushed to, defaulting to the task name with a "_dead_letter" suffix
+func WithDeadLetterTask(taskName string) TaskWorkerOption {
+	return func(o *taskWorkerOptions) {
+		o.deadLetter = taskName
+	}
+}
+
+// WithTaskReconnectBackoff sets the delay before reconnecting a failed stream, doubling from min up to max
+func WithTaskReconnectBackoff(min, max time.Duration) TaskWorkerOption {
+	return func(o *taskWorkerOptions) {
+		o.minBackoff = min
+		o.maxBackoff = max
+	}
+}
+
+// WithTaskDrainTimeout sets how long running handlers have to finish once the worker is stopped
+func WithTaskDrainTimeout(timeout time.Duration) TaskWorkerOption {
+	return func(o *taskWorkerOptions) {
+		o.drainTimeout = timeout
+	}
+}
+
+// TaskWorker processes tasks for a task name with a pool of handlers.
+// Failed tasks are re-pushed with an incremented TaskAttemptKey until the attempts are used up, then pushed to the
+// dead letter task with the error in TaskErrorKey. Re-pushed tasks keep the workspace of the failed task, and are
+// given an ID derived from the original task ID, which is kept in TaskOriginKey. A task is only acked once it has succeeded or been re-pushed,
+// so a task is redelivered by the server when the worker stops before acking it.
+type TaskWorker struct {
+	actor    *Actor
+	taskName string
+	handler  TaskHandler
+	opts     taskWorkerOptions
+}
+
+// NewTaskWorker creates a worker for the named task
+func NewTaskWorker(actor *Actor, taskName string, handler TaskHandler, opts ...TaskWorkerOption) *TaskWorker {
+	options := taskWorkerOptions{
+		concurrency:  1,
+		timeout:      time.Minute,
+		attempts:     3,
+		deadLetter:   taskName + "_dead_letter",
+		minBackoff:   100 * time.Millisecond,
+		maxBackoff:   30 * time.Second,
+		drainTimeout: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &TaskWorker{actor: actor, taskName: taskName, handler: handler, opts: options}
+}
+
+// Run processes tasks until ctx is done, returning nil on a graceful shutdown.
+// On shutdown no new tasks are received, and running handlers are given the drain timeout to finish before their
+// context is cancelled. A stream error which cannot be retried, such as an authentication failure, stops every
+// handler and is returned.
+func (w *TaskWorker) Run(ctx context.Context) error {
+	if w.actor == nil || w.actor.connection == nil {
+		return errors.New("actor or connection is nil")
+	}
+	if w.handler == nil {
+		return errors.New("task handler is nil")
+	}
+
+	// stopped early when a stream cannot be retried
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	// handlers outlive ctx, so running tasks can finish and be acked during the drain
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-handlerCtx.Done():
+			return
+		}
+		select {
+		case <-time.After(w.opts.drainTimeout):
+			cancelHandlers()
+		case <-handlerCtx.Done():
+		}
+	}()
+
+	var streamErr error
+	var once sync.Once
+	wg := sync.WaitGroup{}
+	for i := 0; i < w.opts.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.work(ctx, handlerCtx); err != nil {
+				once.Do(func() {
+					streamErr = err
+					stop()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	return streamErr
+}
+
+// work processes tasks from a single stream, reconnecting until ctx is done or the stream cannot be retried
+func (w *TaskWorker) work(ctx, handlerCtx context.Context) error {
+	delay := w.opts.minBackoff
+	for ctx.Err() == nil {
+		received, err := w.stream(ctx, handlerCtx)
+		if err != nil && !retryableStreamError(err) {
+			return err
+		}
+		if err != nil && w.actor.connection.logger != nil {
+			w.actor.connection.logger.Warn("Task stream failed", zap.String("task", w.taskName), zap.Error(err))
+		}
+		if received {
+			delay = w.opts.minBackoff
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+		delay = min(delay*2, w.opts.maxBackoff)
+	}
+	return nil
+}
+
+// stream handles tasks from one task stream until it fails or ctx is done
+func (w *TaskWorker) stream(ctx, handlerCtx context.Context) (received bool, err error) {
+	// the stream is closed by the worker once ctx is done, so a running task can still be acked
+	streamCtx, cancelStream := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelStream()
+	streamCtx = metadata.AppendToOutgoingContext(w.actor.AuthorizeContext(streamCtx), "task_name", w.taskName)
+
+	busy := sync.Mutex{}
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// wait for the running task to be acked before closing the stream
+			busy.Lock()
+			cancelStream()
+			busy.Unlock()
+		case <-stopped:
+		}
+	}()
+
+	stream, err := w.actor.Connection().TaskStream(streamCtx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = stream.CloseSend() }()
+
+	for {
+		task, recvErr := stream.Recv()
+		if recvErr == io.EOF || ctx.Err() != nil {
+			return received, nil
+		}
+		if recvErr != nil {
+			return received, recvErr
+		}
+		received = true
+
+		busy.Lock()
+		if ctx.Err() != nil {
+			// the worker stopped before the task started, it is redelivered as it is not acked
+			busy.Unlock()
+			return received, nil
+		}
+		acked := w.process(handlerCtx, task)
+		sendErr := stream.Send(&proto.TaskAckRequest{TaskId: task.GetTaskId(), Acked: acked})
+		busy.Unlock()
+		if sendErr == io.EOF {
+			return received, nil
+		}
+		if sendErr != nil {
+			return received, sendErr
+		}
+	}
+}
+
+// process handles a task, re-pushing or dead lettering it on failure, and returns whether it can be acked
+func (w *TaskWorker) process(ctx context.Context, task *proto.TaskResponse) bool {
+	handleErr := w.call(ctx, task)
+	if handleErr == nil {
+		return true
+	}
+	if errors.Is(handleErr, errTaskAbandoned) {
+		// left unacked, so the server redelivers the task once the worker has stopped
+		return false
+	}
+
+	attempt := TaskAttempt(task)
+	data := maps.Clone(task.GetData())
+	if data == nil {
+		data = map[string]string{}
+	}
+	origin := TaskOriginID(task)
+	taskName, taskID := w.taskName, ""
+	if attempt < w.opts.attempts {
+		data[TaskAttemptKey] = strconv.Itoa(attempt + 1)
+		taskID = origin + ":attempt-" + strconv.Itoa(attempt+1)
+	} else {
+		taskName = w.opts.deadLetter
+		data[TaskErrorKey] = handleErr.Error()
+		taskID = origin + ":dead-letter"
+	}
+	if origin == "" {
+		// the server assigns IDs to tasks pushed without one
+		taskID = ""
+	} else {
+		data[TaskOriginKey] = origin
+	}
+
+	actor := w.actor
+	if ws := task.GetWs(); ws != "" && ws != actor.WorkspaceID() {
+		actor = actor.cloneForWorkspace(ws)
+	}
+
+	pushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if err := actor.TaskPush(pushCtx, taskName, taskID, data); err != nil {
+		// left unacked, so the server redelivers the task
+		return false
+	}
+	return true
+}
+
+// errTaskAbandoned is returned for a handler still running when the drain timeout is reached
+var errTaskAbandoned = errors.New("task handler abandoned at shutdown")
+
+// call runs the handler with the task timeout, returning panics as errors.
+// Once the timeout is reached the handler context is cancelled, and the task is failed once the handler returns, so a
+// task is never re-pushed while its handler is still running. A handler which ignores its context holds up the worker
+// until it returns, and is only abandoned when the drain timeout is reached at shutdown.
+func (w *TaskWorker) call(ctx context.Context, task *proto.TaskResponse) error {
+	callCtx := ctx
+	if w.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, w.opts.timeout)
+		defer cancel()
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- fmt.Errorf("task handler panic: %v", r)
+			}
+		}()
+		result <- w.handler(callCtx, task)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-callCtx.Done():
+	}
+
+	select {
+	case <-result:
+		return fmt.Errorf("task handler did not complete: %w", callCtx.Err())
+	case <-ctx.Done():
+		return errTaskAbandoned
+	}
+}
@@ -0,0 +1,354 @@
+package keystone
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testTaskQueue serves pushed tasks to task streams, one at a time per stream
+type testTaskQueue struct {
+	lock    sync.Mutex
+	tasks   map[string]chan *proto.TaskResponse
+	acked   []string
+	streams atomic.Int32
+}
+
+func (q *testTaskQueue) queue(taskName string) chan *proto.TaskResponse {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.tasks == nil {
+		q.tasks = map[string]chan *proto.TaskResponse{}
+	}
+	if q.tasks[taskName] == nil {
+		q.tasks[taskName] = make(chan *proto.TaskResponse, 100)
+	}
+	return q.tasks[taskName]
+}
+
+func (q *testTaskQueue) ackedTasks() []string {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return append([]string(nil), q.acked...)
+}
+
+func (q *testTaskQueue) install(mock *MockServer) {
+	mock.PushTaskFunc = func(_ context.Context, req *proto.PushTaskRequest) (*proto.GenericResponse, error) {
+		q.queue(req.GetTaskName()) <- &proto.TaskResponse{
+			TaskId: req.GetTaskId(),
+			Ws:     req.GetAuthorization().GetWorkspaceId(),
+			Data:   req.GetData(),
+		}
+		return &proto.GenericResponse{Success: true}, nil
+	}
+	mock.TaskStreamFunc = func(stream grpc.BidiStreamingServer[proto.TaskAckRequest, proto.TaskResponse]) error {
+		q.streams.Add(1)
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		tasks := q.queue(md.Get("task_name")[0])
+		for {
+			var task *proto.TaskResponse
+			select {
+			case task = <-tasks:
+			case <-stream.Context().Done():
+				return nil
+			}
+			if err := stream.Send(task); err != nil {
+				tasks <- task
+				return err
+			}
+			ack, err := stream.Recv()
+			if err != nil || !ack.GetAcked() {
+				tasks <- task
+				if err != nil {
+					return nil
+				}
+				continue
+			}
+			q.lock.Lock()
+			q.acked = append(q.acked, ack.GetTaskId())
+			q.lock.Unlock()
+		}
+	}
+}
+
+func runTaskWorker(worker *TaskWorker) (context.CancelFunc, chan error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- worker.Run(ctx) }()
+	return cancel, done
+}
+
+func TestTaskWorker_Concurrency(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	queue := &testTaskQueue{}
+	queue.install(mock)
+
+	for _, id := range []string{"t1", "t2", "t3", "t4"} {
+		if err := actor.TaskPush(context.Background(), "email", id, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var running, maxRunning atomic.Int32
+	worker := NewTaskWorker(actor, "email", func(ctx context.Context, task *proto.TaskResponse) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			current := maxRunning.Load()
+			if n <= current || maxRunning.CompareAndSwap(current, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}, WithTaskConcurrency(4))
+
+	cancel, done := runTaskWorker(worker)
+	waitFor(t, "tasks to be acked", func() bool { return len(queue.ackedTasks()) == 4 })
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if maxRunning.Load() < 2 {
+		t.Errorf("expected tasks to be handled concurrently, max running %d", maxRunning.Load())
+	}
+}
+
+func TestTaskWorker_RetriesAndDeadLetters(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	queue := &testTaskQueue{}
+	queue.install(mock)
+
+	_ = actor.TaskPush(context.Background(), "email", "flaky", map[string]string{"to": "a"})
+	_ = actor.TaskPush(context.Background(), "email", "panics", nil)
+	_ = actor.TaskPush(context.Background(), "email", "slow", nil)
+
+	var lock sync.Mutex
+	attempts := map[string][]int{}
+	worker := NewTaskWorker(actor, "email", func(ctx context.Context, task *proto.TaskResponse) error {
+		lock.Lock()
+		attempts[TaskOriginID(task)] = append(attempts[TaskOriginID(task)], TaskAttempt(task))
+		lock.Unlock()
+		switch TaskOriginID(task) {
+		case "flaky":
+			if TaskAttempt(task) < 2 {
+				return errors.New("temporary failure")
+			}
+		case "panics":
+			panic("handler bug")
+		case "slow":
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}, WithTaskAttempts(2), WithTaskTimeout(20*time.Millisecond), WithTaskConcurrency(3))
+
+	cancel, done := runTaskWorker(worker)
+	dead := queue.queue("email_dead_letter")
+	deadLettered := map[string]*proto.TaskResponse{}
+	for len(deadLettered) < 2 {
+		select {
+		case task := <-dead:
+			deadLettered[TaskOriginID(task)] = task
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for dead letters, got %v", deadLettered)
+		}
+	}
+	waitFor(t, "tasks to be acked", func() bool { return len(queue.ackedTasks()) == 5 })
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if got := attempts["flaky"]; len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("expected flaky to succeed on attempt 2, got %v", got)
+	}
+	if _, ok := deadLettered["flaky"]; ok {
+		t.Errorf("expected flaky not to be dead lettered")
+	}
+	if got := deadLettered["panics"].GetData()[TaskErrorKey]; got != "task handler panic: handler bug" {
+		t.Errorf("expected the panic to be recorded, got %q", got)
+	}
+	if got := deadLettered["slow"].GetData()[TaskErrorKey]; got == "" || len(attempts["slow"]) != 2 {
+		t.Errorf("expected slow to time out twice, got %q after %v", got, attempts["slow"])
+	}
+}
+
+func TestTaskWorker_TimeoutWaitsForHandler(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	queue := &testTaskQueue{}
+	queue.install(mock)
+
+	_ = actor.TaskPush(context.Background(), "email", "stubborn", nil)
+
+	var running, maxRunning, calls atomic.Int32
+	worker := NewTaskWorker(actor, "email", func(ctx context.Context, task *proto.TaskResponse) error {
+		calls.Add(1)
+		if n := running.Add(1); n > maxRunning.Load() {
+			maxRunning.Store(n)
+		}
+		defer running.Add(-1)
+		// ignores the context, so the retry must wait for it to return
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}, WithTaskAttempts(2), WithTaskTimeout(10*time.Millisecond), WithTaskConcurrency(2))
+
+	cancel, done := runTaskWorker(worker)
+	waitFor(t, "the task to be retried", func() bool { return calls.Load() == 2 && running.Load() == 0 })
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if maxRunning.Load() != 1 {
+		t.Errorf("expected the retry to wait for the timed out handler, got %d running at once", maxRunning.Load())
+	}
+}
+
+func TestTaskWorker_StopsOnPermanentStreamError(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+
+	var streams atomic.Int32
+	mock.TaskStreamFunc = func(stream grpc.BidiStreamingServer[proto.TaskAckRequest, proto.TaskResponse]) error {
+		streams.Add(1)
+		return status.Error(codes.PermissionDenied, "not allowed")
+	}
+
+	worker := NewTaskWorker(actor, "email", func(ctx context.Context, task *proto.TaskResponse) error { return nil },
+		WithTaskConcurrency(3), WithTaskReconnectBackoff(time.Millisecond, time.Millisecond))
+
+	done := make(chan error)
+	go func() { done <- worker.Run(context.Background()) }()
+	select {
+	case err := <-done:
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected the permission error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the worker to stop")
+	}
+	if streams.Load() > 3 {
+		t.Errorf("expected the stream not to be retried, got %d streams", streams.Load())
+	}
+}
+
+func TestTaskWorker_RepushKeepsWorkspace(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	queue := &testTaskQueue{}
+	queue.install(mock)
+
+	// the task was pushed by another workspace, and is delivered to a worker across all workspaces
+	_ = actor.cloneForWorkspace("workspace-2").TaskPush(context.Background(), "email", "t1", nil)
+	pushed := make(chan *proto.PushTaskRequest, 10)
+	push := mock.PushTaskFunc
+	mock.PushTaskFunc = func(ctx context.Context, req *proto.PushTaskRequest) (*proto.GenericResponse, error) {
+		pushed <- req
+		return push(ctx, req)
+	}
+
+	worker := NewTaskWorker(actor.CloneWithoutWorkspace(), "email", func(ctx context.Context, task *proto.TaskResponse) error {
+		return errors.New("always fails")
+	}, WithTaskAttempts(2))
+
+	cancel, done := runTaskWorker(worker)
+	var requests []*proto.PushTaskRequest
+	for len(requests) < 2 {
+		select {
+		case req := <-pushed:
+			requests = append(requests, req)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for re-pushed tasks, got %d", len(requests))
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	wantIDs := []string{"t1:attempt-2", "t1:dead-letter"}
+	for i, req := range requests {
+		if req.GetAuthorization().GetWorkspaceId() != "workspace-2" {
+			t.Errorf("expected the task to be re-pushed to workspace-2, got %q", req.GetAuthorization().GetWorkspaceId())
+		}
+		if req.GetTaskId() != wantIDs[i] {
+			t.Errorf("expected task ID %s, got %s", wantIDs[i], req.GetTaskId())
+		}
+		if req.GetData()[TaskOriginKey] != "t1" {
+			t.Errorf("expected the origin task ID to be kept, got %q", req.GetData()[TaskOriginKey])
+		}
+	}
+}
+
+func TestTaskWorker_Reconnects(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	queue := &testTaskQueue{}
+	queue.install(mock)
+	serve := mock.TaskStreamFunc
+	mock.TaskStreamFunc = func(stream grpc.BidiStreamingServer[proto.TaskAckRequest, proto.TaskResponse]) error {
+		if queue.streams.Load() == 0 {
+			queue.streams.Add(1)
+			return errors.New("stream unavailable")
+		}
+		return serve(stream)
+	}
+
+	_ = actor.TaskPush(context.Background(), "email", "t1", nil)
+	worker := NewTaskWorker(actor, "email", func(ctx context.Context, task *proto.TaskResponse) error { return nil },
+		WithTaskReconnectBackoff(time.Millisecond, time.Millisecond))
+
+	cancel, done := runTaskWorker(worker)
+	waitFor(t, "the task to be acked", func() bool { return len(queue.ackedTasks()) == 1 })
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if queue.streams.Load() < 2 {
+		t.Errorf("expected the stream to be reconnected")
+	}
+}
+
+func TestTaskWorker_Drain(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	queue := &testTaskQueue{}
+	queue.install(mock)
+
+	_ = actor.TaskPush(context.Background(), "email", "t1", nil)
+	started := make(chan struct{})
+	var handlerErr atomic.Value
+	worker := NewTaskWorker(actor, "email", func(ctx context.Context, task *proto.TaskResponse) error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		if ctx.Err() != nil {
+			handlerErr.Store(ctx.Err())
+		}
+		return nil
+	})
+
+	cancel, done := runTaskWorker(worker)
+	<-started
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if handlerErr.Load() != nil {
+		t.Errorf("expected the handler context to outlive the shutdown, got %v", handlerErr.Load())
+	}
+	waitFor(t, "the running task to be acked during the drain", func() bool { return len(queue.ackedTasks()) == 1 })
+}
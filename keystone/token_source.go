@@ -0,0 +1,203 @@
+package keystone
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/grpc/metadata"
+)
+
+var ErrEmptyToken = errors.New("token source returned an empty token")
+
+// Token is an access token, with an optional expiry
+type Token struct {
+	AccessToken string    `json:"access_token"`
+	Expiry      time.Time `json:"expiry"`
+}
+
+// Valid returns true if the token is set and will not expire within leeway
+func (t *Token) Valid(leeway time.Duration) bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Add(leeway).Before(t.Expiry))
+}
+
+// TokenSource provides the access token for each call made through a Connection
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+type staticTokenSource struct{ token *Token }
+
+func (s staticTokenSource) Token(context.Context) (*Token, error) { return s.token, nil }
+
+// StaticTokenSource always returns the same access token
+func StaticTokenSource(accessToken string) TokenSource {
+	return staticTokenSource{token: &Token{AccessToken: accessToken}}
+}
+
+type cachedTokenSource struct {
+	source        TokenSource
+	refreshBefore time.Duration
+	lock          sync.Mutex
+	token         *Token
+}
+
+// CachedTokenSource caches tokens from source, refreshing them refreshBefore their expiry
+func CachedTokenSource(source TokenSource, refreshBefore time.Duration) TokenSource {
+	return &cachedTokenSource{source: source, refreshBefore: refreshBefore}
+}
+
+func (s *cachedTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.token.Valid(s.refreshBefore) {
+		return s.token, nil
+	}
+
+	token, err := s.source.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.token = token
+	return token, nil
+}
+
+type fileTokenSource struct {
+	path          string
+	checkInterval time.Duration
+	lock          sync.Mutex
+	checked       time.Time
+	modTime       time.Time
+	size          int64
+	token         *Token
+}
+
+// FileTokenSource reads the access token from path, re-reading the file whenever it changes.
+// While the token is valid the file is checked for changes at most every 5 seconds, once the token has expired it
+// is checked on every call. The file may contain the raw token, or a JSON Token with access_token and expiry.
+func FileTokenSource(path string) TokenSource {
+	return &fileTokenSource{path: path, checkInterval: 5 * time.Second}
+}
+
+func (s *fileTokenSource) Token(context.Context) (*Token, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.token.Valid(0) && time.Since(s.checked) < s.checkInterval {
+		return s.token, nil
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, err
+	}
+	s.checked = time.Now()
+	if s.token != nil && info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return s.token, nil
+	}
+
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	content := strings.TrimSpace(string(raw))
+	token := &Token{AccessToken: content}
+	if strings.HasPrefix(content, "{") {
+		token = &Token{}
+		if err = json.Unmarshal([]byte(content), token); err != nil {
+			return nil, err
+		}
+	}
+
+	s.token, s.modTime, s.size = token, info.ModTime(), info.Size()
+	return token, nil
+}
+
+// WithTokenSource uses source to provide the access token for each call
+func WithTokenSource(source TokenSource) ConnOption {
+	return func(o *connOptions) {
+		o.tokenSource = source
+	}
+}
+
+// SetTokenSource uses source to provide the access token for each call, replacing the connection access token
+func (c *Connection) SetTokenSource(source TokenSource) {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+	c.tokenSource = source
+}
+
+// Token returns the current access token, refreshing it from the token source when one is set
+func (c *Connection) Token(ctx context.Context) (string, error) {
+	c.tokenLock.RLock()
+	source := c.tokenSource
+	c.tokenLock.RUnlock()
+
+	if source == nil {
+		return c.accessToken(), nil
+	}
+
+	token, err := source.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	if token == nil || token.AccessToken == "" {
+		return "", ErrEmptyToken
+	}
+
+	c.tokenLock.Lock()
+	c.token = token.AccessToken
+	c.tokenLock.Unlock()
+	return token.AccessToken, nil
+}
+
+// accessToken returns the most recent access token, without refreshing it
+func (c *Connection) accessToken() string {
+	c.tokenLock.RLock()
+	defer c.tokenLock.RUnlock()
+	return c.token
+}
+
+type authorizedRequest interface {
+	GetAuthorization() *proto.Authorization
+}
+
+// authorizeCall sets the current access token on the request authorization and outgoing token metadata
+func (c *Connection) authorizeCall(ctx context.Context, request any) (context.Context, error) {
+	c.tokenLock.RLock()
+	hasSource := c.tokenSource != nil
+	c.tokenLock.RUnlock()
+	if !hasSource {
+		return ctx, nil
+	}
+
+	token, err := c.Token(ctx)
+	if err != nil {
+		return ctx, err
+	}
+
+	switch req := request.(type) {
+	case *proto.Authorization:
+		if req != nil {
+			req.Token = token
+		}
+	case authorizedRequest:
+		if auth := req.GetAuthorization(); auth != nil {
+			auth.Token = token
+		}
+	}
+
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get("token")) > 0 {
+		md = md.Copy()
+		md.Set("token", token)
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+	return ctx, nil
+}
@@ -0,0 +1,195 @@
+package keystone
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keystonedb/sdk-go/proto"
+	"google.golang.org/grpc/metadata"
+)
+
+type countingTokenSource struct {
+	calls  int
+	expiry time.Duration
+	err    error
+}
+
+func (s *countingTokenSource) Token(context.Context) (*Token, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.calls++
+	return &Token{AccessToken: "token-" + string(rune('0'+s.calls)), Expiry: time.Now().Add(s.expiry)}, nil
+}
+
+func TestCachedTokenSource(t *testing.T) {
+	src := &countingTokenSource{expiry: time.Hour}
+	cached := CachedTokenSource(src, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		tok, err := cached.Token(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if tok.AccessToken != "token-1" {
+			t.Errorf("expected cached token, got %s", tok.AccessToken)
+		}
+	}
+	if src.calls != 1 {
+		t.Errorf("expected 1 fetch, got %d", src.calls)
+	}
+}
+
+func TestCachedTokenSource_RefreshesBeforeExpiry(t *testing.T) {
+	src := &countingTokenSource{expiry: 30 * time.Second}
+	cached := CachedTokenSource(src, time.Minute)
+
+	_, _ = cached.Token(context.Background())
+	tok, err := cached.Token(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tok.AccessToken != "token-2" || src.calls != 2 {
+		t.Errorf("expected refreshed token, got %s after %d calls", tok.AccessToken, src.calls)
+	}
+}
+
+func TestFileTokenSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("first\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	src := FileTokenSource(path)
+	src.(*fileTokenSource).checkInterval = 0
+	tok, err := src.Token(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tok.AccessToken != "first" {
+		t.Errorf("expected first, got %q", tok.AccessToken)
+	}
+
+	expiry := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if err = os.WriteFile(path, []byte(`{"access_token":"second-token","expiry":"`+expiry.Format(time.RFC3339)+`"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tok, err = src.Token(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tok.AccessToken != "second-token" || !tok.Expiry.Equal(expiry) {
+		t.Errorf("expected reloaded json token, got %+v", tok)
+	}
+}
+
+func TestFileTokenSource_CheckInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("first"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	src := FileTokenSource(path)
+	if _, err := src.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// a valid token is returned without checking the file again until the interval has passed
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if tok, err := src.Token(context.Background()); err != nil || tok.AccessToken != "first" {
+		t.Errorf("expected the cached token, got %+v %v", tok, err)
+	}
+
+	// an expired token is checked on every call
+	expired := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	if err := os.WriteFile(path, []byte(`{"access_token":"old","expiry":"`+expired+`"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	src = FileTokenSource(path)
+	if _, err := src.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("refreshed-token"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if tok, err := src.Token(context.Background()); err != nil || tok.AccessToken != "refreshed-token" {
+		t.Errorf("expected the expired token to be re-read, got %+v %v", tok, err)
+	}
+}
+
+func TestFileTokenSource_Missing(t *testing.T) {
+	if _, err := FileTokenSource(filepath.Join(t.TempDir(), "missing")).Token(context.Background()); err == nil {
+		t.Error("expected error for missing token file")
+	}
+}
+
+func TestConnectionTokenSource_PerCall(t *testing.T) {
+	testActor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	conn := testActor.connection
+
+	var seen []string
+	var seenMeta []string
+	mock.StatusFunc = func(ctx context.Context, req *proto.Authorization) (*proto.StatusResponse, error) {
+		seen = append(seen, req.GetToken())
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			seenMeta = append(seenMeta, md.Get("token")...)
+		}
+		return &proto.StatusResponse{}, nil
+	}
+
+	src := &countingTokenSource{expiry: -time.Second}
+	conn.SetTokenSource(src)
+	actor := conn.Actor("ws", "127.0.0.1", "user", "agent")
+
+	for i := 0; i < 2; i++ {
+		if _, err := conn.Status(actor.AuthorizeContext(context.Background()), conn.authorization()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(seen) != 2 || seen[0] != "token-1" || seen[1] != "token-2" {
+		t.Errorf("expected refreshed token per call, got %v", seen)
+	}
+	if len(seenMeta) != 2 || seenMeta[1] != "token-2" {
+		t.Errorf("expected refreshed token metadata, got %v", seenMeta)
+	}
+	if got := actor.Authorization().GetToken(); got != "token-2" {
+		t.Errorf("expected actor authorization to use latest token, got %s", got)
+	}
+}
+
+func TestConnectionTokenSource_Error(t *testing.T) {
+	actor, mock, cleanup := newQueryIndexTestActor(t)
+	defer cleanup()
+	conn := actor.connection
+
+	called := false
+	mock.StatusFunc = func(context.Context, *proto.Authorization) (*proto.StatusResponse, error) {
+		called = true
+		return &proto.StatusResponse{}, nil
+	}
+
+	fetchErr := errors.New("sidecar unavailable")
+	conn.SetTokenSource(&countingTokenSource{err: fetchErr})
+	if _, err := conn.Status(context.Background(), conn.authorization()); !errors.Is(err, fetchErr) {
+		t.Errorf("expected token source error, got %v", err)
+	}
+	if called {
+		t.Error("expected call not to reach the server")
+	}
+}
+
+func TestStaticTokenSource(t *testing.T) {
+	conn := NewConnection(nil, "", "", "")
+	conn.SetTokenSource(StaticTokenSource("static"))
+	tok, err := conn.Token(context.Background())
+	if err != nil || tok != "static" {
+		t.Errorf("expected static token, got %q %v", tok, err)
+	}
+}
@@ -227,7 +227,7 @@ func UnmarshalProperties(data map[Property]*proto.Value, v interface{}) error {
 		}
 
 		currentVal := val.FieldByIndex(field.Index)
-		ref := GetReflector(field.Type, currentVal)
+		ref := fieldReflector(field, currentVal)
 		if ref != nil {
 			if err := ref.SetValue(toHydrate, currentVal); err != nil {
 				return err
@@ -0,0 +1,169 @@
+package keystone
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/keystonedb/sdk-go/keystone/reflector"
+)
+
+// Issue is a problem with the keystone definition of a struct field
+type Issue struct {
+	Field    string // Go field path e.g. Address.Line1
+	Property string // keystone property name, empty if the field is not stored
+	Message  string
+}
+
+func (i Issue) String() string {
+	if i.Property == "" {
+		return i.Field + ": " + i.Message
+	}
+	return fmt.Sprintf("%s (%s): %s", i.Field, i.Property, i.Message)
+}
+
+// conflictingOptions are tag options which cannot be combined, as one is dropped or the combination cannot be stored
+var conflictingOptions = []struct {
+	a, b    string
+	message string
+}{
+	{"verify", "immutable", "verify values cannot be read, so immutable is not applied"},
+	{"verify", "indexed", "verify values cannot be read, so cannot be indexed"},
+	{"verify", "searchable", "verify values cannot be read, so cannot be searched"},
+	{"verify", "lookup", "verify values cannot be read, so cannot be used for lookups"},
+	{"verify", "metric", "verify values cannot be read, so cannot be reported as a metric"},
+	{"verify", "pii", "pii replaces the verify data type"},
+	{"verify", "user", "user replaces the verify data type"},
+	{"verify", "json", "verify values cannot be read, so cannot be decoded from JSON"},
+	{"pii", "user", "pii replaces the user input type"},
+	{"required", "deprecated", "a deprecated property should not be required"},
+}
+
+// ValidateTag checks a keystone struct tag for unknown and conflicting options
+func ValidateTag(fieldName, tag string) []Issue {
+	opt := parseFieldTag(fieldName, tag)
+	issue := func(format string, args ...interface{}) Issue {
+		return Issue{Field: fieldName, Property: opt.name, Message: fmt.Sprintf(format, args...)}
+	}
+
+	var issues []Issue
+	if opt.ignoredWithOptions {
+		issues = append(issues, issue("options are not applied to a field ignored with \"-\""))
+	}
+	for _, unknown := range opt.unknown {
+		issues = append(issues, issue("unknown tag option %q", unknown))
+	}
+
+	name := strings.TrimSpace(strings.Split(tag, ",")[0])
+	if name != "" && name != "-" && name != opt.name {
+		issues = append(issues, issue("property name %q is stored as %q", name, opt.name))
+	}
+
+	set := opt.tagOptions()
+	for _, conflict := range conflictingOptions {
+		if set[conflict.a] && set[conflict.b] {
+			issues = append(issues, issue("%s and %s conflict: %s", conflict.a, conflict.b, conflict.message))
+		}
+	}
+	return issues
+}
+
+// tagOptions returns the canonical names of the options set
+func (fOpt fieldOptions) tagOptions() map[string]bool {
+	return map[string]bool{
+		"unique":     fOpt.unique,
+		"primary":    fOpt.primary,
+		"indexed":    fOpt.indexed,
+		"searchable": fOpt.searchable,
+		"immutable":  fOpt.immutable,
+		"deprecated": fOpt.deprecated,
+		"required":   fOpt.required,
+		"lookup":     fOpt.reverseLookup,
+		"verify":     fOpt.verifyOnly,
+		"metric":     fOpt.metric || fOpt.metricFilter,
+		"pii":        fOpt.personalData,
+		"user":       fOpt.userInputData,
+		"json":       fOpt.json,
+	}
+}
+
+// ValidateType checks the keystone definition of v, returning any tag options which are unknown or conflict,
+// property names which collide once normalized, and fields which cannot be stored
+func ValidateType(v any) []Issue {
+	if v == nil {
+		return []Issue{{Message: CannotMapNil.Error()}}
+	}
+	t := reflector.Deref(reflect.ValueOf(v)).Type()
+	if t.Kind() != reflect.Struct {
+		return []Issue{{Field: t.String(), Message: CannotMapPrimitives.Error()}}
+	}
+
+	var issues []Issue
+	seen := map[string]string{}
+	validateFields(t, "", "", seen, map[reflect.Type]bool{}, &issues)
+	return issues
+}
+
+// validateFields checks the fields of t, visiting holds the struct types being validated to detect recursion
+func validateFields(t reflect.Type, fieldPrefix, propertyPrefix string, seen map[string]string, visiting map[reflect.Type]bool, issues *[]Issue) {
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for _, field := range reflect.VisibleFields(t) {
+		if field.Anonymous || !field.IsExported() {
+			continue
+		}
+
+		fieldPath := fieldPrefix + field.Name
+		for _, issue := range ValidateTag(field.Name, field.Tag.Get("keystone")) {
+			issue.Field = fieldPath
+			if issue.Property != "" {
+				prop := knownPrefixProperty(propertyPrefix, issue.Property)
+				issue.Property = prop.Name()
+			}
+			*issues = append(*issues, issue)
+		}
+
+		prop, _ := ReflectProperty(field, propertyPrefix)
+		if prop.name == "" || prop.HydrateOnly() {
+			continue
+		}
+
+		if existing, ok := seen[prop.Name()]; ok {
+			*issues = append(*issues, Issue{Field: fieldPath, Property: prop.Name(),
+				Message: fmt.Sprintf("property name collides with %s", existing)})
+		} else {
+			seen[prop.Name()] = fieldPath
+		}
+
+		if getFieldOptions(field).json && !jsonEncodable(field.Type) {
+			*issues = append(*issues, Issue{Field: fieldPath, Property: prop.Name(),
+				Message: fmt.Sprintf("%s cannot be encoded as JSON, the field will not be stored", field.Type)})
+			continue
+		}
+		if fieldReflector(field, reflect.Value{}) != nil {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		switch {
+		case fieldType.Kind() == reflect.Struct && visiting[fieldType]:
+			*issues = append(*issues, Issue{Field: fieldPath, Property: prop.Name(),
+				Message: fmt.Sprintf("recursive type %s cannot be stored as nested properties", field.Type)})
+		case fieldType.Kind() == reflect.Struct:
+			validateFields(fieldType, fieldPath+".", prop.Name(), seen, visiting, issues)
+		case fieldType.Kind() == reflect.Slice && reflect.PointerTo(fieldType.Elem()).Implements(NestedChildType):
+			// nested children are stored separately to the entity properties
+		case (fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array || fieldType.Kind() == reflect.Map) &&
+			jsonEncodable(fieldType):
+			*issues = append(*issues, Issue{Field: fieldPath, Property: prop.Name(),
+				Message: fmt.Sprintf("unsupported field type %s, the field will not be stored unless tagged with the json option", field.Type)})
+		default:
+			*issues = append(*issues, Issue{Field: fieldPath, Property: prop.Name(),
+				Message: fmt.Sprintf("unsupported field type %s, the field will not be stored", field.Type)})
+		}
+	}
+}
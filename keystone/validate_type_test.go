@@ -0,0 +1,136 @@
+package keystone
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type validateAddress struct {
+	Line1 string
+	Zip   string `keystone:"line1"`
+}
+
+type validateChild struct{ BaseChildEntity }
+
+func (c *validateChild) ChildID() string      { return "" }
+func (c *validateChild) SetChildID(id string) {}
+
+type validateEntity struct {
+	BaseEntity
+	Email    string `keystone:",uniqe"`
+	Password string `keystone:",verify,immutable"`
+	UserID   string
+	UserId   string
+	Name     string `keystone:"Name"`
+	Balance  Amount
+	Joined   time.Time
+	Address  validateAddress
+	Children []validateChild
+	Updates  chan string
+	Ignored  chan string `keystone:"-"`
+	Skipped  string      `keystone:"-,indexed"`
+	Checksum [16]byte
+	Channels map[string]chan int `keystone:",json"`
+}
+
+type validEntity struct {
+	BaseEntity
+	Name    string `keystone:",indexed"`
+	Email   string `keystone:",unique,pii"`
+	Tags    StringSet
+	Created time.Time
+	Address validateAddress `keystone:"-"`
+
+	Addresses []validateAddress `keystone:",json"`
+	Scores    []float64
+	Home      validateAddress `keystone:",json"`
+}
+
+func TestValidateType(t *testing.T) {
+	issues := ValidateType(&validateEntity{})
+
+	want := []string{
+		`Email (email): unknown tag option "uniqe"`,
+		`Password (password): verify and immutable conflict`,
+		`UserId (user_id): property name collides with UserID`,
+		`Name (name): property name "Name" is stored as "name"`,
+		`Address.Zip (address.line1): property name collides with Address.Line1`,
+		`Updates (updates): unsupported field type chan string`,
+		`Skipped: options are not applied to a field ignored with "-"`,
+		`Checksum (checksum): unsupported field type [16]uint8, the field will not be stored unless tagged with the json option`,
+		`Channels (channels): map[string]chan int cannot be encoded as JSON`,
+	}
+	if len(issues) != len(want) {
+		t.Errorf("expected %d issues, got %d: %v", len(want), len(issues), issues)
+	}
+	for _, w := range want {
+		found := false
+		for _, issue := range issues {
+			if strings.HasPrefix(issue.String(), w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected issue %q, got %v", w, issues)
+		}
+	}
+}
+
+func TestValidateType_Valid(t *testing.T) {
+	if issues := ValidateType(validEntity{}); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+	if issues := ValidateType("text"); len(issues) != 1 {
+		t.Errorf("expected a primitive to be an issue, got %v", issues)
+	}
+}
+
+type validateNode struct {
+	Name string `keystone:",indexed"`
+	Next *validateNode
+	Tree struct {
+		Left *validateNode
+	}
+}
+
+func TestValidateType_Recursive(t *testing.T) {
+	issues := ValidateType(validateNode{})
+	want := []string{
+		"Next (next): recursive type *keystone.validateNode cannot be stored as nested properties",
+		"Tree.Left (tree.left): recursive type *keystone.validateNode cannot be stored as nested properties",
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %v", len(want), issues)
+	}
+	for i, w := range want {
+		if issues[i].String() != w {
+			t.Errorf("expected %q, got %q", w, issues[i])
+		}
+	}
+}
+
+func TestValidateTag(t *testing.T) {
+	tests := []struct {
+		tag    string
+		issues int
+	}{
+		{"", 0},
+		{"name,unique,indexed,view=summary", 0},
+		{",unique,", 0},
+		{",uniqe", 1},
+		{",verify,indexed,searchable", 2},
+		{",pii,user", 1},
+		{",required,deprecated", 1},
+		{",json", 0},
+		{",verify,json", 1},
+		{"-", 0},
+		{"-,unique", 1},
+	}
+	for _, test := range tests {
+		if issues := ValidateTag("Field", test.tag); len(issues) != test.issues {
+			t.Errorf("ValidateTag(%q) = %v; want %d issues", test.tag, issues, test.issues)
+		}
+	}
+}
@@ -1,10 +1,11 @@
 package main
 
 import (
+	"flag"
 	"log"
-	"time"
+	"os"
 
-	"github.com/keystonedb/sdk-go/test/requirements"
+	"github.com/keystonedb/sdk-go/test/suite"
 	"github.com/packaged/environment/environment"
 	"github.com/packaged/logger/v3/logger"
 )
@@ -19,56 +20,58 @@ func init() {
 }
 
 func main() {
-	totalRun := 0
-	totalFailures := 0
-	var failedRequirements []string
+	cfg := suite.ConfigFromEnv()
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = suite.DefaultEndpoint
+	}
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
 
-	accessToken := "test-access-token"
+	os.Exit(run(cfg))
+}
 
-	kHost := "127.0.0.1"
-	kPort := "50051"
-	InitKeystone(kHost, kPort, accessToken)
-	defer CloseKeystone()
+func run(cfg suite.Config) int {
+	reqs, err := cfg.Select(suite.All())
+	if err != nil {
+		log.Println(Cross, err)
+		return 2
+	}
 
-	actor := Actor()
+	conn, err := cfg.Connect()
+	if err != nil {
+		log.Println(Cross, "did not connect:", err)
+		return 2
+	}
+	defer conn.Close()
 
-	startTime := time.Now()
-	log.Println("Running Requirements Test - Against " + kHost + ":" + kPort)
-	for _, req := range reqs {
-		totalRun++
-		log.Println("")
-		log.Println("Verifying", req.Name())
-		regErr := req.Register(keystoneConnection)
-		if regErr != nil {
-			failedRequirements = append(failedRequirements, req.Name()+" : Registration")
-			log.Println(Cross, " Registration Failed", regErr.Error())
-			totalFailures++
-			continue
+	log.Println("Running Requirements Test - Against " + cfg.Endpoint)
+	report := suite.Run(conn, reqs, cfg.Parallel, func(result suite.Result) {
+		if result.Error != nil {
+			log.Println(Cross, result.Requirement, "-", result.Name, "-", result.Error.Error())
+		} else {
+			log.Println(Check, result.Requirement, "-", result.Name)
 		}
-		keystoneConnection.SyncSchema().Wait()
-
-		reqName := req.Name()
-		req.Verify(actor, func(result requirements.TestResult) {
-			if result.Error != nil {
-				log.Println(Cross, result.Name, "-", result.Error.Error())
-				failedRequirements = append(failedRequirements, reqName+" - "+result.Name)
-				totalFailures++
-			} else {
-				log.Println(Check, result.Name)
-			}
-		})
-	}
+	})
 
+	failures := report.Failures()
 	log.Println("")
-	log.Printf("Total requirements run: %d", totalRun)
-	log.Printf("Total requirements failed: %d", totalFailures)
-	log.Printf("Total Duration: %v", time.Since(startTime))
-	if totalFailures > 0 {
+	log.Printf("Total requirements run: %d", len(reqs))
+	log.Printf("Total checks failed: %d", len(failures))
+	log.Printf("Total Duration: %v", report.Duration)
+	if len(failures) > 0 {
 		log.Println("Failed requirements:")
-		for _, req := range failedRequirements {
-			log.Println("-", req)
+		for _, res := range failures {
+			log.Println("-", res.Requirement, "-", res.Name)
 		}
 	}
 	log.Println("")
 
+	if err = report.WriteFiles(cfg); err != nil {
+		log.Println(Cross, "unable to write report:", err)
+		return 2
+	}
+	if len(failures) > 0 {
+		return 1
+	}
+	return 0
 }
@@ -0,0 +1,161 @@
+package suite
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"os"
+	"time"
+)
+
+// Result is the outcome of a single requirement check
+type Result struct {
+	Requirement string
+	Name        string
+	Error       error
+	Duration    time.Duration
+}
+
+// Report holds every result from a run, in the order they were produced
+type Report struct {
+	Started  time.Time
+	Duration time.Duration
+	Results  []Result
+}
+
+// Failures returns the results with an error
+func (r *Report) Failures() []Result {
+	var failed []Result
+	for _, res := range r.Results {
+		if res.Error != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// Requirements returns the requirement names in the order they first reported
+func (r *Report) Requirements() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, res := range r.Results {
+		if !seen[res.Requirement] {
+			seen[res.Requirement] = true
+			names = append(names, res.Requirement)
+		}
+	}
+	return names
+}
+
+type jsonResult struct {
+	Requirement string  `json:"requirement"`
+	Name        string  `json:"name"`
+	Passed      bool    `json:"passed"`
+	Error       string  `json:"error,omitempty"`
+	Seconds     float64 `json:"seconds"`
+}
+
+type jsonReport struct {
+	Started time.Time    `json:"started"`
+	Seconds float64      `json:"seconds"`
+	Total   int          `json:"total"`
+	Failed  int          `json:"failed"`
+	Results []jsonResult `json:"results"`
+}
+
+// WriteJSON writes the report as JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	out := jsonReport{Started: r.Started, Seconds: r.Duration.Seconds(), Total: len(r.Results), Failed: len(r.Failures())}
+	for _, res := range r.Results {
+		jr := jsonResult{Requirement: res.Requirement, Name: res.Name, Passed: res.Error == nil, Seconds: res.Duration.Seconds()}
+		if res.Error != nil {
+			jr.Error = res.Error.Error()
+		}
+		out.Results = append(out.Results, jr)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     float64      `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     float64     `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the report as JUnit XML, with a test suite per requirement
+func (r *Report) WriteJUnit(w io.Writer) error {
+	out := junitSuites{Tests: len(r.Results), Failures: len(r.Failures()), Time: r.Duration.Seconds()}
+	suites := map[string]*junitSuite{}
+	for _, name := range r.Requirements() {
+		suites[name] = &junitSuite{Name: name}
+	}
+	for _, res := range r.Results {
+		s := suites[res.Requirement]
+		tc := junitCase{Name: res.Name, ClassName: res.Requirement, Time: res.Duration.Seconds()}
+		if res.Error != nil {
+			tc.Failure = &junitFailure{Message: res.Error.Error()}
+			s.Failures++
+		}
+		s.Tests++
+		s.Time += tc.Time
+		s.Cases = append(s.Cases, tc)
+	}
+	for _, name := range r.Requirements() {
+		out.Suites = append(out.Suites, *suites[name])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(out)
+}
+
+// WriteFiles writes the JUnit and JSON reports to the paths in cfg, when set
+func (r *Report) WriteFiles(cfg Config) error {
+	if cfg.JUnit != "" {
+		if err := writeFile(cfg.JUnit, r.WriteJUnit); err != nil {
+			return err
+		}
+	}
+	if cfg.JSON != "" {
+		return writeFile(cfg.JSON, r.WriteJSON)
+	}
+	return nil
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
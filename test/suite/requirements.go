@@ -1,4 +1,4 @@
-package main
+package suite
 
 import (
 	"github.com/keystonedb/sdk-go/test/requirements"
@@ -55,9 +55,9 @@ import (
 	"github.com/keystonedb/sdk-go/test/requirements/watcher"
 )
 
-var reqs []requirements.Requirement
-
-func init() {
+// All returns every requirement in the suite, in the order they are run
+func All() []requirements.Requirement {
+	var reqs []requirements.Requirement
 	//reqs = append(reqs, &requirements.DummyRequirement{})
 	reqs = append(reqs, &akv.Requirement{})
 	reqs = append(reqs, &cru.Requirement{})
@@ -112,11 +112,5 @@ func init() {
 	reqs = append(reqs, &enums.Requirement{})
 	reqs = append(reqs, &akv_timeline.Requirement{})
 	//reqs = append(reqs, &relay.Requirement{})
-
-	if false {
-		reqs = []requirements.Requirement{}
-		reqs = append(reqs, &query.Requirement{})
-		//reqs = append(reqs, &akv_timeline.Requirement{})
-		//reqs = append(reqs, &tasks.Requirement{})
-	}
+	return reqs
 }
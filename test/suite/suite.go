@@ -0,0 +1,176 @@
+// Package suite runs the keystone requirements against a keystone server,
+// either from the requirements command or as a go test.
+package suite
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/keystonedb/sdk-go/keystone"
+	"github.com/keystonedb/sdk-go/test/requirements"
+)
+
+const (
+	DefaultEndpoint = "127.0.0.1:50051"
+	DefaultToken    = "test-access-token"
+	DefaultVendorID = "testing"
+	DefaultAppID    = "tester"
+)
+
+// Config configures which requirements are run, and where
+type Config struct {
+	Endpoint string
+	Token    string
+	VendorID string
+	AppID    string
+	Run      string // regular expression matching requirement names to run
+	Skip     string // regular expression matching requirement names to skip
+	Parallel int
+	JUnit    string // path to write a JUnit XML report
+	JSON     string // path to write a JSON report
+}
+
+// ConfigFromEnv reads the config from KEYSTONE_* environment variables
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Endpoint: os.Getenv("KEYSTONE_ENDPOINT"),
+		Token:    envOr("KEYSTONE_TOKEN", DefaultToken),
+		VendorID: envOr("KEYSTONE_VENDOR_ID", DefaultVendorID),
+		AppID:    envOr("KEYSTONE_APP_ID", DefaultAppID),
+		Run:      os.Getenv("KEYSTONE_RUN"),
+		Skip:     os.Getenv("KEYSTONE_SKIP"),
+		Parallel: 1,
+		JUnit:    os.Getenv("KEYSTONE_JUNIT"),
+		JSON:     os.Getenv("KEYSTONE_JSON"),
+	}
+	if p, err := strconv.Atoi(os.Getenv("KEYSTONE_PARALLEL")); err == nil && p > 0 {
+		cfg.Parallel = p
+	}
+	return cfg
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// RegisterFlags registers keystone.* flags on fs, defaulting to the current config values
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Endpoint, "keystone.endpoint", c.Endpoint, "keystone server address (env KEYSTONE_ENDPOINT)")
+	fs.StringVar(&c.Token, "keystone.token", c.Token, "access token (env KEYSTONE_TOKEN)")
+	fs.StringVar(&c.VendorID, "keystone.vendor", c.VendorID, "vendor ID (env KEYSTONE_VENDOR_ID)")
+	fs.StringVar(&c.AppID, "keystone.app", c.AppID, "app ID (env KEYSTONE_APP_ID)")
+	fs.StringVar(&c.Run, "keystone.run", c.Run, "only run requirements matching this regular expression (env KEYSTONE_RUN)")
+	fs.StringVar(&c.Skip, "keystone.skip", c.Skip, "skip requirements matching this regular expression (env KEYSTONE_SKIP)")
+	fs.IntVar(&c.Parallel, "keystone.parallel", c.Parallel, "number of requirements to verify in parallel (env KEYSTONE_PARALLEL)")
+	fs.StringVar(&c.JUnit, "keystone.junit", c.JUnit, "write a JUnit XML report to this path (env KEYSTONE_JUNIT)")
+	fs.StringVar(&c.JSON, "keystone.json", c.JSON, "write a JSON report to this path (env KEYSTONE_JSON)")
+}
+
+// Select returns the requirements with names matching run, and not matching skip
+func (c Config) Select(reqs []requirements.Requirement) ([]requirements.Requirement, error) {
+	var run, skip *regexp.Regexp
+	var err error
+	if c.Run != "" {
+		if run, err = regexp.Compile("(?i)" + c.Run); err != nil {
+			return nil, fmt.Errorf("invalid run expression: %w", err)
+		}
+	}
+	if c.Skip != "" {
+		if skip, err = regexp.Compile("(?i)" + c.Skip); err != nil {
+			return nil, fmt.Errorf("invalid skip expression: %w", err)
+		}
+	}
+
+	var selected []requirements.Requirement
+	for _, req := range reqs {
+		if (run == nil || run.MatchString(req.Name())) && (skip == nil || !skip.MatchString(req.Name())) {
+			selected = append(selected, req)
+		}
+	}
+	return selected, nil
+}
+
+// Connect opens a connection to the configured keystone server
+func (c Config) Connect() (*keystone.Connection, error) {
+	if c.Endpoint == "" {
+		return nil, errors.New("no keystone endpoint configured")
+	}
+	return keystone.NewConnectionWithOptions(c.Endpoint, c.VendorID, c.AppID, c.Token)
+}
+
+// Actor returns the actor requirements are verified as
+func Actor(conn *keystone.Connection) *keystone.Actor {
+	a := conn.Actor("tt", "91.91.91.91", "random-userid", "UserAgent")
+	return &a
+}
+
+// Run registers every requirement, then verifies them with up to parallel requirements at once.
+// onResult is called for each result as it is produced.
+func Run(conn *keystone.Connection, reqs []requirements.Requirement, parallel int, onResult func(Result)) *Report {
+	report := &Report{Started: time.Now()}
+	var lock sync.Mutex
+	add := func(result Result) {
+		lock.Lock()
+		defer lock.Unlock()
+		report.Results = append(report.Results, result)
+		if onResult != nil {
+			onResult(result)
+		}
+	}
+
+	// Registration modifies the connection type register, so happens before any verification
+	var verify []requirements.Requirement
+	for _, req := range reqs {
+		start := time.Now()
+		if err := req.Register(conn); err != nil {
+			add(Result{Requirement: req.Name(), Name: "Registration", Error: err, Duration: time.Since(start)})
+			continue
+		}
+		verify = append(verify, req)
+	}
+	if err := conn.SyncSchemaContext(context.Background()); err != nil {
+		add(Result{Requirement: "Schema", Name: "Sync", Error: err})
+	}
+
+	actor := Actor(conn)
+	sem := make(chan struct{}, max(parallel, 1))
+	wg := sync.WaitGroup{}
+	for _, req := range verify {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			verifyRequirement(req, actor, add)
+		}()
+	}
+	wg.Wait()
+
+	report.Duration = time.Since(report.Started)
+	return report
+}
+
+func verifyRequirement(req requirements.Requirement, actor *keystone.Actor, add func(Result)) {
+	last := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			add(Result{Requirement: req.Name(), Name: "Panic", Error: fmt.Errorf("panic: %v\n%s", r, debug.Stack()), Duration: time.Since(last)})
+		}
+	}()
+
+	req.Verify(actor, func(result requirements.TestResult) {
+		now := time.Now()
+		add(Result{Requirement: req.Name(), Name: result.Name, Error: result.Error, Duration: now.Sub(last)})
+		last = now
+	})
+}
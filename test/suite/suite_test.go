@@ -0,0 +1,119 @@
+package suite
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/keystonedb/sdk-go/keystone"
+	"github.com/keystonedb/sdk-go/test/requirements"
+)
+
+var cfg = ConfigFromEnv()
+
+func init() {
+	cfg.RegisterFlags(flag.CommandLine)
+}
+
+// TestRequirements runs the requirements suite against a keystone server.
+// It is skipped unless an endpoint is set with -keystone.endpoint or KEYSTONE_ENDPOINT, e.g.
+//
+//	go test ./test/suite -run TestRequirements -args -keystone.endpoint=127.0.0.1:50051 -keystone.junit=report.xml
+func TestRequirements(t *testing.T) {
+	if cfg.Endpoint == "" {
+		t.Skip("no keystone endpoint configured, set -keystone.endpoint or KEYSTONE_ENDPOINT")
+	}
+
+	reqs, err := cfg.Select(All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := cfg.Connect()
+	if err != nil {
+		t.Fatalf("did not connect: %v", err)
+	}
+	defer conn.Close()
+
+	report := Run(conn, reqs, cfg.Parallel, nil)
+	if err = report.WriteFiles(cfg); err != nil {
+		t.Errorf("unable to write report: %v", err)
+	}
+
+	for _, name := range report.Requirements() {
+		t.Run(name, func(t *testing.T) {
+			for _, res := range report.Results {
+				if res.Requirement != name {
+					continue
+				}
+				t.Run(res.Name, func(t *testing.T) {
+					if res.Error != nil {
+						t.Error(res.Error)
+					}
+				})
+			}
+		})
+	}
+}
+
+type fakeRequirement struct {
+	name        string
+	registerErr error
+}
+
+func (f *fakeRequirement) Name() string                        { return f.name }
+func (f *fakeRequirement) Register(*keystone.Connection) error { return f.registerErr }
+func (f *fakeRequirement) Verify(_ *keystone.Actor, report requirements.Reporter) {
+	if f.name == "Panics" {
+		panic("boom")
+	}
+	report(requirements.NewResult("Check", nil))
+	report(requirements.NewResult("Broken", errors.New("broken")))
+}
+
+func TestConfigSelect(t *testing.T) {
+	reqs := []requirements.Requirement{&fakeRequirement{name: "App Key Value"}, &fakeRequirement{name: "Find"}, &fakeRequirement{name: "Find Child"}}
+
+	selected, err := Config{Run: "^find", Skip: "child"}.Select(reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 1 || selected[0].Name() != "Find" {
+		t.Errorf("unexpected selection %v", selected)
+	}
+
+	if _, err = (Config{Run: "("}).Select(reqs); err == nil {
+		t.Error("expected invalid expression error")
+	}
+}
+
+func TestRunReport(t *testing.T) {
+	conn := keystone.NewConnection(nil, DefaultVendorID, DefaultAppID, "")
+	reqs := []requirements.Requirement{
+		&fakeRequirement{name: "Working"},
+		&fakeRequirement{name: "Unregistered", registerErr: errors.New("no schema")},
+		&fakeRequirement{name: "Panics"},
+	}
+
+	report := Run(conn, reqs, 2, nil)
+	if len(report.Results) != 4 || len(report.Failures()) != 3 {
+		t.Fatalf("unexpected results %+v", report.Results)
+	}
+
+	junit := &bytes.Buffer{}
+	if err := report.WriteJUnit(junit); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(junit.String(), `<testsuites tests="4" failures="3"`) || !strings.Contains(junit.String(), `<failure message="broken">`) {
+		t.Errorf("unexpected junit report %s", junit.String())
+	}
+
+	js := &bytes.Buffer{}
+	if err := report.WriteJSON(js); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(js.String(), `"failed": 3`) {
+		t.Errorf("unexpected json report %s", js.String())
+	}
+}